@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditCollection encapsula as operações na trilha de auditoria dos documentos
+type AuditCollection struct {
+	Collection *mongo.Collection
+}
+
+// InsertEntry registra uma nova entrada na trilha de auditoria
+func (c *AuditCollection) InsertEntry(entry *models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	_, err := c.Collection.InsertOne(ctx, entry)
+	return err
+}
+
+// ListByDocument lista as entradas de auditoria de um documento, das mais recentes para as mais antigas
+func (c *AuditCollection) ListByDocument(documentID string, offset, limit int) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 50 // Limite padrão
+	}
+	if limit > 200 {
+		limit = 200 // Limite máximo
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "timestamp", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := c.Collection.Find(ctx, bson.M{"document_id": documentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlobCollection encapsula a contagem de referências dos blobs armazenados por conteúdo
+type BlobCollection struct {
+	Collection *mongo.Collection
+}
+
+// ErrBlobDeleting indica que o blob continua em remoção depois da espera de AddRef
+var ErrBlobDeleting = errors.New("blob em remoção; tente novamente")
+
+const (
+	// blobDeleteWait é o intervalo entre as tentativas de AddRef enquanto o blob é removido
+	blobDeleteWait = 100 * time.Millisecond
+	// blobDeleteTimeout é o tempo após o qual uma remoção interrompida é considerada abandonada
+	// e o registro pode voltar a receber referências
+	blobDeleteTimeout = 10 * time.Minute
+)
+
+// AddRef registra mais uma referência ao blob, criando o registro pendente se ainda não existir,
+// e retorna o registro atualizado. Enquanto ele não estiver gravado (Blob.IsStored), quem
+// registrou a referência deve gravar o objeto e chamar MarkStored. Se o blob estiver sendo
+// removido, AddRef espera a remoção terminar.
+func (c *BlobCollection) AddRef(blob *models.Blob) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for {
+		now := time.Now()
+		update := bson.M{
+			"$inc": bson.M{"ref_count": 1},
+			"$set": bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{
+				"object_name":  blob.ObjectName,
+				"size":         blob.Size,
+				"content_type": blob.ContentType,
+				"state":        models.BlobPending,
+				"created_at":   now,
+			},
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+		// Registros em remoção não atendem ao filtro, e o upsert falha pela chave duplicada
+		var current models.Blob
+		err := c.Collection.FindOneAndUpdate(ctx, bson.M{"_id": blob.Hash, "state": bson.M{"$ne": models.BlobDeleting}}, update, opts).Decode(&current)
+		if err == nil {
+			return &current, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		// Outro envio criou o registro ao mesmo tempo, ou o blob está sendo removido.
+		// Uma remoção abandonada há muito tempo é assumida: o objeto será gravado de novo.
+		err = c.Collection.FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": blob.Hash, "state": models.BlobDeleting, "deleting_at": bson.M{"$lt": now.Add(-blobDeleteTimeout)}},
+			bson.M{
+				"$set":   bson.M{"ref_count": 1, "state": models.BlobPending, "updated_at": now},
+				"$unset": bson.M{"deleting_at": ""},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&current)
+		if err == nil {
+			return &current, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrBlobDeleting
+		case <-time.After(blobDeleteWait):
+		}
+	}
+}
+
+// MarkStored confirma que o objeto do blob foi gravado
+func (c *BlobCollection) MarkStored(hash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": hash, "state": models.BlobPending},
+		bson.M{"$set": bson.M{"state": models.BlobStored, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// Release remove uma referência ao blob. Quando não restam referências, o registro é marcado
+// como em remoção e retornado para que o objeto seja removido do armazenamento, seguido de
+// DeleteTombstone; caso contrário retorna nil.
+func (c *BlobCollection) Release(hash string) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": hash, "ref_count": bson.M{"$gt": 0}},
+		bson.M{
+			"$inc": bson.M{"ref_count": -1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.tombstone(ctx, bson.M{"_id": hash, "ref_count": bson.M{"$lte": 0}})
+}
+
+// tombstone marca como em remoção o blob que atende ao filtro, se ele ainda não estiver
+func (c *BlobCollection) tombstone(ctx context.Context, filter bson.M) (*models.Blob, error) {
+	now := time.Now()
+	filter["state"] = bson.M{"$ne": models.BlobDeleting}
+
+	var removed models.Blob
+	err := c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"state": models.BlobDeleting, "deleting_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&removed)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &removed, nil
+}
+
+// DeleteTombstone apaga o registro do blob depois da remoção do objeto. Retorna false se a
+// remoção foi assumida como abandonada por AddRef e o registro voltou a ser usado.
+func (c *BlobCollection) DeleteTombstone(blob *models.Blob) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := c.Collection.DeleteOne(ctx, bson.M{"_id": blob.Hash, "state": models.BlobDeleting, "deleting_at": blob.DeletingAt})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// GetBlob busca o registro de um blob pelo hash
+func (c *BlobCollection) GetBlob(hash string) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var blob models.Blob
+	if err := c.Collection.FindOne(ctx, bson.M{"_id": hash}).Decode(&blob); err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
@@ -0,0 +1,357 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrFolderCycle indica uma tentativa de mover uma pasta para dentro de si mesma
+var ErrFolderCycle = errors.New("a pasta não pode ser movida para dentro de si mesma")
+
+// FolderCollection encapsula as operações na coleção de pastas
+type FolderCollection struct {
+	Collection *mongo.Collection
+}
+
+// InsertFolder insere uma nova pasta como filha de parent (nil para a raiz)
+func (c *FolderCollection) InsertFolder(folder *models.Folder, parent *models.Folder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folder.ID = primitive.NewObjectID()
+	now := time.Now()
+	folder.CreatedAt = now
+	folder.UpdatedAt = now
+
+	folder.ParentID = ""
+	folder.Ancestors = []string{}
+	if parent != nil {
+		folder.ParentID = parent.ID.Hex()
+		folder.Ancestors = parent.Path()
+	}
+
+	_, err := c.Collection.InsertOne(ctx, folder)
+	return err
+}
+
+// GetFolderByID busca uma pasta pelo ID
+func (c *FolderCollection) GetFolderByID(id string) (*models.Folder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var folder models.Folder
+	err = c.Collection.FindOne(ctx, bson.M{"_id": folderID}).Decode(&folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+// GetFolderChain retorna a pasta informada e todos os seus ancestrais, da raiz até ela
+func (c *FolderCollection) GetFolderChain(id string) ([]models.Folder, error) {
+	folder, err := c.GetFolderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(folder.Ancestors) == 0 {
+		return []models.Folder{*folder}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ancestorIDs, err := toObjectIDs(folder.Ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := c.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ancestorIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	byID := map[string]models.Folder{}
+	for cursor.Next(ctx) {
+		var ancestor models.Folder
+		if err := cursor.Decode(&ancestor); err != nil {
+			return nil, err
+		}
+		byID[ancestor.ID.Hex()] = ancestor
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reordenar conforme o caminho materializado
+	chain := make([]models.Folder, 0, len(folder.Ancestors)+1)
+	for _, ancestorID := range folder.Ancestors {
+		if ancestor, ok := byID[ancestorID]; ok {
+			chain = append(chain, ancestor)
+		}
+	}
+	return append(chain, *folder), nil
+}
+
+// ListChildren lista as subpastas diretas de uma pasta (vazio para a raiz),
+// restritas aos IDs informados quando allowedIDs não é nil
+func (c *FolderCollection) ListChildren(parentID string, allowedIDs []string) ([]models.Folder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"parent_id": parentID}
+	if allowedIDs != nil {
+		ids, err := toObjectIDs(allowedIDs)
+		if err != nil {
+			return nil, err
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "name", Value: 1}})
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	folders := []models.Folder{}
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// ListDescendants lista todas as pastas abaixo da pasta informada, em qualquer nível
+func (c *FolderCollection) ListDescendants(id string) ([]models.Folder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "name", Value: 1}})
+	cursor, err := c.Collection.Find(ctx, bson.M{"ancestors": id}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	folders := []models.Folder{}
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// ReadableFolderIDs retorna os IDs das pastas que o usuário pode ler, seja por
+// permissão direta na pasta ou herdada de algum ancestral
+func (c *FolderCollection) ReadableFolderIDs(userID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	granted := bson.M{"$or": bson.A{
+		bson.M{"permissions.owner_id": userID},
+		bson.M{"permissions.read_access": userID},
+		bson.M{"permissions.write_access": userID},
+		bson.M{"permissions.admin_access": userID},
+		bson.M{"permissions.is_public": true},
+	}}
+
+	grantedIDs, err := c.findIDs(ctx, granted)
+	if err != nil {
+		return nil, err
+	}
+	if len(grantedIDs) == 0 {
+		return []string{}, nil
+	}
+
+	descendantIDs, err := c.findIDs(ctx, bson.M{"ancestors": bson.M{"$in": grantedIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Unir as pastas concedidas e suas descendentes sem repetições
+	seen := map[string]bool{}
+	ids := make([]string, 0, len(grantedIDs)+len(descendantIDs))
+	for _, id := range append(grantedIDs, descendantIDs...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// RenameFolder altera o nome de uma pasta
+func (c *FolderCollection) RenameFolder(id string, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": folderID},
+		bson.M{"$set": bson.M{"name": name, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// MoveFolder move uma pasta para dentro de newParent (nil para a raiz), atualizando
+// o caminho materializado da pasta e de todas as suas descendentes
+func (c *FolderCollection) MoveFolder(folder *models.Folder, newParent *models.Folder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id := folder.ID.Hex()
+	newParentID := ""
+	newAncestors := []string{}
+	if newParent != nil {
+		if newParent.ID == folder.ID {
+			return ErrFolderCycle
+		}
+		for _, ancestorID := range newParent.Ancestors {
+			if ancestorID == id {
+				return ErrFolderCycle
+			}
+		}
+		newParentID = newParent.ID.Hex()
+		newAncestors = newParent.Path()
+	}
+
+	descendants, err := c.ListDescendants(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	writes := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": folder.ID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"parent_id":  newParentID,
+				"ancestors":  newAncestors,
+				"updated_at": now,
+			}}),
+	}
+
+	// Substituir o prefixo do caminho de cada descendente
+	for _, descendant := range descendants {
+		suffix := []string{}
+		for i, ancestorID := range descendant.Ancestors {
+			if ancestorID == id {
+				suffix = descendant.Ancestors[i:]
+				break
+			}
+		}
+		ancestors := append(append([]string{}, newAncestors...), suffix...)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": descendant.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"ancestors": ancestors}}))
+	}
+
+	_, err = c.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	return err
+}
+
+// DeleteFolder remove uma pasta pelo ID
+func (c *FolderCollection) DeleteFolder(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Collection.DeleteOne(ctx, bson.M{"_id": folderID})
+	return err
+}
+
+// CountChildren conta as subpastas diretas de uma pasta
+func (c *FolderCollection) CountChildren(id string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return c.Collection.CountDocuments(ctx, bson.M{"parent_id": id})
+}
+
+// UpdatePermissions substitui as permissões de uma pasta, desde que ela ainda esteja na revisão informada
+func (c *FolderCollection) UpdatePermissions(id string, permissions models.DocumentPermissions, expectedRevision int64) (*models.Folder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated models.Folder
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		revisionFilter(folderID, expectedRevision),
+		bson.M{
+			"$set": bson.M{"permissions": permissions, "updated_at": time.Now()},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRevisionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// findIDs retorna os IDs (em hexadecimal) das pastas que atendem ao filtro
+func (c *FolderCollection) findIDs(ctx context.Context, filter bson.M) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := []string{}
+	for cursor.Next(ctx) {
+		var result struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		ids = append(ids, result.ID.Hex())
+	}
+	return ids, cursor.Err()
+}
+
+// toObjectIDs converte uma lista de IDs em hexadecimal para ObjectIDs
+func toObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
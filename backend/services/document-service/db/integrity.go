@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ForEachStorageReference percorre todos os documentos, inclusive os da lixeira, carregando
+// apenas os campos que apontam para o armazenamento. A varredura para no primeiro erro de fn.
+func (c *DocCollection) ForEachStorageReference(ctx context.Context, fn func(*models.Document) error) error {
+	opts := options.Find().
+		SetProjection(bson.M{
+			"storage_path":    1,
+			"content_hash":    1,
+			"version_history": 1,
+		}).
+		SetBatchSize(100)
+
+	cursor, err := c.Collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
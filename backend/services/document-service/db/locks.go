@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDocumentLocked indica que outro usuário detém a reserva de edição do documento
+var ErrDocumentLocked = errors.New("o documento está reservado para edição por outro usuário")
+
+// ErrLockNotHeld indica que não há reserva vigente para ser renovada ou liberada
+var ErrLockNotHeld = errors.New("não há reserva de edição vigente")
+
+// Operações de reserva de edição exclusiva
+
+// AcquireLock reserva o documento para edição exclusiva do usuário até expirar o prazo.
+// Se o usuário já detém a reserva, ela é renovada. Só rascunhos podem ser reservados.
+func (c *DocCollection) AcquireLock(id string, userID string, ttl time.Duration) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := editableBy(userID, now)
+	filter["_id"] = docID
+	filter["deleted_at"] = nil
+	filter["status"] = bson.M{"$in": bson.A{models.StatusDraft, ""}}
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"lock": models.EditLock{
+			HolderID:   userID,
+			AcquiredAt: now,
+			RenewedAt:  now,
+			ExpiresAt:  now.Add(ttl),
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		reason := c.lockFailureReason(ctx, docID, userID)
+		if reason == ErrRevisionConflict {
+			// Sem reserva de outro usuário, o documento saiu do rascunho ou foi excluído
+			return nil, ErrDocumentNotEditable
+		}
+		return nil, reason
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// RenewLock estende o prazo da reserva vigente. Quando holderID é vazio, renova
+// a reserva de qualquer usuário (uso administrativo).
+func (c *DocCollection) RenewLock(id string, holderID string, ttl time.Duration) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"_id": docID, "lock.expires_at": bson.M{"$gt": now}}
+	if holderID != "" {
+		filter["lock.holder_id"] = holderID
+	}
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{
+			"lock.renewed_at": now,
+			"lock.expires_at": now.Add(ttl),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLockNotHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// ReleaseLock libera a reserva do documento e retorna a reserva removida. Quando holderID
+// é vazio, remove a reserva de qualquer usuário (quebra administrativa).
+func (c *DocCollection) ReleaseLock(id string, holderID string) (*models.EditLock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": docID, "lock": bson.M{"$ne": nil}}
+	if holderID != "" {
+		filter["lock.holder_id"] = holderID
+	}
+
+	var previous models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$unset": bson.M{"lock": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLockNotHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return previous.Lock, nil
+}
+
+// ReleaseExpiredLocks remove as reservas cujo prazo já terminou
+func (c *DocCollection) ReleaseExpiredLocks() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := c.Collection.UpdateMany(
+		ctx,
+		bson.M{"lock.expires_at": bson.M{"$lte": time.Now()}},
+		bson.M{"$unset": bson.M{"lock": ""}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// editableBy monta o filtro que garante que nenhum outro usuário detém uma reserva vigente
+func editableBy(userID string, now time.Time) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"lock": nil},
+		bson.M{"lock.expires_at": bson.M{"$lte": now}},
+		bson.M{"lock.holder_id": userID},
+	}}
+}
+
+// lockFailureReason identifica por que uma gravação condicionada à reserva não encontrou o documento
+func (c *DocCollection) lockFailureReason(ctx context.Context, docID primitive.ObjectID, userID string) error {
+	var current models.Document
+	err := c.Collection.FindOne(ctx, bson.M{"_id": docID, "deleted_at": nil}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		// Excluído depois de lido: também é uma alteração concorrente
+		return ErrRevisionConflict
+	}
+	if err != nil {
+		return err
+	}
+	if current.Lock.BlocksUser(userID, time.Now()) {
+		return ErrDocumentLocked
+	}
+	return ErrRevisionConflict
+}
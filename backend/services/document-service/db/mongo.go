@@ -2,11 +2,17 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"gestor-e-docs/document-service/highlight"
 	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/pagination"
+	"gestor-e-docs/document-service/querylang"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,6 +23,12 @@ import (
 var client *mongo.Client
 var database *mongo.Database
 
+// ErrRevisionConflict indica que o documento foi alterado por outra gravação
+var ErrRevisionConflict = errors.New("o documento foi modificado por outra operação")
+
+// maxUpdateAttempts limita as novas tentativas de atualizações sem revisão esperada
+const maxUpdateAttempts = 3
+
 // DocCollection é uma struct para encapsular operações em uma coleção específica
 type DocCollection struct {
 	Collection *mongo.Collection
@@ -24,7 +36,12 @@ type DocCollection struct {
 
 // Collections contém referências a todas as coleções do banco de dados
 type Collections struct {
-	Documents *DocCollection
+	Documents  *DocCollection
+	Audit      *AuditCollection
+	Folders    *FolderCollection
+	ShareLinks *ShareLinkCollection
+	Uploads    *UploadCollection
+	Blobs      *BlobCollection
 }
 
 // DbCollections contém todas as coleções do banco de dados
@@ -73,6 +90,9 @@ func ConnectDatabase() error {
 	// Criar índices para melhor performance nas consultas
 	createIndices()
 
+	// Preencher a contagem de versões dos documentos anteriores ao campo
+	backfillVersionCounts()
+
 	return nil
 }
 
@@ -98,6 +118,22 @@ func initCollections() {
 		Documents: &DocCollection{
 			Collection: database.Collection("documents"),
 		},
+		Audit: &AuditCollection{
+			Collection: database.Collection("document_audit"),
+		},
+		Folders: &FolderCollection{
+			Collection: database.Collection("folders"),
+		},
+		ShareLinks: &ShareLinkCollection{
+			Collection: database.Collection("share_links"),
+			Attempts:   database.Collection("share_link_attempts"),
+		},
+		Uploads: &UploadCollection{
+			Collection: database.Collection("uploads"),
+		},
+		Blobs: &BlobCollection{
+			Collection: database.Collection("blobs"),
+		},
 	}
 }
 
@@ -108,15 +144,6 @@ func createIndices() {
 
 	// Índices para a coleção de documentos
  	documentIndices := []mongo.IndexModel{
- 		{
- 			Keys: bson.D{bson.E{Key: "title", Value: "text"}, bson.E{Key: "content", Value: "text"}},
- 			Options: options.Index().
- 				SetName("text_search").
- 				SetWeights(bson.D{
- 					bson.E{Key: "title", Value: 10},
- 					bson.E{Key: "content", Value: 5},
- 				}),
- 		},
 		{
 			Keys:    bson.D{bson.E{Key: "author_id", Value: 1}},
 			Options: options.Index().SetName("author_id_idx"),
@@ -133,22 +160,185 @@ func createIndices() {
 			Keys:    bson.D{bson.E{Key: "status", Value: 1}},
 			Options: options.Index().SetName("status_idx"),
 		},
+		// Índices para as consultas de documentos legíveis pelo usuário
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.read_access", Value: 1}},
+			Options: options.Index().SetName("read_access_idx"),
+		},
 		{
-			Keys:    bson.D{bson.E{Key: "created_at", Value: -1}},
-			Options: options.Index().SetName("created_at_idx"),
+			Keys:    bson.D{bson.E{Key: "permissions.write_access", Value: 1}},
+			Options: options.Index().SetName("write_access_idx"),
 		},
 		{
-			Keys:    bson.D{bson.E{Key: "updated_at", Value: -1}},
-			Options: options.Index().SetName("updated_at_idx"),
+			Keys:    bson.D{bson.E{Key: "permissions.admin_access", Value: 1}},
+			Options: options.Index().SetName("admin_access_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.is_public", Value: 1}, bson.E{Key: "updated_at", Value: -1}},
+			Options: options.Index().SetName("public_updated_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "folder_id", Value: 1}, bson.E{Key: "updated_at", Value: -1}},
+			Options: options.Index().SetName("folder_updated_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("deleted_at_idx").SetSparse(true),
 		},
 	}
 
+	// Índices das ordenações aceitas na listagem (cada campo sozinho e as combinações de
+	// models.IndexedSorts), em todos os documentos e nos do próprio usuário, com o _id no fim
+	// para o desempate da paginação por cursor
+	var sorts [][]models.SortKey
+	for _, sortKey := range []string{models.SortUpdatedAt, models.SortCreatedAt, models.SortTitle, models.SortViewCount, models.SortVersionCount} {
+		sorts = append(sorts, []models.SortKey{{Field: sortKey, Descending: sortKey != models.SortTitle}})
+	}
+	sorts = append(sorts, models.IndexedSorts...)
+	for _, keys := range sorts {
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = key.Field
+		}
+		name := strings.Join(fields, "_")
+		sort := sortDocument(keys, false)
+		documentIndices = append(documentIndices,
+			mongo.IndexModel{
+				Keys:    sort,
+				Options: options.Index().SetName(name + "_sort_idx"),
+			},
+			mongo.IndexModel{
+				Keys:    append(bson.D{bson.E{Key: "permissions.owner_id", Value: 1}}, sort...),
+				Options: options.Index().SetName("owner_" + name + "_sort_idx"),
+			},
+		)
+	}
+
 	_, err := DbCollections.Documents.Collection.Indexes().CreateMany(ctx, documentIndices)
 	if err != nil {
 		log.Printf("Erro ao criar índices para a coleção de documentos: %v", err)
 	} else {
 		log.Println("Índices criados com sucesso para a coleção de documentos")
 	}
+
+	// O índice textual é criado à parte: se o idioma configurado mudou, só ele falha
+	if _, err := DbCollections.Documents.Collection.Indexes().CreateOne(ctx, textIndexModel()); err != nil {
+		log.Printf("Erro ao criar o índice textual (se SEARCH_LANGUAGE mudou, recrie-o pela reindexação da busca): %v", err)
+	}
+
+	// Remover os índices substituídos pelos de ordenação; os que já não existem são ignorados
+	for _, name := range []string{"created_at_idx", "updated_at_idx", "owner_updated_idx"} {
+		if _, err := DbCollections.Documents.Collection.Indexes().DropOne(ctx, name); err == nil {
+			log.Printf("Índice %s substituído pelos índices de ordenação", name)
+		}
+	}
+
+	// Índices para a trilha de auditoria
+	auditIndices := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "document_id", Value: 1}, bson.E{Key: "timestamp", Value: -1}},
+			Options: options.Index().SetName("document_timestamp_idx"),
+		},
+	}
+
+	_, err = DbCollections.Audit.Collection.Indexes().CreateMany(ctx, auditIndices)
+	if err != nil {
+		log.Printf("Erro ao criar índices para a trilha de auditoria: %v", err)
+	} else {
+		log.Println("Índices criados com sucesso para a trilha de auditoria")
+	}
+
+	// Índices para a coleção de pastas
+	folderIndices := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "parent_id", Value: 1}, bson.E{Key: "name", Value: 1}},
+			Options: options.Index().SetName("parent_name_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "ancestors", Value: 1}},
+			Options: options.Index().SetName("ancestors_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.owner_id", Value: 1}},
+			Options: options.Index().SetName("owner_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.read_access", Value: 1}},
+			Options: options.Index().SetName("read_access_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.write_access", Value: 1}},
+			Options: options.Index().SetName("write_access_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "permissions.admin_access", Value: 1}},
+			Options: options.Index().SetName("admin_access_idx"),
+		},
+	}
+
+	_, err = DbCollections.Folders.Collection.Indexes().CreateMany(ctx, folderIndices)
+	if err != nil {
+		log.Printf("Erro ao criar índices para a coleção de pastas: %v", err)
+	} else {
+		log.Println("Índices criados com sucesso para a coleção de pastas")
+	}
+
+	// Índices para os links públicos
+	shareLinkIndices := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("token_hash_idx").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "document_id", Value: 1}, bson.E{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("document_created_idx"),
+		},
+	}
+
+	_, err = DbCollections.ShareLinks.Collection.Indexes().CreateMany(ctx, shareLinkIndices)
+	if err != nil {
+		log.Printf("Erro ao criar índices para a coleção de links públicos: %v", err)
+	} else {
+		log.Println("Índices criados com sucesso para a coleção de links públicos")
+	}
+
+	// Índices para as senhas incorretas dos links públicos, descartadas ao expirar
+	attemptIndices := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "link_id", Value: 1}, bson.E{Key: "client_key", Value: 1}},
+			Options: options.Index().SetName("link_client_idx").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl_idx").SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = DbCollections.ShareLinks.Attempts.Indexes().CreateMany(ctx, attemptIndices)
+	if err != nil {
+		log.Printf("Erro ao criar índices para a coleção de senhas incorretas dos links: %v", err)
+	} else {
+		log.Println("Índices criados com sucesso para a coleção de senhas incorretas dos links")
+	}
+
+	// Índices para os envios de arquivos
+	uploadIndices := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_id_idx"),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_idx"),
+		},
+	}
+
+	_, err = DbCollections.Uploads.Collection.Indexes().CreateMany(ctx, uploadIndices)
+	if err != nil {
+		log.Printf("Erro ao criar índices para a coleção de envios de arquivos: %v", err)
+	} else {
+		log.Println("Índices criados com sucesso para a coleção de envios de arquivos")
+	}
 }
 
 // Métodos do DocCollection para operações CRUD
@@ -158,8 +348,10 @@ func (c *DocCollection) InsertDocument(doc *models.Document) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Configurar campos de criação
-	doc.ID = primitive.NewObjectID()
+	// Configurar campos de criação; um ID já reservado (envio pré-assinado) é mantido
+	if doc.ID.IsZero() {
+		doc.ID = primitive.NewObjectID()
+	}
 	now := time.Now()
 	doc.CreatedAt = now
 	doc.UpdatedAt = now
@@ -172,28 +364,61 @@ func (c *DocCollection) InsertDocument(doc *models.Document) error {
 			AuthorID:      doc.AuthorID,
 			Description:   "Criação inicial do documento",
 			StoragePath:   doc.StoragePath,
+			ContentHash:   doc.ContentHash,
 		},
 	}
+	doc.VersionCount = len(doc.VersionHistory)
 
 	_, err := c.Collection.InsertOne(ctx, doc)
 	return err
 }
 
-// UpdateDocument atualiza um documento existente
-func (c *DocCollection) UpdateDocument(id string, update *models.DocumentUpdate, userID string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+// UpdateDocument atualiza um documento existente. Quando expectedRevision é informado,
+// a gravação só ocorre se o documento ainda estiver nessa revisão; caso contrário
+// retorna ErrRevisionConflict. A verificação é feita no próprio filtro da atualização.
+// Com newContent, uma nova versão é registrada e o documento passa a apontar para ela.
+func (c *DocCollection) UpdateDocument(id string, update *models.DocumentUpdate, newContent *models.VersionContent, userID string, expectedRevision *int64) (*models.Document, error) {
 	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Sem revisão esperada, repetir algumas vezes caso outra gravação concorra com esta
+	attempts := 1
+	if expectedRevision == nil {
+		attempts = maxUpdateAttempts
 	}
 
+	for attempt := 0; attempt < attempts; attempt++ {
+		updated, err := c.updateDocumentOnce(docID, update, newContent, userID, expectedRevision)
+		if err != ErrRevisionConflict || expectedRevision != nil {
+			return updated, err
+		}
+	}
+
+	return nil, ErrRevisionConflict
+}
+
+// updateDocumentOnce executa uma tentativa de atualização condicionada à revisão lida
+func (c *DocCollection) updateDocumentOnce(docID primitive.ObjectID, update *models.DocumentUpdate, newContent *models.VersionContent, userID string, expectedRevision *int64) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
 	// Obter o documento atual para calcular a nova versão
 	var currentDoc models.Document
-	err = c.Collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&currentDoc)
+	err := c.Collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&currentDoc)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if expectedRevision != nil && currentDoc.Revision != *expectedRevision {
+		return nil, ErrRevisionConflict
+	}
+	if !currentDoc.Status.IsEditable() {
+		return nil, ErrDocumentNotEditable
+	}
+	if currentDoc.Lock.BlocksUser(userID, time.Now()) {
+		return nil, ErrDocumentLocked
 	}
 
 	// Construir o documento de atualização
@@ -205,9 +430,6 @@ func (c *DocCollection) UpdateDocument(id string, update *models.DocumentUpdate,
 	if update.Title != "" {
 		updateFields["title"] = update.Title
 	}
-	if update.Content != "" {
-		updateFields["content"] = update.Content
-	}
 	if update.Tags != nil {
 		updateFields["tags"] = update.Tags
 	}
@@ -218,31 +440,41 @@ func (c *DocCollection) UpdateDocument(id string, update *models.DocumentUpdate,
 		updateFields["status"] = update.Status
 	}
 
-	// Criar uma nova versão se o conteúdo foi alterado
-	if update.Content != "" {
-		// Gerar um novo caminho de armazenamento para esta versão
-		versionPath := currentDoc.StoragePath + ".v" + 
-			primitive.NewObjectID().Hex()
+	updateDoc := bson.M{
+		"$set": updateFields,
+		"$inc": bson.M{"revision": 1},
+	}
 
-		newVersion := models.Version{
-			VersionNumber: len(currentDoc.VersionHistory) + 1,
-			CreatedAt:     now,
-			AuthorID:      userID,
-			Description:   update.Description,
-			StoragePath:   versionPath,
-		}
+	// Criar uma nova versão se o conteúdo foi alterado
+	if newContent != nil {
+		newVersion := newVersionFor(&currentDoc, newContent, userID, update.Description, now)
+		setCurrentContent(updateFields, newContent)
 
-		updateFields["$push"] = bson.M{
+		updateDoc["$push"] = bson.M{
 			"version_history": newVersion,
 		}
+		updateDoc["$inc"] = bson.M{"revision": 1, "version_count": 1}
 	}
 
-	_, err = c.Collection.UpdateOne(
+	// A gravação exige a mesma revisão e que nenhum outro usuário tenha reservado o documento
+	filter := revisionFilter(docID, currentDoc.Revision)
+	filter["$or"] = editableBy(userID, now)["$or"]
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": docID},
-		bson.M{"$set": updateFields},
-	)
-	return err
+		filter,
+		updateDoc,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, c.lockFailureReason(ctx, docID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
 }
 
 // GetDocumentByID busca um documento pelo ID
@@ -255,8 +487,9 @@ func (c *DocCollection) GetDocumentByID(id string) (*models.Document, error) {
 		return nil, err
 	}
 
+	// Documentos na lixeira só são acessíveis pelas operações da lixeira
 	var document models.Document
-	err = c.Collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&document)
+	err = c.Collection.FindOne(ctx, bson.M{"_id": docID, "deleted_at": nil}).Decode(&document)
 	if err != nil {
 		return nil, err
 	}
@@ -264,17 +497,178 @@ func (c *DocCollection) GetDocumentByID(id string) (*models.Document, error) {
 	return &document, nil
 }
 
-// SearchDocuments busca documentos com base em critérios de pesquisa
-func (c *DocCollection) SearchDocuments(query *models.DocumentSearchQuery) ([]models.DocumentListItem, error) {
+// SearchDocuments busca documentos com base em critérios de pesquisa e retorna
+// a página solicitada junto com o total de documentos que atendem ao mesmo filtro.
+// Com query.Cursor, a página é localizada pela chave de ordenação em vez do offset.
+func (c *DocCollection) SearchDocuments(query *models.DocumentSearchQuery) (*models.DocumentPage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	expr, err := querylang.Parse(query.Query)
+	if err != nil {
+		return nil, err
+	}
+	filter := buildSearchFilter(query, expr)
+	_, textSearch := filter["$text"]
+
+	// Configurar paginação
+	query.NormalizeLimit()
+
+	// Configurar ordenação; sem campos informados, buscas textuais seguem a relevância e as
+	// demais, a data de atualização decrescente. A relevância não tem valor que possa ir no
+	// cursor, então só pagina por offset.
+	keys, err := models.ParseSort(query.SortBy, query.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.SortKey{{Field: models.SortUpdatedAt, Descending: true}}
+		if textSearch {
+			keys = []models.SortKey{{Field: models.SortRelevance, Descending: true}}
+		}
+	}
+	relevance := false
+	for _, key := range keys {
+		relevance = relevance || key.Field == models.SortRelevance
+	}
+	if relevance && !textSearch {
+		return nil, fmt.Errorf("%w: relevance exige termos de busca", models.ErrInvalidSort)
+	}
+	sortSpec := models.FormatSort(keys)
+	filterHash := pagination.FilterHash(query.FilterKey()...)
+
+	var position *pagination.Cursor
+	if query.Cursor != "" {
+		if relevance {
+			return nil, fmt.Errorf("%w: a ordenação por relevância só pagina por offset", pagination.ErrInvalidCursor)
+		}
+		position, err = pagination.Decode(query.Cursor, sortSpec, filterHash)
+		if err != nil {
+			return nil, err
+		}
+		if len(position.Values) != len(keys) {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+	backward := position != nil && position.Backward
+
+	// O conteúdo só é lido quando há termos a destacar nos trechos
+	terms := querylang.Terms(expr)
+	projection := bson.M{}
+	if textSearch {
+		projection["score"] = bson.M{"$meta": "textScore"}
+	}
+	if len(terms) == 0 {
+		projection["content"] = 0
+	}
+
+	opts := options.Find()
+	findFilter := filter
+	if len(projection) > 0 {
+		opts.SetProjection(projection)
+	}
+	opts.SetSort(sortDocument(keys, backward))
+	if relevance {
+		opts.SetSkip(int64(query.Offset))
+		opts.SetLimit(int64(query.Limit))
+	} else {
+		// Um item a mais indica se há outra página na direção percorrida
+		opts.SetLimit(int64(query.Limit) + 1)
+		if position != nil {
+			findFilter = bson.M{"$and": bson.A{filter, keysetFilter(keys, position)}}
+		} else {
+			opts.SetSkip(int64(query.Offset))
+		}
+	}
+
+	// Executar consulta
+	cursor, err := c.Collection.Find(ctx, findFilter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// Converter resultados para a lista de documentos
+	results := []models.DocumentListItem{}
+	var edges [][]bson.RawValue
+	for cursor.Next(ctx) {
+		var doc scoredDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if !relevance {
+			edges = append(edges, sortValues(cursor.Current, keys))
+		}
+
+		// Converter para DocumentListItem
+		item := doc.ListItem(query.ViewerID)
+		item.Score = doc.Score
+		item.Highlights = highlight.Excerpts(doc.Content, terms)
+		results = append(results, item)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// Contar o total com o mesmo filtro usado na busca
+	total, err := c.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.DocumentPage{Documents: results, Total: total}
+	if relevance {
+		return page, nil
+	}
+
+	hasMore := len(results) > query.Limit
+	if hasMore {
+		results, edges = results[:query.Limit], edges[:query.Limit]
+	}
+	if backward {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+	}
+	page.Documents = results
+	if len(results) == 0 {
+		return page, nil
+	}
+
+	// Quem voltou uma página sabe que há outra depois; quem avançou, que há outra antes
+	moreAfter := hasMore || backward
+	moreBefore := (backward && hasMore) || (!backward && (position != nil || query.Offset > 0))
+	if moreAfter {
+		last := len(results) - 1
+		page.NextCursor, err = pagination.Encode(pagination.Cursor{Sort: sortSpec, Filter: filterHash, Values: edges[last], ID: results[last].ID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if moreBefore {
+		page.PrevCursor, err = pagination.Encode(pagination.Cursor{Sort: sortSpec, Filter: filterHash, Values: edges[0], ID: results[0].ID, Backward: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// buildSearchFilter monta o filtro do MongoDB a partir dos critérios de pesquisa e da
+// consulta já interpretada
+func buildSearchFilter(query *models.DocumentSearchQuery, expr querylang.Expr) bson.M {
 	filter := bson.M{}
 
-	// Aplicar filtros de busca
-	if query.Query != "" {
-		filter["$text"] = bson.M{"$search": query.Query}
+	// Aplicar a consulta: termos livres no índice textual, demais itens como condições
+	textSearch, conditions := queryFilter(expr, query.ViewerID)
+	if textSearch != "" {
+		filter["$text"] = bson.M{"$search": textSearch}
 	}
+
+	// Aplicar filtros de busca
 	if len(query.Tags) > 0 {
 		filter["tags"] = bson.M{"$in": query.Tags}
 	}
@@ -305,85 +699,239 @@ func (c *DocCollection) SearchDocuments(query *models.DocumentSearchQuery) ([]mo
 	if len(dateFilter) > 0 {
 		filter["created_at"] = dateFilter
 	}
+	if len(query.FolderIDs) > 0 {
+		filter["folder_id"] = bson.M{"$in": query.FolderIDs}
+	}
 
-	// Configurar ordenação
-	opts := options.Find()
-	if query.SortBy != "" {
-		sortOrder := 1 // Crescente por padrão
-		if query.SortOrder == "desc" {
-			sortOrder = -1
-		}
-		opts.SetSort(bson.D{bson.E{Key: query.SortBy, Value: sortOrder}})
-	} else {
-		// Ordenação padrão por data de atualização decrescente
-		opts.SetSort(bson.D{bson.E{Key: "updated_at", Value: -1}})
+	// Ocultar documentos na lixeira
+	filter["deleted_at"] = nil
+
+	// Restringir aos documentos que quem consulta pode ler
+	if query.ViewerID != "" {
+		conditions = append(conditions, scopeFilter(query.ViewerID, query.Scope, query.ReadFolders))
+	}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
 	}
 
-	// Configurar paginação
-	if query.Limit <= 0 {
-		query.Limit = 10 // Limite padrão
+	return filter
+}
+
+// scopeFilter monta o filtro de documentos legíveis pelo usuário dentro do escopo informado.
+// readFolders contém as pastas cujas permissões concedem leitura ao usuário por herança.
+func scopeFilter(userID string, scope string, readFolders []string) bson.M {
+	owned := bson.M{"permissions.owner_id": userID}
+	sharedClauses := bson.A{
+		bson.M{"permissions.read_access": userID},
+		bson.M{"permissions.write_access": userID},
+		bson.M{"permissions.admin_access": userID},
+	}
+	if len(readFolders) > 0 {
+		sharedClauses = append(sharedClauses, bson.M{"folder_id": bson.M{"$in": readFolders}})
+	}
+	shared := bson.M{"$or": sharedClauses}
+	public := bson.M{"permissions.is_public": true}
+
+	switch scope {
+	case models.ScopeOwned:
+		return owned
+	case models.ScopeShared:
+		return bson.M{"$and": bson.A{
+			shared,
+			bson.M{"permissions.owner_id": bson.M{"$ne": userID}},
+		}}
+	case models.ScopePublic:
+		return public
+	default:
+		return bson.M{"$or": append(bson.A{owned, public}, sharedClauses...)}
 	}
-	if query.Limit > 100 {
-		query.Limit = 100 // Limite máximo
+}
+
+// DeleteDocument remove um documento pelo ID. Quando expectedRevision é informado,
+// a remoção só ocorre se o documento ainda estiver nessa revisão.
+func (c *DocCollection) DeleteDocument(id string, expectedRevision *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
 	}
-	opts.SetSkip(int64(query.Offset))
-	opts.SetLimit(int64(query.Limit))
 
-	// Executar consulta
-	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if expectedRevision == nil {
+		_, err = c.Collection.DeleteOne(ctx, bson.M{"_id": docID})
+		return err
+	}
+
+	result, err := c.Collection.DeleteOne(ctx, revisionFilter(docID, *expectedRevision))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer cursor.Close(ctx)
+	if result.DeletedCount == 0 {
+		return ErrRevisionConflict
+	}
+	return nil
+}
 
-	// Converter resultados para a lista de documentos
-	var results []models.DocumentListItem
-	for cursor.Next(ctx) {
-		var doc models.Document
-		if err := cursor.Decode(&doc); err != nil {
-			return nil, err
-		}
+// CountDocuments conta documentos com base em critérios
+func (c *DocCollection) CountDocuments(filter bson.M) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-		// Converter para DocumentListItem
-		item := models.DocumentListItem{
-			ID:           doc.ID,
-			Title:        doc.Title,
-			AuthorID:     doc.AuthorID,
-			CreatedAt:    doc.CreatedAt,
-			UpdatedAt:    doc.UpdatedAt,
-			Status:       doc.Status,
-			Tags:         doc.Tags,
-			Categories:   doc.Categories,
-			VersionCount: len(doc.VersionHistory),
-		}
-		results = append(results, item)
+	return c.Collection.CountDocuments(ctx, filter)
+}
+
+// AddVersion registra uma nova versão do documento apontando para um objeto já armazenado
+func (c *DocCollection) AddVersion(id string, content *models.VersionContent, userID string, description string) (*models.Version, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := cursor.Err(); err != nil {
+	// Obter o documento atual para calcular o número da nova versão
+	var currentDoc models.Document
+	err = c.Collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&currentDoc)
+	if err != nil {
+		return nil, err
+	}
+	if !currentDoc.Status.IsEditable() {
+		return nil, ErrDocumentNotEditable
+	}
+	if currentDoc.Lock.BlocksUser(userID, time.Now()) {
+		return nil, ErrDocumentLocked
+	}
+
+	now := time.Now()
+	newVersion := newVersionFor(&currentDoc, content, userID, description, now)
+
+	updateFields := bson.M{"updated_at": now}
+	setCurrentContent(updateFields, content)
+
+	filter := revisionFilter(docID, currentDoc.Revision)
+	filter["$or"] = editableBy(userID, now)["$or"]
+
+	result, err := c.Collection.UpdateOne(
+		ctx,
+		filter,
+		bson.M{
+			"$set": updateFields,
+			"$inc":  bson.M{"revision": 1, "version_count": 1},
+			"$push": bson.M{"version_history": newVersion},
+		},
+	)
+	if err != nil {
 		return nil, err
 	}
+	if result.MatchedCount == 0 {
+		return nil, c.lockFailureReason(ctx, docID, userID)
+	}
 
-	return results, nil
+	return &newVersion, nil
 }
 
-// DeleteDocument remove um documento pelo ID
-func (c *DocCollection) DeleteDocument(id string) error {
+// newVersionFor monta a próxima versão do documento com o conteúdo já gravado
+func newVersionFor(doc *models.Document, content *models.VersionContent, userID string, description string, now time.Time) models.Version {
+	return models.Version{
+		VersionNumber: len(doc.VersionHistory) + 1,
+		CreatedAt:     now,
+		AuthorID:      userID,
+		Description:   description,
+		StoragePath:   content.StoragePath,
+		ContentHash:   content.ContentHash,
+	}
+}
+
+// setCurrentContent faz o documento apontar para o conteúdo da nova versão: caminho, blob,
+// texto indexado e os metadados que descrevem o arquivo atual
+func setCurrentContent(fields bson.M, content *models.VersionContent) {
+	fields["storage_path"] = content.StoragePath
+	fields["content_hash"] = content.ContentHash
+	fields["content"] = content.Text
+	fields["metadata.file_size"] = content.Size
+	if content.ContentType != "" {
+		fields["metadata.content_type"] = content.ContentType
+	}
+}
+
+// revisionFilter monta o filtro que garante que o documento ainda está na revisão informada.
+// Documentos anteriores ao controle de revisões não possuem o campo e equivalem à revisão 0.
+func revisionFilter(docID primitive.ObjectID, revision int64) bson.M {
+	if revision == 0 {
+		return bson.M{"_id": docID, "revision": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": docID, "revision": revision}
+}
+
+// UpdatePermissions substitui as permissões do documento, desde que ele ainda esteja na revisão informada
+func (c *DocCollection) UpdatePermissions(id string, permissions models.DocumentPermissions, expectedRevision int64) (*models.Document, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = c.Collection.DeleteOne(ctx, bson.M{"_id": docID})
-	return err
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		revisionFilter(docID, expectedRevision),
+		bson.M{
+			"$set": bson.M{
+				"permissions": permissions,
+				"updated_at":  time.Now(),
+			},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRevisionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
 }
 
-// CountDocuments conta documentos com base em critérios
-func (c *DocCollection) CountDocuments(filter bson.M) (int64, error) {
+// MoveDocument move o documento para outra pasta (vazio para a raiz), desde que ele ainda esteja na revisão informada
+// e que nenhum outro usuário detenha a reserva de edição
+func (c *DocCollection) MoveDocument(id string, folderID string, userID string, expectedRevision int64) (*models.Document, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return c.Collection.CountDocuments(ctx, filter)
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := revisionFilter(docID, expectedRevision)
+	filter["$or"] = editableBy(userID, now)["$or"]
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"folder_id":  folderID,
+				"updated_at": now,
+			},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, c.lockFailureReason(ctx, docID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
 }
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ForEachBlob percorre todos os registros de blobs
+func (c *BlobCollection) ForEachBlob(ctx context.Context, fn func(*models.Blob) error) error {
+	cursor, err := c.Collection.Find(ctx, bson.M{}, options.Find().SetBatchSize(500))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var blob models.Blob
+		if err := cursor.Decode(&blob); err != nil {
+			return err
+		}
+		if err := fn(&blob); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// CorrectRefCount ajusta a contagem de referências de um blob, desde que ela ainda seja a
+// lida na reconciliação. Retorna false se outra operação alterou o blob nesse meio-tempo.
+func (c *BlobCollection) CorrectRefCount(hash string, recorded int64, actual int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": hash, "ref_count": recorded},
+		bson.M{"$set": bson.M{"ref_count": actual, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// TombstoneUnreferenced marca como em remoção o registro de um blob sem referências, desde
+// que a contagem ainda seja a lida na reconciliação. O objeto é removido em seguida, e o
+// registro, por DeleteTombstone.
+func (c *BlobCollection) TombstoneUnreferenced(hash string, recorded int64) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return c.tombstone(ctx, bson.M{"_id": hash, "ref_count": recorded})
+}
+
+// RestoreBlob recria o registro de um blob referenciado por versões mas ausente da coleção.
+// Retorna false se o registro foi criado por outra operação nesse meio-tempo.
+func (c *BlobCollection) RestoreBlob(blob *models.Blob) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	blob.State = models.BlobStored
+	blob.CreatedAt = now
+	blob.UpdatedAt = now
+	_, err := c.Collection.InsertOne(ctx, blob)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ForEachUpload percorre todos os envios registrados, concluídos ou não
+func (c *UploadCollection) ForEachUpload(ctx context.Context, fn func(*models.Upload) error) error {
+	cursor, err := c.Collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var upload models.Upload
+		if err := cursor.Decode(&upload); err != nil {
+			return err
+		}
+		if err := fn(&upload); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// RepointStoragePath substitui um caminho de armazenamento inexistente por outro, tanto no
+// ponteiro atual do documento quanto nas versões que o usam
+func (c *DocCollection) RepointStoragePath(id primitive.ObjectID, oldPath string, newPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "version_history.storage_path": oldPath},
+		bson.M{
+			"$set": bson.M{"version_history.$[v].storage_path": newPath},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"v.storage_path": oldPath}},
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "storage_path": oldPath},
+		bson.M{
+			"$set": bson.M{"storage_path": newPath},
+			"$inc": bson.M{"revision": 1},
+		},
+	)
+	return err
+}
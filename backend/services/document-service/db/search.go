@@ -0,0 +1,299 @@
+package db
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/pagination"
+	"gestor-e-docs/document-service/querylang"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxFacetValues limita os valores retornados por faceta
+	maxFacetValues = 20
+	// maxFacetMonths limita os meses retornados na faceta de criação
+	maxFacetMonths = 24
+)
+
+// scoredDocument é um documento acompanhado da relevância calculada pela busca textual
+type scoredDocument struct {
+	models.Document `bson:",inline"`
+	Score           float64 `bson:"score,omitempty"`
+}
+
+// SearchFacets conta os documentos que atendem ao mesmo filtro de SearchDocuments por
+// tag, categoria, status, autor e mês de criação
+func (c *DocCollection) SearchFacets(query *models.DocumentSearchQuery) (*models.SearchFacets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	expr, err := querylang.Parse(query.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	month := bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$created_at"}}
+
+	pipeline := bson.A{
+		bson.M{"$match": buildSearchFilter(query, expr)},
+		bson.M{"$facet": bson.M{
+			"tags":          arrayFacet("$tags", maxFacetValues),
+			"categories":    arrayFacet("$categories", maxFacetValues),
+			"status":        valueFacet("$status", bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}, maxFacetValues),
+			"author":        valueFacet("$author_id", bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}, maxFacetValues),
+			"created_month": valueFacet(month, bson.D{{Key: "_id", Value: -1}}, maxFacetMonths),
+		}},
+	}
+
+	cursor, err := c.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	facets := &models.SearchFacets{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(facets); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// arrayFacet conta os valores de um campo de lista, do mais frequente ao menos frequente
+func arrayFacet(field string, limit int) bson.A {
+	return append(bson.A{bson.M{"$unwind": field}},
+		valueFacet(field, bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}, limit)...)
+}
+
+// valueFacet agrupa os documentos pela expressão informada e conta cada grupo
+func valueFacet(expr interface{}, sort bson.D, limit int) bson.A {
+	return bson.A{
+		bson.M{"$group": bson.M{"_id": expr, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": sort},
+		bson.M{"$limit": limit},
+	}
+}
+
+// queryFilter traduz a consulta interpretada para o MongoDB. Os termos livres do nível principal
+// vão para o índice textual, que calcula a relevância; cada um é enviado como frase, para que
+// todos sejam exigidos. Os demais itens viram condições do filtro.
+func queryFilter(expr querylang.Expr, viewerID string) (string, bson.A) {
+	items := []querylang.Expr{expr}
+	if and, ok := expr.(*querylang.And); ok {
+		items = and.Items
+	} else if expr == nil {
+		items = nil
+	}
+
+	var phrases []string
+	conditions := bson.A{}
+	for _, item := range items {
+		if text, ok := item.(*querylang.Text); ok {
+			// A barra invertida escaparia as aspas da frase na sintaxe do $text
+			phrases = append(phrases, `"`+strings.ReplaceAll(text.Value, `\`, " ")+`"`)
+			continue
+		}
+		conditions = append(conditions, exprFilter(item, viewerID))
+	}
+
+	return strings.Join(phrases, " "), conditions
+}
+
+// exprFilter traduz um item da consulta em uma condição do MongoDB. Fora do nível principal
+// o índice textual não pode ser usado, então os termos livres são procurados por expressão regular.
+func exprFilter(expr querylang.Expr, viewerID string) bson.M {
+	switch e := expr.(type) {
+	case *querylang.And:
+		return bson.M{"$and": exprFilters(e.Items, viewerID)}
+
+	case *querylang.Or:
+		return bson.M{"$or": exprFilters(e.Items, viewerID)}
+
+	case *querylang.Not:
+		return bson.M{"$nor": bson.A{exprFilter(e.Item, viewerID)}}
+
+	case *querylang.Text:
+		return bson.M{"$or": bson.A{
+			bson.M{"title": containsPattern(e.Value)},
+			bson.M{"content": containsPattern(e.Value)},
+		}}
+
+	case *querylang.Match:
+		switch e.Field {
+		case querylang.FieldTag:
+			return bson.M{"tags": e.Value}
+		case querylang.FieldCategory:
+			return bson.M{"categories": e.Value}
+		case querylang.FieldTitle:
+			return bson.M{"title": containsPattern(e.Value)}
+		case querylang.FieldAuthor:
+			if e.Value == querylang.AuthorMe {
+				return bson.M{"author_id": viewerID}
+			}
+			return bson.M{"author_id": e.Value}
+		case querylang.FieldStatus:
+			// Documentos sem status são rascunhos (ver DocumentStatus.Normalize)
+			if e.Value == string(models.StatusDraft) {
+				return bson.M{"status": bson.M{"$in": bson.A{e.Value, "", nil}}}
+			}
+			return bson.M{"status": e.Value}
+		}
+
+	case *querylang.DateRange:
+		field := "created_at"
+		if e.Field == querylang.FieldUpdated {
+			field = "updated_at"
+		}
+		bounds := bson.M{}
+		if !e.From.IsZero() {
+			bounds["$gte"] = e.From
+		}
+		if !e.To.IsZero() {
+			bounds["$lt"] = e.To
+		}
+		return bson.M{field: bounds}
+	}
+
+	// Parse só produz os nós tratados acima
+	return bson.M{"_id": bson.M{"$exists": false}}
+}
+
+// exprFilters traduz uma lista de itens da consulta
+func exprFilters(items []querylang.Expr, viewerID string) bson.A {
+	filters := make(bson.A, 0, len(items))
+	for _, item := range items {
+		filters = append(filters, exprFilter(item, viewerID))
+	}
+	return filters
+}
+
+// containsPattern procura o texto em qualquer parte do campo, sem diferenciar maiúsculas
+func containsPattern(value string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}
+}
+
+// sortFields associa os campos de ordenação aceitos aos campos do documento
+var sortFields = map[string]string{
+	models.SortTitle:        "title",
+	models.SortCreatedAt:    "created_at",
+	models.SortUpdatedAt:    "updated_at",
+	models.SortViewCount:    "metadata.view_count",
+	models.SortVersionCount: "version_count",
+}
+
+// sortDocument monta a ordenação do MongoDB, desempatando pelo _id no sentido do último campo.
+// reverse inverte todos os sentidos, para percorrer a listagem de trás para frente.
+func sortDocument(keys []models.SortKey, reverse bool) bson.D {
+	sort := bson.D{}
+	order := 1
+	for _, key := range keys {
+		if key.Field == models.SortRelevance {
+			sort = append(sort, bson.E{Key: "score", Value: bson.M{"$meta": "textScore"}})
+			continue
+		}
+		order = 1
+		if key.Descending != reverse {
+			order = -1
+		}
+		sort = append(sort, bson.E{Key: sortFields[key.Field], Value: order})
+	}
+	return append(sort, bson.E{Key: "_id", Value: order})
+}
+
+// sortValues extrai de um documento os valores dos campos ordenados. Campos ausentes
+// ordenam como nulos.
+func sortValues(doc bson.Raw, keys []models.SortKey) []bson.RawValue {
+	values := make([]bson.RawValue, len(keys))
+	for i, key := range keys {
+		value, err := doc.LookupErr(strings.Split(sortFields[key.Field], ".")...)
+		if err != nil {
+			value = bson.RawValue{Type: bsontype.Null}
+		}
+		values[i] = value
+	}
+	return values
+}
+
+// keysetFilter seleciona os documentos depois da posição do cursor, no sentido em que ele
+// percorre a listagem: os que superam a posição no primeiro campo, os que empatam no primeiro
+// e a superam no segundo, e assim por diante até o _id. Documentos sem o campo ordenado ficam
+// antes de todos na ordem crescente e depois de todos na decrescente.
+func keysetFilter(keys []models.SortKey, position *pagination.Cursor) bson.M {
+	var after bson.A
+	var equal bson.A
+	ascending := true
+	for i, key := range keys {
+		field := sortFields[key.Field]
+		value := position.Values[i]
+		isNull := value.Type == bsontype.Null || value.Type == bsontype.Undefined
+		ascending = key.Descending == position.Backward
+
+		var beyond bson.M
+		switch {
+		case isNull && ascending:
+			beyond = bson.M{field: bson.M{"$ne": nil}}
+		case ascending:
+			beyond = bson.M{field: bson.M{"$gt": value}}
+		case !isNull:
+			beyond = bson.M{"$or": bson.A{bson.M{field: bson.M{"$lt": value}}, bson.M{field: nil}}}
+		}
+		if beyond != nil {
+			after = append(after, allOf(append(equal, beyond)))
+		}
+
+		if isNull {
+			equal = append(equal, bson.M{field: nil})
+		} else {
+			equal = append(equal, bson.M{field: value})
+		}
+	}
+
+	op := "$lt"
+	if ascending {
+		op = "$gt"
+	}
+	after = append(after, allOf(append(equal, bson.M{"_id": bson.M{op: position.ID}})))
+	return bson.M{"$or": after}
+}
+
+// allOf combina as condições com $and, dispensando-o quando há só uma
+func allOf(conditions bson.A) bson.M {
+	if len(conditions) == 1 {
+		return conditions[0].(bson.M)
+	}
+	return bson.M{"$and": append(bson.A{}, conditions...)}
+}
+
+// backfillVersionCounts preenche version_count nos documentos gravados antes do campo
+func backfillVersionCounts() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	result, err := DbCollections.Documents.Collection.UpdateMany(
+		ctx,
+		bson.M{"version_count": bson.M{"$exists": false}},
+		bson.A{bson.M{"$set": bson.M{
+			"version_count": bson.M{"$size": bson.M{"$ifNull": bson.A{"$version_history", bson.A{}}}},
+		}}},
+	)
+	if err != nil {
+		log.Printf("Erro ao preencher a contagem de versões dos documentos: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Contagem de versões preenchida em %d documentos", result.ModifiedCount)
+	}
+}
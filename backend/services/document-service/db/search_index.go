@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Idiomas aceitos em SEARCH_LANGUAGE
+const (
+	LanguagePortuguese = "pt"
+	LanguageEnglish    = "en"
+)
+
+// textIndexName é o nome do índice textual da coleção de documentos
+const textIndexName = "text_search"
+
+// SearchLanguage retorna o idioma padrão da busca textual, definido por SEARCH_LANGUAGE
+// (pt, o padrão, ou en). Ele determina o stemming do índice textual do MongoDB.
+func SearchLanguage() string {
+	switch strings.ToLower(os.Getenv("SEARCH_LANGUAGE")) {
+	case "en", "en-us", "english":
+		return LanguageEnglish
+	default:
+		return LanguagePortuguese
+	}
+}
+
+// textIndexModel define o índice textual sobre título e conteúdo no idioma configurado
+func textIndexModel() mongo.IndexModel {
+	language := "portuguese"
+	if SearchLanguage() == LanguageEnglish {
+		language = "english"
+	}
+
+	return mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "title", Value: "text"}, bson.E{Key: "content", Value: "text"}},
+		Options: options.Index().
+			SetName(textIndexName).
+			SetDefaultLanguage(language).
+			SetWeights(bson.D{
+				bson.E{Key: "title", Value: 10},
+				bson.E{Key: "content", Value: 5},
+			}),
+	}
+}
+
+// RebuildTextIndex recria o índice textual, aplicando o idioma configurado atualmente
+func (c *DocCollection) RebuildTextIndex(ctx context.Context) error {
+	// O índice pode não existir, por exemplo quando a criação anterior falhou
+	if _, err := c.Collection.Indexes().DropOne(ctx, textIndexName); err != nil {
+		log.Printf("Índice textual não removido antes da recriação: %v", err)
+	}
+
+	_, err := c.Collection.Indexes().CreateOne(ctx, textIndexModel())
+	return err
+}
+
+// FindReadableDocuments busca os documentos informados que não estão na lixeira e que o
+// usuário pode ler, indexados pelo ID. IDs inexistentes ou ilegíveis são omitidos. Sem
+// withContent, o conteúdo não é lido.
+func (c *DocCollection) FindReadableDocuments(ids []primitive.ObjectID, viewerID string, readFolders []string, withContent bool) (map[primitive.ObjectID]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "deleted_at": nil}
+	if viewerID != "" {
+		filter["$and"] = bson.A{scopeFilter(viewerID, models.ScopeAll, readFolders)}
+	}
+
+	opts := options.Find()
+	if !withContent {
+		opts.SetProjection(bson.M{"content": 0})
+	}
+
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := make(map[primitive.ObjectID]models.Document, len(ids))
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		documents[doc.ID] = doc
+	}
+	return documents, cursor.Err()
+}
+
+// ForEachDocument percorre todos os documentos fora da lixeira, sem o histórico de versões,
+// para reconstruir índices de busca. A iteração para no primeiro erro de fn.
+func (c *DocCollection) ForEachDocument(ctx context.Context, fn func(*models.Document) error) error {
+	return c.forEachIndexable(ctx, bson.M{"deleted_at": nil}, fn)
+}
+
+// ForEachChangedDocument percorre, como ForEachDocument, os documentos alterados ou
+// visualizados a partir de since, para atualizar índices de busca
+func (c *DocCollection) ForEachChangedDocument(ctx context.Context, since time.Time, fn func(*models.Document) error) error {
+	return c.forEachIndexable(ctx, bson.M{
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"updated_at": bson.M{"$gte": since}},
+			bson.M{"metadata.last_viewed_at": bson.M{"$gte": since}},
+		},
+	}, fn)
+}
+
+// forEachIndexable percorre os documentos do filtro sem o histórico de versões
+func (c *DocCollection) forEachIndexable(ctx context.Context, filter bson.M, fn func(*models.Document) error) error {
+	opts := options.Find().
+		SetProjection(bson.M{"version_history": 0}).
+		SetBatchSize(100)
+
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rawValue converte o valor no formato guardado no cursor
+func rawValue(t *testing.T, value interface{}) bson.RawValue {
+	t.Helper()
+	kind, data, err := bson.MarshalValue(value)
+	if err != nil {
+		t.Fatalf("MarshalValue(%v): %v", value, err)
+	}
+	return bson.RawValue{Type: kind, Value: data}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	null := bson.RawValue{Type: bsontype.Null}
+	title := rawValue(t, "Plano")
+	views := rawValue(t, int64(5))
+
+	tests := []struct {
+		name     string
+		keys     []models.SortKey
+		values   []bson.RawValue
+		backward bool
+		want     bson.M
+	}{
+		{
+			name:   "crescente",
+			keys:   []models.SortKey{{Field: models.SortTitle}},
+			values: []bson.RawValue{title},
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$gt": title}},
+				bson.M{"$and": bson.A{bson.M{"title": title}, bson.M{"_id": bson.M{"$gt": id}}}},
+			}},
+		},
+		{
+			// Na ordem decrescente os nulos vêm depois de todos os valores
+			name:   "decrescente inclui os nulos depois",
+			keys:   []models.SortKey{{Field: models.SortTitle, Descending: true}},
+			values: []bson.RawValue{title},
+			want: bson.M{"$or": bson.A{
+				bson.M{"$or": bson.A{bson.M{"title": bson.M{"$lt": title}}, bson.M{"title": nil}}},
+				bson.M{"$and": bson.A{bson.M{"title": title}, bson.M{"_id": bson.M{"$lt": id}}}},
+			}},
+		},
+		{
+			// Na ordem crescente os nulos vêm antes: depois de um nulo vem qualquer valor
+			name:   "crescente a partir de nulo",
+			keys:   []models.SortKey{{Field: models.SortTitle}},
+			values: []bson.RawValue{null},
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$ne": nil}},
+				bson.M{"$and": bson.A{bson.M{"title": nil}, bson.M{"_id": bson.M{"$gt": id}}}},
+			}},
+		},
+		{
+			// Na ordem decrescente nada supera um nulo; só resta desempatar pelo _id
+			name:   "decrescente a partir de nulo",
+			keys:   []models.SortKey{{Field: models.SortTitle, Descending: true}},
+			values: []bson.RawValue{null},
+			want: bson.M{"$or": bson.A{
+				bson.M{"$and": bson.A{bson.M{"title": nil}, bson.M{"_id": bson.M{"$lt": id}}}},
+			}},
+		},
+		{
+			name:     "voltando na ordem decrescente percorre a crescente",
+			keys:     []models.SortKey{{Field: models.SortTitle, Descending: true}},
+			values:   []bson.RawValue{null},
+			backward: true,
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$ne": nil}},
+				bson.M{"$and": bson.A{bson.M{"title": nil}, bson.M{"_id": bson.M{"$gt": id}}}},
+			}},
+		},
+		{
+			name:     "voltando na ordem crescente percorre a decrescente",
+			keys:     []models.SortKey{{Field: models.SortTitle}},
+			values:   []bson.RawValue{title},
+			backward: true,
+			want: bson.M{"$or": bson.A{
+				bson.M{"$or": bson.A{bson.M{"title": bson.M{"$lt": title}}, bson.M{"title": nil}}},
+				bson.M{"$and": bson.A{bson.M{"title": title}, bson.M{"_id": bson.M{"$lt": id}}}},
+			}},
+		},
+		{
+			name: "dois campos com nulo no segundo",
+			keys: []models.SortKey{
+				{Field: models.SortViewCount, Descending: true},
+				{Field: models.SortUpdatedAt, Descending: true},
+			},
+			values: []bson.RawValue{views, null},
+			want: bson.M{"$or": bson.A{
+				bson.M{"$or": bson.A{bson.M{"metadata.view_count": bson.M{"$lt": views}}, bson.M{"metadata.view_count": nil}}},
+				bson.M{"$and": bson.A{bson.M{"metadata.view_count": views}, bson.M{"updated_at": nil}, bson.M{"_id": bson.M{"$lt": id}}}},
+			}},
+		},
+		{
+			name: "dois campos com nulo no primeiro",
+			keys: []models.SortKey{
+				{Field: models.SortTitle},
+				{Field: models.SortUpdatedAt},
+			},
+			values: []bson.RawValue{null, null},
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$ne": nil}},
+				bson.M{"$and": bson.A{bson.M{"title": nil}, bson.M{"updated_at": bson.M{"$ne": nil}}}},
+				bson.M{"$and": bson.A{bson.M{"title": nil}, bson.M{"updated_at": nil}, bson.M{"_id": bson.M{"$gt": id}}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := &pagination.Cursor{Values: tt.values, ID: id, Backward: tt.backward}
+			if got := keysetFilter(tt.keys, position); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keysetFilter() =\n%v\nesperado\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []models.SortKey
+		reverse bool
+		want    bson.D
+	}{
+		{
+			name: "desempate no sentido do último campo",
+			keys: []models.SortKey{{Field: models.SortTitle}, {Field: models.SortUpdatedAt, Descending: true}},
+			want: bson.D{{Key: "title", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			name:    "invertida",
+			keys:    []models.SortKey{{Field: models.SortTitle}, {Field: models.SortUpdatedAt, Descending: true}},
+			reverse: true,
+			want:    bson.D{{Key: "title", Value: -1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortDocument(tt.keys, tt.reverse); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortDocument() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrShareLinkUnavailable indica que o link foi revogado, expirou ou atingiu o limite de usos
+var ErrShareLinkUnavailable = errors.New("link de compartilhamento indisponível")
+
+// ShareLinkCollection encapsula as operações na coleção de links públicos
+type ShareLinkCollection struct {
+	Collection *mongo.Collection
+	Attempts   *mongo.Collection // Senhas incorretas por link e cliente
+}
+
+// InsertLink insere um novo link público
+func (c *ShareLinkCollection) InsertLink(link *models.ShareLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link.ID = primitive.NewObjectID()
+	link.CreatedAt = time.Now()
+	link.UseCount = 0
+
+	_, err := c.Collection.InsertOne(ctx, link)
+	return err
+}
+
+// GetByTokenHash busca um link pelo hash do token
+func (c *ShareLinkCollection) GetByTokenHash(tokenHash string) (*models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var link models.ShareLink
+	err := c.Collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListByDocument lista os links de um documento, dos mais recentes para os mais antigos
+func (c *ShareLinkCollection) ListByDocument(documentID string) ([]models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: -1}})
+	cursor, err := c.Collection.Find(ctx, bson.M{"document_id": documentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeLink revoga um link ativo de um documento
+func (c *ShareLinkCollection) RevokeLink(id string, documentID string, userID string) (*models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	linkID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked models.ShareLink
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": linkID, "document_id": documentID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_by": userID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&revoked)
+	if err != nil {
+		return nil, err
+	}
+	return &revoked, nil
+}
+
+// ConsumeUse registra um acesso ao link de forma atômica, falhando com
+// ErrShareLinkUnavailable se o link não puder mais ser usado
+func (c *ShareLinkCollection) ConsumeUse(id primitive.ObjectID) (*models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":        id,
+		"revoked_at": nil,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"expires_at": nil},
+				bson.M{"expires_at": bson.M{"$gt": now}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"max_uses": bson.M{"$lte": 0}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$use_count", "$max_uses"}}},
+			}},
+		},
+	}
+
+	var updated models.ShareLink
+	err := c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$inc": bson.M{"use_count": 1},
+			"$set": bson.M{"last_used_at": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrShareLinkUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// PasswordLockedUntil retorna o fim do bloqueio de senhas do cliente no link, ou nil se
+// o cliente pode tentar
+func (c *ShareLinkCollection) PasswordLockedUntil(id primitive.ObjectID, clientKey string) (*time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var attempts models.ShareLinkAttempts
+	err := c.Attempts.FindOne(ctx, bson.M{
+		"link_id":      id,
+		"client_key":   clientKey,
+		"locked_until": bson.M{"$gt": time.Now()},
+	}).Decode(&attempts)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attempts.LockedUntil, nil
+}
+
+// RecordFailedPassword conta uma senha incorreta do cliente no link. Ao atingir maxAttempts
+// tentativas seguidas dentro do período de lockout, a contagem recomeça e o cliente fica
+// bloqueado por lockout; nesse caso o fim do bloqueio é retornado.
+func (c *ShareLinkCollection) RecordFailedPassword(id primitive.ObjectID, clientKey string, maxAttempts int64, lockout time.Duration) (*time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"link_id": id, "client_key": clientKey}
+	now := time.Now()
+
+	var updated models.ShareLinkAttempts
+	err := c.Attempts.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$inc": bson.M{"failed": 1},
+			"$set": bson.M{"expires_at": now.Add(lockout)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return nil, err
+	}
+	if updated.Failed < maxAttempts {
+		return nil, nil
+	}
+
+	lockedUntil := now.Add(lockout)
+	_, err = c.Attempts.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"failed": 0, "locked_until": lockedUntil, "expires_at": lockedUntil}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &lockedUntil, nil
+}
+
+// ResetFailedPasswords descarta as senhas incorretas do cliente após um acesso com a senha certa
+func (c *ShareLinkCollection) ResetFailedPasswords(id primitive.ObjectID, clientKey string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Attempts.DeleteOne(ctx, bson.M{"link_id": id, "client_key": clientKey})
+	return err
+}
+
+// RevokeByDocument revoga todos os links ativos de um documento
+func (c *ShareLinkCollection) RevokeByDocument(documentID string, userID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := c.Collection.UpdateMany(
+		ctx,
+		bson.M{"document_id": documentID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_by": userID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Operações da lixeira de documentos
+
+// SoftDeleteDocument move o documento para a lixeira, desde que ele ainda esteja na revisão
+// informada e que nenhum outro usuário detenha a reserva de edição
+func (c *DocCollection) SoftDeleteDocument(id string, userID string, expectedRevision int64) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := revisionFilter(docID, expectedRevision)
+	filter["deleted_at"] = nil
+	filter["$or"] = editableBy(userID, now)["$or"]
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"deleted_at": now,
+				"deleted_by": userID,
+			},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, c.lockFailureReason(ctx, docID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// RestoreDocument retira o documento da lixeira, opcionalmente movendo-o para outra pasta
+func (c *DocCollection) RestoreDocument(id string, folderID string, expectedRevision int64) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Só documentos ainda na lixeira: uma restauração repetida não deve mover o documento
+	filter := revisionFilter(docID, expectedRevision)
+	filter["deleted_at"] = bson.M{"$ne": nil}
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"folder_id":  folderID,
+				"updated_at": time.Now(),
+			},
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+			"$inc":   bson.M{"revision": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRevisionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// GetDeletedDocumentByID busca um documento que está na lixeira
+func (c *DocCollection) GetDeletedDocumentByID(id string) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var document models.Document
+	err = c.Collection.FindOne(ctx, bson.M{"_id": docID, "deleted_at": bson.M{"$ne": nil}}).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// ListTrash lista os documentos na lixeira que pertencem ao usuário ou foram excluídos por ele
+func (c *DocCollection) ListTrash(userID string, offset, limit int) ([]models.Document, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = 10 // Limite padrão
+	}
+	if limit > 100 {
+		limit = 100 // Limite máximo
+	}
+
+	filter := bson.M{
+		"deleted_at": bson.M{"$ne": nil},
+		"$or": bson.A{
+			bson.M{"permissions.owner_id": userID},
+			bson.M{"deleted_by": userID},
+		},
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "deleted_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"content": 0})
+
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []models.Document{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := c.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return docs, total, nil
+}
+
+// ListExpiredTrash lista documentos que estão na lixeira desde antes do instante informado
+func (c *DocCollection) ListExpiredTrash(before time.Time, limit int) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "deleted_at", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"content": 0})
+
+	cursor, err := c.Collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []models.Document{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrUploadOffsetMismatch indica que o offset informado não corresponde aos bytes já recebidos
+var ErrUploadOffsetMismatch = errors.New("o offset informado não corresponde ao do envio")
+
+// ErrUploadBusy indica que outra requisição está enviando dados para o mesmo envio
+var ErrUploadBusy = errors.New("o envio está recebendo dados de outra requisição")
+
+// UploadCollection encapsula as operações na coleção de envios de arquivos
+type UploadCollection struct {
+	Collection *mongo.Collection
+}
+
+// InsertUpload registra um novo envio retomável
+func (c *UploadCollection) InsertUpload(upload *models.Upload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	upload.ID = primitive.NewObjectID()
+	now := time.Now()
+	upload.CreatedAt = now
+	upload.UpdatedAt = now
+	if upload.Parts == nil {
+		upload.Parts = []models.UploadPart{}
+	}
+
+	_, err := c.Collection.InsertOne(ctx, upload)
+	return err
+}
+
+// GetUpload busca um envio do usuário pelo ID
+func (c *UploadCollection) GetUpload(id string, userID string) (*models.Upload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	uploadID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var upload models.Upload
+	err = c.Collection.FindOne(ctx, bson.M{"_id": uploadID, "user_id": userID}).Decode(&upload)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// AcquireLease reserva o envio para receber dados a partir do offset informado.
+// Falha com ErrUploadOffsetMismatch se o offset não for o atual e com ErrUploadBusy
+// se outra requisição já estiver enviando dados.
+func (c *UploadCollection) AcquireLease(id string, userID string, protocol models.UploadProtocol, offset int64, ttl time.Duration) (*models.Upload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	uploadID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":          uploadID,
+		"user_id":      userID,
+		"protocol":     protocol,
+		"offset":       offset,
+		"completed_at": nil,
+		"$or": bson.A{
+			bson.M{"lease_until": nil},
+			bson.M{"lease_until": bson.M{"$lte": now}},
+		},
+	}
+
+	var upload models.Upload
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"lease_until": now.Add(ttl)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&upload)
+	if err == mongo.ErrNoDocuments {
+		// Identificar o motivo da falha
+		current, getErr := c.GetUpload(id, userID)
+		if getErr != nil {
+			return nil, getErr
+		}
+		if current.Protocol != protocol {
+			return nil, mongo.ErrNoDocuments
+		}
+		if current.Offset != offset || current.IsComplete() {
+			return nil, ErrUploadOffsetMismatch
+		}
+		return nil, ErrUploadBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// SaveProgress grava as partes e o trecho pendente recebidos, renova a expiração e libera a reserva
+func (c *UploadCollection) SaveProgress(upload *models.Upload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	upload.Offset = upload.PartsSize() + upload.PendingSize
+	upload.UpdatedAt = time.Now()
+
+	_, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": upload.ID},
+		bson.M{
+			"$set": bson.M{
+				"offset":       upload.Offset,
+				"parts":        upload.Parts,
+				"pending_size": upload.PendingSize,
+				"expires_at":   upload.ExpiresAt,
+				"updated_at":   upload.UpdatedAt,
+			},
+			"$unset": bson.M{"lease_until": ""},
+		},
+	)
+	return err
+}
+
+// MarkCompleted registra a conclusão do envio e o documento criado a partir dele
+func (c *UploadCollection) MarkCompleted(id primitive.ObjectID, documentID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"completed_at": time.Now(), "document_id": documentID},
+			"$unset": bson.M{"lease_until": ""},
+		},
+	)
+	return err
+}
+
+// DeleteUpload remove o registro de um envio
+func (c *UploadCollection) DeleteUpload(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// ListExpired lista os envios cujo prazo de expiração já passou
+func (c *UploadCollection) ListExpired(before time.Time, limit int) ([]models.Upload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "expires_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := c.Collection.Find(ctx, bson.M{"expires_at": bson.M{"$lte": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	uploads := []models.Upload{}
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LegacyVersionPathPattern identifica os caminhos que a edição de conteúdo registrava nas versões
+// antes da correção do modelo de versões: o caminho do documento seguido de ".v" e um ObjectID.
+// Nenhum objeto era gravado nesses caminhos.
+const LegacyVersionPathPattern = `\.v[0-9a-f]{24}$`
+
+// ForEachLegacyVersionDocument percorre os documentos, inclusive os da lixeira, que ainda têm
+// versões com caminhos inventados. A varredura para no primeiro erro de fn.
+func (c *DocCollection) ForEachLegacyVersionDocument(ctx context.Context, fn func(*models.Document) error) error {
+	filter := bson.M{
+		"version_history.storage_path": primitive.Regex{Pattern: LegacyVersionPathPattern},
+	}
+
+	cursor, err := c.Collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// RewriteVersionStorage grava o histórico de versões corrigido e, com current, faz o documento
+// apontar para o conteúdo da versão atual. Retorna ErrRevisionConflict se o documento mudou
+// desde a leitura.
+func (c *DocCollection) RewriteVersionStorage(id primitive.ObjectID, revision int64, versions []models.Version, current *models.VersionContent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := bson.M{"version_history": versions, "version_count": len(versions)}
+	if current != nil {
+		setCurrentContent(fields, current)
+	}
+
+	result, err := c.Collection.UpdateOne(
+		ctx,
+		revisionFilter(id, revision),
+		bson.M{
+			"$set": fields,
+			"$inc": bson.M{"revision": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrRevisionConflict
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDocumentNotEditable indica que o estado do documento no fluxo de aprovação não permite edição
+var ErrDocumentNotEditable = errors.New("o documento não pode ser editado no estado atual")
+
+// UpdateWorkflow grava o novo estado do documento e os dados do fluxo de aprovação, desde
+// que ele ainda esteja na revisão informada. Com userID, exige também que nenhum outro usuário
+// detenha a reserva de edição; com userID vazio (decisões dos revisores), a reserva é ignorada.
+// Ao sair do rascunho, a reserva de edição é liberada.
+func (c *DocCollection) UpdateWorkflow(id string, status models.DocumentStatus, workflow models.DocumentWorkflow, userID string, expectedRevision int64) (*models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if workflow.Reviewers == nil {
+		workflow.Reviewers = []string{}
+	}
+	if workflow.Decisions == nil {
+		workflow.Decisions = []models.WorkflowDecision{}
+	}
+
+	now := time.Now()
+	filter := revisionFilter(docID, expectedRevision)
+	if userID != "" {
+		filter["$or"] = editableBy(userID, now)["$or"]
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":     status,
+			"workflow":   workflow,
+			"updated_at": now,
+		},
+		"$inc": bson.M{"revision": 1},
+	}
+	if !status.IsEditable() {
+		update["$unset"] = bson.M{"lock": ""}
+	}
+
+	var updated models.Document
+	err = c.Collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		if userID == "" {
+			return nil, ErrRevisionConflict
+		}
+		return nil, c.lockFailureReason(ctx, docID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
@@ -0,0 +1,445 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OpKind identifica o tipo de operação de uma linha ou palavra no diff
+type OpKind string
+
+const (
+	OpEqual  OpKind = "equal"
+	OpInsert OpKind = "insert"
+	OpDelete OpKind = "delete"
+)
+
+// DefaultContext é o número padrão de linhas de contexto ao redor de cada alteração
+const DefaultContext = 3
+
+// MaxEdits limita as linhas inseridas e removidas que o diff procura antes de desistir.
+// O custo do algoritmo cresce com o número de linhas vezes o de alterações; acima do limite
+// o resultado só informa que as versões são diferentes demais para comparar.
+const MaxEdits = 5000
+
+// maxWordEdits limita o diff por palavra de cada par de linhas; acima dele as linhas são
+// marcadas como inteiramente alteradas
+const maxWordEdits = 500
+
+// WordChange representa um trecho de uma linha modificada
+type WordChange struct {
+	Kind OpKind `json:"kind"`
+	Text string `json:"text"`
+}
+
+// Line representa uma linha do diff com sua numeração nas versões de origem e destino
+type Line struct {
+	Kind      OpKind       `json:"kind"`
+	OldNumber int          `json:"old_number,omitempty"`
+	NewNumber int          `json:"new_number,omitempty"`
+	Text      string       `json:"text"`
+	Words     []WordChange `json:"words,omitempty"` // Presente apenas em linhas modificadas
+}
+
+// Hunk agrupa alterações próximas com suas linhas de contexto
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Result é o resultado estruturado da comparação entre dois textos
+type Result struct {
+	Hunks        []Hunk `json:"hunks"`
+	Added        int    `json:"added"`
+	Removed      int    `json:"removed"`
+	Unified      string `json:"unified"`
+	TooDifferent bool   `json:"too_different"` // As versões excedem MaxEdits; Hunks fica vazio
+}
+
+// edit é uma operação elementar produzida pelo algoritmo de Myers
+type edit struct {
+	kind OpKind
+	a    int // Índice na sequência de origem (-1 para inserções)
+	b    int // Índice na sequência de destino (-1 para remoções)
+}
+
+var wordPattern = regexp.MustCompile(`\s+|[\p{L}\p{N}_]+|[^\s\p{L}\p{N}_]`)
+
+// Compute compara dois textos linha a linha e retorna os hunks com as alterações
+func Compute(oldText, newText string, context int) *Result {
+	if context < 0 {
+		context = DefaultContext
+	}
+
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+	result := &Result{Hunks: []Hunk{}}
+	edits, ok := myers(oldLines, newLines, MaxEdits)
+	if !ok {
+		// Sem o script de edição, contar as versões como inteiramente substituídas
+		result.TooDifferent = true
+		result.Added = len(newLines)
+		result.Removed = len(oldLines)
+		return result
+	}
+
+	// Converter as operações em linhas numeradas
+	lines := make([]Line, 0, len(edits))
+	for _, e := range edits {
+		switch e.kind {
+		case OpEqual:
+			lines = append(lines, Line{Kind: OpEqual, OldNumber: e.a + 1, NewNumber: e.b + 1, Text: oldLines[e.a]})
+		case OpDelete:
+			lines = append(lines, Line{Kind: OpDelete, OldNumber: e.a + 1, Text: oldLines[e.a]})
+			result.Removed++
+		case OpInsert:
+			lines = append(lines, Line{Kind: OpInsert, NewNumber: e.b + 1, Text: newLines[e.b]})
+			result.Added++
+		}
+	}
+
+	annotateWordChanges(lines)
+	result.Hunks = buildHunks(lines, context)
+	result.Unified = renderUnified(result.Hunks)
+
+	return result
+}
+
+// splitLines divide o texto em linhas, normalizando quebras de linha do Windows
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	return strings.Split(text, "\n")
+}
+
+// myers calcula o script de edição mínimo entre duas sequências pelo algoritmo O(ND) de Myers
+// na variante de espaço linear: cada passo procura a "cobra do meio" do caminho mínimo e
+// resolve as duas metades recursivamente, usando memória O(N+M) em vez de guardar o
+// histórico de cada passo. Com maxD >= 0, desiste e retorna false quando as sequências
+// precisam de mais de maxD operações.
+func myers(a, b []string, maxD int) ([]edit, bool) {
+	s := &myersState{a: a, b: b}
+	if !s.compare(0, len(a), 0, len(b), maxD) {
+		return nil, false
+	}
+	edits := s.edits
+
+	// Apresentar remoções antes das inserções em cada bloco de alterações
+	for start := 0; start < len(edits); {
+		if edits[start].kind == OpEqual {
+			start++
+			continue
+		}
+		end := start
+		for end < len(edits) && edits[end].kind != OpEqual {
+			end++
+		}
+		block := make([]edit, 0, end-start)
+		for _, e := range edits[start:end] {
+			if e.kind == OpDelete {
+				block = append(block, e)
+			}
+		}
+		for _, e := range edits[start:end] {
+			if e.kind == OpInsert {
+				block = append(block, e)
+			}
+		}
+		copy(edits[start:end], block)
+		start = end
+	}
+
+	return edits, true
+}
+
+// myersState acumula as operações produzidas pela recursão do algoritmo de Myers
+type myersState struct {
+	a, b  []string
+	edits []edit
+}
+
+// compare emite as operações que transformam a[aLo:aHi] em b[bLo:bHi]
+func (s *myersState) compare(aLo, aHi, bLo, bHi, maxD int) bool {
+	// Prefixo e sufixo comuns não precisam entrar na busca
+	for aLo < aHi && bLo < bHi && s.a[aLo] == s.b[bLo] {
+		s.edits = append(s.edits, edit{kind: OpEqual, a: aLo, b: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && s.a[aHi-suffix-1] == s.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for j := bLo; j < bHi; j++ {
+			s.edits = append(s.edits, edit{kind: OpInsert, a: -1, b: j})
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			s.edits = append(s.edits, edit{kind: OpDelete, a: i, b: -1})
+		}
+	default:
+		// Sem prefixo nem sufixo comum, são pelo menos duas operações e cada metade
+		// fica com menos operações que o todo, o que garante o fim da recursão
+		x, y, u, v, ok := s.middleSnake(aLo, aHi, bLo, bHi, maxD)
+		if !ok {
+			return false
+		}
+		s.compare(aLo, x, bLo, y, -1)
+		for i := 0; i < u-x; i++ {
+			s.edits = append(s.edits, edit{kind: OpEqual, a: x + i, b: y + i})
+		}
+		s.compare(u, aHi, v, bHi, -1)
+	}
+
+	for i := suffix; i > 0; i-- {
+		s.edits = append(s.edits, edit{kind: OpEqual, a: aHi + suffix - i, b: bHi + suffix - i})
+	}
+	return true
+}
+
+// middleSnake procura simultaneamente a partir do início e do fim até os caminhos se
+// encontrarem, retornando o trecho diagonal (x,y)-(u,v) do meio do caminho mínimo
+func (s *myersState) middleSnake(aLo, aHi, bLo, bHi, maxD int) (x, y, u, v int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	half := (n + m + 1) / 2
+
+	offset := half + 1
+	forward := make([]int, 2*half+3)
+	backward := make([]int, 2*half+3)
+
+	for d := 0; d <= half; d++ {
+		if maxD >= 0 && 2*d-1 > maxD {
+			return 0, 0, 0, 0, false
+		}
+
+		// Caminhos a partir do início
+		for k := -d; k <= d; k += 2 {
+			var px int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				px = forward[offset+k+1]
+			} else {
+				px = forward[offset+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && s.a[aLo+px] == s.b[bLo+py] {
+				px++
+				py++
+			}
+			forward[offset+k] = px
+
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) && px+backward[offset+delta-k] >= n {
+				return aLo + sx, bLo + sy, aLo + px, bLo + py, true
+			}
+		}
+
+		// Caminhos a partir do fim, contados de trás para frente
+		for k := -d; k <= d; k += 2 {
+			var px int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				px = backward[offset+k+1]
+			} else {
+				px = backward[offset+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && s.a[aHi-px-1] == s.b[bHi-py-1] {
+				px++
+				py++
+			}
+			backward[offset+k] = px
+
+			if !odd && delta-k >= -d && delta-k <= d && px+forward[offset+delta-k] >= n {
+				if maxD >= 0 && 2*d > maxD {
+					return 0, 0, 0, 0, false
+				}
+				return aHi - px, bHi - py, aHi - sx, bHi - sy, true
+			}
+		}
+	}
+
+	// Inalcançável: o caminho mínimo tem no máximo n+m operações
+	return 0, 0, 0, 0, false
+}
+
+// annotateWordChanges emparelha linhas removidas e inseridas adjacentes e calcula o diff por palavra
+func annotateWordChanges(lines []Line) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != OpDelete {
+			i++
+			continue
+		}
+
+		delStart := i
+		for i < len(lines) && lines[i].Kind == OpDelete {
+			i++
+		}
+		insStart := i
+		for i < len(lines) && lines[i].Kind == OpInsert {
+			i++
+		}
+
+		pairs := insStart - delStart
+		if i-insStart < pairs {
+			pairs = i - insStart
+		}
+		for p := 0; p < pairs; p++ {
+			oldWords, newWords := wordDiff(lines[delStart+p].Text, lines[insStart+p].Text)
+			lines[delStart+p].Words = oldWords
+			lines[insStart+p].Words = newWords
+		}
+	}
+}
+
+// wordDiff compara duas linhas por palavra, retornando os trechos de cada lado
+func wordDiff(oldLine, newLine string) ([]WordChange, []WordChange) {
+	a := wordPattern.FindAllString(oldLine, -1)
+	b := wordPattern.FindAllString(newLine, -1)
+
+	edits, ok := myers(a, b, maxWordEdits)
+	if !ok {
+		return []WordChange{{Kind: OpDelete, Text: oldLine}}, []WordChange{{Kind: OpInsert, Text: newLine}}
+	}
+
+	var oldWords, newWords []WordChange
+	for _, e := range edits {
+		switch e.kind {
+		case OpEqual:
+			oldWords = appendWord(oldWords, OpEqual, a[e.a])
+			newWords = appendWord(newWords, OpEqual, b[e.b])
+		case OpDelete:
+			oldWords = appendWord(oldWords, OpDelete, a[e.a])
+		case OpInsert:
+			newWords = appendWord(newWords, OpInsert, b[e.b])
+		}
+	}
+	return oldWords, newWords
+}
+
+// appendWord agrega trechos consecutivos do mesmo tipo
+func appendWord(words []WordChange, kind OpKind, text string) []WordChange {
+	if n := len(words); n > 0 && words[n-1].Kind == kind {
+		words[n-1].Text += text
+		return words
+	}
+	return append(words, WordChange{Kind: kind, Text: text})
+}
+
+// buildHunks agrupa as linhas alteradas com até context linhas inalteradas ao redor
+func buildHunks(lines []Line, context int) []Hunk {
+	hunks := []Hunk{}
+
+	i := 0
+	oldBefore, newBefore := 0, 0
+	for i < len(lines) {
+		// Localizar a próxima alteração
+		for i < len(lines) && lines[i].Kind == OpEqual {
+			i++
+		}
+		if i >= len(lines) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Contar as linhas de cada versão anteriores ao início do hunk
+		for _, l := range lines[:start] {
+			if l.OldNumber > oldBefore {
+				oldBefore = l.OldNumber
+			}
+			if l.NewNumber > newBefore {
+				newBefore = l.NewNumber
+			}
+		}
+
+		// Estender o hunk enquanto as alterações estiverem próximas
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != OpEqual {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(lines) && lines[gap].Kind == OpEqual {
+				gap++
+			}
+			if gap < len(lines) && gap-end <= 2*context {
+				end = gap
+				continue
+			}
+			end += context
+			if end > len(lines) {
+				end = len(lines)
+			}
+			break
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end], oldBefore, newBefore))
+		i = end
+	}
+
+	return hunks
+}
+
+// newHunk calcula o cabeçalho de um hunk a partir das suas linhas e da quantidade
+// de linhas de cada versão que o antecedem
+func newHunk(lines []Line, oldBefore, newBefore int) Hunk {
+	hunk := Hunk{Lines: lines}
+	for _, l := range lines {
+		if l.Kind != OpInsert {
+			hunk.OldLines++
+		}
+		if l.Kind != OpDelete {
+			hunk.NewLines++
+		}
+	}
+
+	// Pela convenção do formato unificado, um lado vazio aponta para a linha anterior
+	hunk.OldStart = oldBefore
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	hunk.NewStart = newBefore
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	return hunk
+}
+
+// renderUnified gera a representação textual no formato de diff unificado
+func renderUnified(hunks []Hunk) string {
+	var sb strings.Builder
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case OpEqual:
+				sb.WriteString(" ")
+			case OpDelete:
+				sb.WriteString("-")
+			case OpInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,210 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// apply reconstrói as duas sequências a partir do script de edição, verificando os índices
+func apply(t *testing.T, a, b []string, edits []edit) {
+	t.Helper()
+	i, j := 0, 0
+	for _, e := range edits {
+		switch e.kind {
+		case OpEqual:
+			if e.a != i || e.b != j || a[e.a] != b[e.b] {
+				t.Fatalf("igualdade inválida %+v na posição (%d,%d)", e, i, j)
+			}
+			i++
+			j++
+		case OpDelete:
+			if e.a != i || e.b != -1 {
+				t.Fatalf("remoção inválida %+v na posição %d", e, i)
+			}
+			i++
+		case OpInsert:
+			if e.b != j || e.a != -1 {
+				t.Fatalf("inserção inválida %+v na posição %d", e, j)
+			}
+			j++
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("script termina em (%d,%d), esperado (%d,%d)", i, j, len(a), len(b))
+	}
+}
+
+// distance conta as remoções e inserções do script
+func distance(edits []edit) int {
+	d := 0
+	for _, e := range edits {
+		if e.kind != OpEqual {
+			d++
+		}
+	}
+	return d
+}
+
+// lcsDistance calcula a distância mínima por programação dinâmica, para comparação
+func lcsDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				cur[j] = prev[j-1] + 1
+			case prev[j] > cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev = cur
+	}
+	return len(a) + len(b) - 2*prev[len(b)]
+}
+
+// describeEdits resume o script em uma letra por operação
+func describeEdits(edits []edit) string {
+	var sb strings.Builder
+	for _, e := range edits {
+		switch e.kind {
+		case OpEqual:
+			sb.WriteByte('=')
+		case OpDelete:
+			sb.WriteByte('-')
+		case OpInsert:
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+func TestMyers(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{"vazias", "", "", ""},
+		{"iguais", "abc", "abc", "==="},
+		{"só inserções", "", "ab", "++"},
+		{"só remoções", "ab", "", "--"},
+		{"inserção no meio", "ac", "abc", "=+="},
+		{"remoção no fim", "abc", "ab", "==-"},
+		{"substituição remove antes de inserir", "axc", "ayc", "=-+="},
+		{"bloco trocado", "abcd", "axyd", "=--++="},
+		{"sem nada em comum", "ab", "cd", "--++"},
+		{"trecho repetido", "abcabba", "cbabac", ""},
+		{"fim em comum", "xxabc", "yabc", "--+==="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := strings.Split(tt.a, "")
+			b := strings.Split(tt.b, "")
+			edits, ok := myers(a, b, -1)
+			if !ok {
+				t.Fatalf("myers(%q, %q) desistiu sem limite", tt.a, tt.b)
+			}
+			apply(t, a, b, edits)
+			if got, want := distance(edits), lcsDistance(a, b); got != want {
+				t.Errorf("myers(%q, %q) usou %d operações, mínimo %d", tt.a, tt.b, got, want)
+			}
+			if tt.want != "" && describeEdits(edits) != tt.want {
+				t.Errorf("myers(%q, %q) = %s, esperado %s", tt.a, tt.b, describeEdits(edits), tt.want)
+			}
+		})
+	}
+}
+
+func TestMyersMinimal(t *testing.T) {
+	// Sequências aleatórias de alfabeto pequeno exercitam as cobras do meio pares e ímpares
+	random := rand.New(rand.NewSource(1))
+	sequence := func() []string {
+		s := make([]string, random.Intn(30))
+		for i := range s {
+			s[i] = string(rune('a' + random.Intn(3)))
+		}
+		return s
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := sequence(), sequence()
+		edits, ok := myers(a, b, -1)
+		if !ok {
+			t.Fatalf("myers(%v, %v) desistiu sem limite", a, b)
+		}
+		apply(t, a, b, edits)
+		if got, want := distance(edits), lcsDistance(a, b); got != want {
+			t.Fatalf("myers(%v, %v) usou %d operações, mínimo %d", a, b, got, want)
+		}
+	}
+}
+
+func TestMyersLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		maxD int
+		ok   bool
+	}{
+		{"dentro do limite", "abcd", "axcd", 2, true},
+		{"exatamente no limite ímpar", "abcd", "abxcd", 1, true},
+		{"uma operação além do limite", "abcd", "axcd", 1, false},
+		{"muito diferentes", "abcdef", "uvwxyz", 6, false},
+		{"limite zero com iguais", "abc", "abc", 0, true},
+		{"prefixo e sufixo não contam", "aaaaXbbbb", "aaaaYbbbb", 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := strings.Split(tt.a, "")
+			b := strings.Split(tt.b, "")
+			edits, ok := myers(a, b, tt.maxD)
+			if ok != tt.ok {
+				t.Fatalf("myers(%q, %q, %d) ok = %v, esperado %v", tt.a, tt.b, tt.maxD, ok, tt.ok)
+			}
+			if ok {
+				apply(t, a, b, edits)
+			}
+		})
+	}
+}
+
+func TestComputeTooDifferent(t *testing.T) {
+	var oldText, newText strings.Builder
+	for i := 0; i <= MaxEdits/2; i++ {
+		oldText.WriteString("antiga\n")
+		newText.WriteString("nova\n")
+	}
+
+	result := Compute(oldText.String(), newText.String(), DefaultContext)
+	if !result.TooDifferent {
+		t.Fatal("Compute() não marcou as versões como diferentes demais")
+	}
+	if len(result.Hunks) != 0 || result.Unified != "" {
+		t.Errorf("Compute() retornou hunks para versões diferentes demais")
+	}
+	if want := MaxEdits/2 + 1; result.Added != want || result.Removed != want {
+		t.Errorf("Compute() = +%d -%d, esperado +%d -%d", result.Added, result.Removed, want, want)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	result := Compute("um\ndois\ntrês\n", "um\nDois\ntrês\nquatro\n", DefaultContext)
+	if result.TooDifferent {
+		t.Fatal("Compute() marcou uma alteração pequena como diferente demais")
+	}
+	if result.Added != 2 || result.Removed != 1 {
+		t.Errorf("Compute() = +%d -%d, esperado +2 -1", result.Added, result.Removed)
+	}
+	want := "@@ -1,3 +1,4 @@\n um\n-dois\n+Dois\n três\n+quatro\n"
+	if result.Unified != want {
+		t.Errorf("Compute().Unified =\n%s\nesperado\n%s", result.Unified, want)
+	}
+}
@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// RenderSideBySideHTML gera uma tabela HTML com as versões de origem e destino lado a lado
+func RenderSideBySideHTML(result *Result) string {
+	var sb strings.Builder
+	sb.WriteString(`<table class="diff diff-side-by-side">`)
+	sb.WriteString(`<colgroup><col class="diff-line-number"><col class="diff-old"><col class="diff-line-number"><col class="diff-new"></colgroup>`)
+
+	if result.TooDifferent {
+		sb.WriteString(`<tbody><tr class="diff-too-different"><td colspan="4">As versões são diferentes demais para comparar</td></tr></tbody>`)
+	}
+
+	for _, h := range result.Hunks {
+		fmt.Fprintf(&sb, `<tbody><tr class="diff-hunk-header"><td colspan="4">@@ -%d,%d +%d,%d @@</td></tr>`,
+			h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+
+		for i := 0; i < len(h.Lines); {
+			line := h.Lines[i]
+			if line.Kind == OpEqual {
+				writeRow(&sb, &line, &line)
+				i++
+				continue
+			}
+
+			// Alinhar as remoções com as inserções do mesmo bloco
+			var removed, added []Line
+			for i < len(h.Lines) && h.Lines[i].Kind == OpDelete {
+				removed = append(removed, h.Lines[i])
+				i++
+			}
+			for i < len(h.Lines) && h.Lines[i].Kind == OpInsert {
+				added = append(added, h.Lines[i])
+				i++
+			}
+
+			rows := len(removed)
+			if len(added) > rows {
+				rows = len(added)
+			}
+			for r := 0; r < rows; r++ {
+				var left, right *Line
+				if r < len(removed) {
+					left = &removed[r]
+				}
+				if r < len(added) {
+					right = &added[r]
+				}
+				writeRow(&sb, left, right)
+			}
+		}
+		sb.WriteString(`</tbody>`)
+	}
+
+	sb.WriteString(`</table>`)
+	return sb.String()
+}
+
+// writeRow escreve uma linha da tabela; lados ausentes ficam vazios
+func writeRow(sb *strings.Builder, left, right *Line) {
+	sb.WriteString(`<tr>`)
+	writeCells(sb, left, true)
+	writeCells(sb, right, false)
+	sb.WriteString(`</tr>`)
+}
+
+// writeCells escreve o número da linha e o conteúdo de um dos lados
+func writeCells(sb *strings.Builder, line *Line, old bool) {
+	if line == nil {
+		sb.WriteString(`<td class="diff-line-number diff-empty"></td><td class="diff-empty"></td>`)
+		return
+	}
+
+	number := line.NewNumber
+	if old {
+		number = line.OldNumber
+	}
+	fmt.Fprintf(sb, `<td class="diff-line-number">%d</td><td class="diff-%s">`, number, line.Kind)
+
+	if len(line.Words) == 0 {
+		sb.WriteString(html.EscapeString(line.Text))
+	} else {
+		for _, w := range line.Words {
+			text := html.EscapeString(w.Text)
+			switch w.Kind {
+			case OpDelete:
+				sb.WriteString(`<del>` + text + `</del>`)
+			case OpInsert:
+				sb.WriteString(`<ins>` + text + `</ins>`)
+			default:
+				sb.WriteString(text)
+			}
+		}
+	}
+	sb.WriteString(`</td>`)
+}
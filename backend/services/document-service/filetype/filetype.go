@@ -0,0 +1,165 @@
+// Package filetype identifica o tipo real dos arquivos enviados a partir do conteúdo
+// e aplica a lista de tipos aceitos pelo serviço de documentos.
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Type descreve um tipo de arquivo suportado
+type Type struct {
+	Extension   string // Extensão canônica, sem ponto
+	ContentType string // Tipo MIME servido no download
+	Text        bool   // Indica se o conteúdo é texto e pode ser indexado
+}
+
+// Tipos de arquivo conhecidos, indexados pela extensão canônica
+var known = map[string]Type{
+	"md":   {Extension: "md", ContentType: "text/markdown; charset=utf-8", Text: true},
+	"txt":  {Extension: "txt", ContentType: "text/plain; charset=utf-8", Text: true},
+	"pdf":  {Extension: "pdf", ContentType: "application/pdf"},
+	"docx": {Extension: "docx", ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	"png":  {Extension: "png", ContentType: "image/png"},
+	"jpg":  {Extension: "jpg", ContentType: "image/jpeg"},
+	"gif":  {Extension: "gif", ContentType: "image/gif"},
+	"webp": {Extension: "webp", ContentType: "image/webp"},
+}
+
+// extensionAliases mapeia extensões alternativas para a canônica
+var extensionAliases = map[string]string{
+	"markdown": "md",
+	"jpeg":     "jpg",
+}
+
+// DefaultAllowed lista os tipos aceitos quando UPLOAD_ALLOWED_TYPES não é definida
+const DefaultAllowed = "md,txt,pdf,docx,png,jpg,gif,webp"
+
+// ByExtension retorna o tipo correspondente a uma extensão (com ou sem ponto)
+func ByExtension(ext string) (Type, bool) {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if alias, ok := extensionAliases[ext]; ok {
+		ext = alias
+	}
+	t, ok := known[ext]
+	return t, ok
+}
+
+// sniffLen é a quantidade de bytes iniciais analisada, a mesma usada por http.DetectContentType
+const sniffLen = 512
+
+// Detect identifica o tipo real do arquivo pelo conteúdo. O nome do arquivo só é usado
+// para diferenciar formatos de texto equivalentes (Markdown e texto simples).
+func Detect(filename string, content []byte) (Type, bool) {
+	return DetectReader(filename, bytes.NewReader(content), int64(len(content)))
+}
+
+// DetectReader identifica o tipo real de um arquivo lendo apenas o início do conteúdo
+// (e o diretório do ZIP, no caso de documentos do Word), sem carregá-lo inteiro em memória
+func DetectReader(filename string, r io.ReaderAt, size int64) (Type, bool) {
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return Type{}, false
+	}
+	head = head[:n]
+
+	sniffed := http.DetectContentType(head)
+	if i := strings.Index(sniffed, ";"); i >= 0 {
+		sniffed = sniffed[:i]
+	}
+
+	switch sniffed {
+	case "application/pdf":
+		return known["pdf"], true
+	case "image/png":
+		return known["png"], true
+	case "image/jpeg":
+		return known["jpg"], true
+	case "image/gif":
+		return known["gif"], true
+	case "image/webp":
+		return known["webp"], true
+	case "application/zip":
+		if isDocx(r, size) {
+			return known["docx"], true
+		}
+		return Type{}, false
+	case "text/plain":
+		if !validUTF8Prefix(head, int64(n) < size) {
+			return Type{}, false
+		}
+		if ext, ok := ByExtension(filepath.Ext(filename)); ok && ext.Extension == "md" {
+			return known["md"], true
+		}
+		return known["txt"], true
+	}
+
+	return Type{}, false
+}
+
+// validUTF8Prefix verifica se o trecho é UTF-8 válido, tolerando um caractere
+// cortado no final quando o trecho não é o conteúdo completo
+func validUTF8Prefix(head []byte, truncated bool) bool {
+	if utf8.Valid(head) {
+		return true
+	}
+	if !truncated {
+		return false
+	}
+	for cut := 1; cut < utf8.UTFMax && cut < len(head); cut++ {
+		if utf8.Valid(head[:len(head)-cut]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDocx verifica se o arquivo ZIP é um documento do Word (OOXML)
+func isDocx(r io.ReaderAt, size int64) bool {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return false
+	}
+	for _, file := range reader.File {
+		if file.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed retorna as extensões aceitas no upload, configuráveis pela variável
+// de ambiente UPLOAD_ALLOWED_TYPES (lista separada por vírgulas)
+func Allowed() map[string]bool {
+	value := os.Getenv("UPLOAD_ALLOWED_TYPES")
+	if strings.TrimSpace(value) == "" {
+		value = DefaultAllowed
+	}
+
+	allowed := map[string]bool{}
+	for _, item := range strings.Split(value, ",") {
+		if t, ok := ByExtension(strings.TrimSpace(item)); ok {
+			allowed[t.Extension] = true
+		}
+	}
+	return allowed
+}
+
+// AllowedList retorna as extensões aceitas, na ordem da lista padrão, para mensagens de erro
+func AllowedList() []string {
+	allowed := Allowed()
+	list := make([]string, 0, len(allowed))
+	for _, ext := range strings.Split(DefaultAllowed, ",") {
+		if allowed[ext] {
+			list = append(list, ext)
+		}
+	}
+	return list
+}
@@ -0,0 +1,134 @@
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipFile monta um arquivo ZIP com as entradas informadas
+func zipFile(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("zip.Create(%q): %v", name, err)
+		}
+		entry.Write([]byte("<xml/>"))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("zip.Close(): %v", err)
+	}
+	return buf.Bytes()
+}
+
+// headOnly falha se alguém tentar ler além dos primeiros sniffLen bytes
+type headOnly struct {
+	content []byte
+}
+
+func (h headOnly) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > sniffLen {
+		return 0, errors.New("leitura além do início do arquivo")
+	}
+	return bytes.NewReader(h.content).ReadAt(p, off)
+}
+
+func TestDetectReader(t *testing.T) {
+	// "ç" ocupa dois bytes; posicionado no fim do trecho analisado, fica cortado ao meio
+	cutRune := strings.Repeat("a", sniffLen-1) + "ção"
+
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		want     string // Extensão esperada; vazio quando o tipo deve ser recusado
+	}{
+		{"pdf", "relatorio.pdf", []byte("%PDF-1.7\n%âãÏÓ\n1 0 obj"), "pdf"},
+		{"png", "imagem.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "png"},
+		{"jpeg", "foto.jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "jpg"},
+		{"gif", "anim.gif", []byte("GIF89a\x01\x00\x01\x00"), "gif"},
+		{"webp", "imagem.webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00"), "webp"},
+		{"docx", "contrato.docx", zipFile(t, "[Content_Types].xml", "word/document.xml"), "docx"},
+		{"zip que não é docx", "planilha.docx", zipFile(t, "[Content_Types].xml", "xl/workbook.xml"), ""},
+		{"markdown pelo nome", "LEIAME.md", []byte("# Título\n\nTexto"), "md"},
+		{"markdown com extensão alternativa", "notas.markdown", []byte("- item"), "md"},
+		{"texto com outro nome", "notas.txt", []byte("# Título"), "txt"},
+		{"extensão não define o tipo binário", "falso.txt", []byte("%PDF-1.4\n"), "pdf"},
+		{"extensão não torna binário texto", "falso.md", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "png"},
+		{"UTF-8 inválido", "notas.txt", []byte("texto \xff\xfe inválido"), ""},
+		{"caractere cortado no fim do trecho", "notas.txt", []byte(cutRune), "txt"},
+		{"HTML não é aceito", "pagina.md", []byte("<!DOCTYPE html><html><body>oi</body></html>"), ""},
+		{"executável", "programa.pdf", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DetectReader(tt.filename, bytes.NewReader(tt.content), int64(len(tt.content)))
+			if tt.want == "" {
+				if ok {
+					t.Errorf("DetectReader(%q) = %s, esperado recusar", tt.filename, got.Extension)
+				}
+				return
+			}
+			if !ok || got.Extension != tt.want {
+				t.Errorf("DetectReader(%q) = %q, %v; esperado %q", tt.filename, got.Extension, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectReaderReadsOnlyTheHead(t *testing.T) {
+	// Arquivos grandes que não são ZIP são identificados só pelos primeiros bytes
+	content := []byte("%PDF-1.7\n" + strings.Repeat("x", 4*sniffLen))
+	got, ok := DetectReader("grande.pdf", headOnly{content}, int64(len(content)))
+	if !ok || got.Extension != "pdf" {
+		t.Errorf("DetectReader() = %q, %v; esperado pdf", got.Extension, ok)
+	}
+
+	text := []byte(strings.Repeat("linha\n", sniffLen))
+	got, ok = DetectReader("grande.txt", headOnly{text}, int64(len(text)))
+	if !ok || got.Extension != "txt" {
+		t.Errorf("DetectReader() = %q, %v; esperado txt", got.Extension, ok)
+	}
+}
+
+func TestDetectReaderError(t *testing.T) {
+	failing := readerAtFunc(func(p []byte, off int64) (int, error) {
+		return 0, io.ErrUnexpectedEOF
+	})
+	if got, ok := DetectReader("notas.txt", failing, 10); ok {
+		t.Errorf("DetectReader() = %q, esperado falha de leitura", got.Extension)
+	}
+}
+
+type readerAtFunc func(p []byte, off int64) (int, error)
+
+func (f readerAtFunc) ReadAt(p []byte, off int64) (int, error) { return f(p, off) }
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"padrão", "", strings.Split(DefaultAllowed, ",")},
+		{"apelidos e maiúsculas", " MARKDOWN, jpeg ,Pdf", []string{"md", "pdf", "jpg"}},
+		{"tipos desconhecidos ignorados", "txt,exe,zip", []string{"txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("UPLOAD_ALLOWED_TYPES", tt.value)
+			if got := AllowedList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllowedList() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
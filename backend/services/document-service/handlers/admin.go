@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireServiceAdmin restringe a rota aos administradores do serviço de documentos.
+// O token não traz o papel do usuário, então os administradores são os IDs listados na
+// variável de ambiente DOCUMENT_SERVICE_ADMINS (separados por vírgula). Sem a variável,
+// as rotas administrativas ficam indisponíveis.
+func RequireServiceAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+			return
+		}
+
+		for _, admin := range strings.Split(os.Getenv("DOCUMENT_SERVICE_ADMINS"), ",") {
+			if strings.TrimSpace(admin) != "" && strings.TrimSpace(admin) == userID.(string) {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Operação restrita aos administradores do serviço"})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"io"
+	"log"
+)
+
+// Os conteúdos são armazenados por endereço: cada blob fica em storage.BlobObjectName(sha256)
+// e a coleção de blobs conta quantas versões o referenciam. Conteúdo idêntico é gravado uma vez.
+
+// storeBlob registra uma referência ao blob do conteúdo, gravando-o enquanto o registro não
+// confirmar que o objeto já está armazenado
+func storeBlob(store storage.BlobStore, content io.ReaderAt, size int64, contentType string) (*models.Blob, error) {
+	hash, _, err := storage.HashContent(io.NewSectionReader(content, 0, size))
+	if err != nil {
+		return nil, err
+	}
+
+	blob := &models.Blob{
+		Hash:        hash,
+		ObjectName:  storage.BlobObjectName(hash),
+		Size:        size,
+		ContentType: contentType,
+	}
+	return addBlobRef(blob, func() error {
+		return storage.PutBlob(store, hash, io.NewSectionReader(content, 0, size), size, contentType)
+	})
+}
+
+// storeBlobBytes registra o blob de um conteúdo já em memória
+func storeBlobBytes(store storage.BlobStore, content []byte, contentType string) (*models.Blob, error) {
+	return storeBlob(store, bytes.NewReader(content), int64(len(content)), contentType)
+}
+
+// adoptBlob transforma um objeto gravado fora do armazenamento por conteúdo (envio tus ou
+// pré-assinado) em blob. O objeto original é removido em seguida: se o conteúdo já existia,
+// ele era apenas uma cópia; caso contrário, foi copiado para o caminho do blob.
+func adoptBlob(store storage.BlobStore, objectName string, hash string, size int64, contentType string) (*models.Blob, error) {
+	blob := &models.Blob{
+		Hash:        hash,
+		ObjectName:  storage.BlobObjectName(hash),
+		Size:        size,
+		ContentType: contentType,
+	}
+	blob, err := addBlobRef(blob, func() error {
+		return storage.CopyToBlob(store, objectName, hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	removeOrphanObject(objectName)
+	return blob, nil
+}
+
+// addBlobRef registra a referência ao blob e, se o objeto ainda não estiver confirmado no
+// armazenamento, grava-o com write e confirma a gravação. Envios simultâneos do mesmo conteúdo
+// gravam cada um o objeto, então nenhum depende da gravação do outro.
+func addBlobRef(blob *models.Blob, write func() error) (*models.Blob, error) {
+	current, err := db.DbCollections.Blobs.AddRef(blob)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsStored() {
+		log.Printf("Conteúdo %s já armazenado; gravação ignorada", blob.Hash)
+		return blob, nil
+	}
+
+	if err := write(); err != nil {
+		releaseBlob(blob.Hash)
+		return nil, err
+	}
+	if err := db.DbCollections.Blobs.MarkStored(blob.Hash); err != nil {
+		// O objeto está gravado; a próxima referência apenas o gravará de novo
+		log.Printf("Aviso: Erro ao confirmar a gravação do blob %s: %v", blob.Hash, err)
+	}
+	return blob, nil
+}
+
+// releaseBlob remove uma referência ao blob e apaga o objeto quando nenhuma versão o referencia mais
+func releaseBlob(hash string) {
+	removed, err := db.DbCollections.Blobs.Release(hash)
+	if err != nil {
+		log.Printf("Aviso: Erro ao liberar referência ao blob %s: %v", hash, err)
+		return
+	}
+	if removed == nil {
+		return
+	}
+
+	// O registro fica marcado como em remoção até o objeto ser apagado, e novas referências
+	// ao mesmo conteúdo esperam por isso antes de gravá-lo de novo
+	removeOrphanObject(removed.ObjectName)
+	deleted, err := db.DbCollections.Blobs.DeleteTombstone(removed)
+	if err != nil {
+		log.Printf("Aviso: Erro ao remover o registro do blob %s: %v", hash, err)
+		return
+	}
+	if !deleted {
+		log.Printf("Aviso: Remoção do blob %s assumida por nova referência; a varredura de integridade confirmará o objeto", hash)
+	}
+}
+
+// releaseDocumentObjects libera os blobs referenciados pelas versões de um documento excluído
+// e remove os objetos de versões anteriores ao armazenamento por conteúdo
+func releaseDocumentObjects(store storage.BlobStore, doc *models.Document) {
+	legacyPaths := map[string]bool{}
+	if doc.ContentHash == "" && doc.StoragePath != "" {
+		legacyPaths[doc.StoragePath] = true
+	}
+
+	for _, version := range doc.VersionHistory {
+		if version.ContentHash != "" {
+			releaseBlob(version.ContentHash)
+			continue
+		}
+		if version.StoragePath != "" {
+			legacyPaths[version.StoragePath] = true
+		}
+	}
+
+	for path := range legacyPaths {
+		if err := store.Delete(path); err != nil {
+			log.Printf("Aviso: Erro ao excluir arquivo %s do armazenamento: %v", path, err)
+		}
+	}
+}
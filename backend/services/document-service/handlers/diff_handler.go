@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/diff"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDiffMaxBytes = 2 << 20 // Tamanho máximo de cada versão comparada (2 MiB)
+	defaultDiffMaxLines = 50000   // Número máximo de linhas de cada versão comparada
+)
+
+// GetDocumentDiff compara duas versões de um documento linha a linha
+func GetDocumentDiff(c *gin.Context) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	// Buscar documento no MongoDB
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+
+	// Verificar permissões
+	if !hasReadAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para acessar este documento"})
+		return
+	}
+
+	// Por padrão, comparar a versão atual com a anterior
+	to := doc.CurrentVersion()
+	if value := c.Query("to"); value != "" {
+		to, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'to' inválido"})
+			return
+		}
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		from, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'from' inválido"})
+			return
+		}
+	}
+	context := diff.DefaultContext
+	if value := c.Query("context"); value != "" {
+		context, err = strconv.Atoi(value)
+		if err != nil || context < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'context' inválido"})
+			return
+		}
+	}
+
+	fromVersion, found := doc.FindVersion(from)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Versão de origem não encontrada"})
+		return
+	}
+	toVersion, found := doc.FindVersion(to)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Versão de destino não encontrada"})
+		return
+	}
+
+	// Buscar o conteúdo das duas versões no armazenamento
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	// Recusar versões grandes demais antes de carregá-las na memória
+	maxBytes := diffLimit("DIFF_MAX_BYTES", defaultDiffMaxBytes)
+	for _, path := range []string{fromVersion.StoragePath, toVersion.StoragePath} {
+		info, err := store.Stat(path)
+		if err != nil {
+			log.Printf("Erro ao consultar %s do documento %s no armazenamento: %v", path, docID, err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conteúdo da versão não disponível no armazenamento"})
+			return
+		}
+		if info.Size > maxBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Versão grande demais para comparar", "max_bytes": maxBytes})
+			return
+		}
+	}
+
+	fromContent, err := storage.ReadAll(store, fromVersion.StoragePath)
+	if err != nil {
+		if respondIntegrityError(c, err) {
+			return
+		}
+		log.Printf("Erro ao buscar versão %d do documento %s no armazenamento: %v", from, docID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conteúdo da versão de origem não disponível no armazenamento"})
+		return
+	}
+	toContent, err := storage.ReadAll(store, toVersion.StoragePath)
+	if err != nil {
+		if respondIntegrityError(c, err) {
+			return
+		}
+		log.Printf("Erro ao buscar versão %d do documento %s no armazenamento: %v", to, docID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conteúdo da versão de destino não disponível no armazenamento"})
+		return
+	}
+
+	maxLines := diffLimit("DIFF_MAX_LINES", defaultDiffMaxLines)
+	if int64(bytes.Count(fromContent, []byte("\n"))) > maxLines || int64(bytes.Count(toContent, []byte("\n"))) > maxLines {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Versão com linhas demais para comparar", "max_lines": maxLines})
+		return
+	}
+
+	result := diff.Compute(string(fromContent), string(toContent), context)
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"from":        fromVersion,
+		"to":          toVersion,
+		"diff":        result,
+		"html":        diff.RenderSideBySideHTML(result),
+	})
+}
+
+// diffLimit lê um limite do diff da variável de ambiente, usando o padrão se ausente ou inválido
+func diffLimit(name string, fallback int64) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 {
+		log.Printf("Aviso: %s inválido (%q), usando %d", name, value, fallback)
+		return fallback
+	}
+	return limit
+}
@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
 	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/pagination"
+	"gestor-e-docs/document-service/querylang"
+	"gestor-e-docs/document-service/search"
 	"gestor-e-docs/document-service/storage"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,7 +23,12 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// CreateDocument cria um novo documento
+// maxIndexedTextSize limita o conteúdo textual copiado para o MongoDB para fins de busca
+const maxIndexedTextSize = 1 << 20
+
+// CreateDocument cria um documento para cada arquivo enviado no formulário multipart.
+// O tipo de cada arquivo é identificado pelo conteúdo e precisa estar na lista de tipos aceitos;
+// a resposta traz o resultado individual de cada arquivo.
 func CreateDocument(c *gin.Context) {
 	// Extrair userID do token JWT (adicionado pelo middleware de autenticação)
 	userID, exists := c.Get("userID")
@@ -25,7 +37,7 @@ func CreateDocument(c *gin.Context) {
 		return
 	}
 
-	// Processar o arquivo enviado via multipart/form-data
+	// Processar os arquivos enviados via multipart/form-data
 	form, err := c.MultipartForm()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato inválido. Esperado multipart/form-data"})
@@ -39,55 +51,183 @@ func CreateDocument(c *gin.Context) {
 		return
 	}
 
-	// Por enquanto, vamos processar apenas o primeiro arquivo
-	file := files[0]
+	// Extrair outros campos do form
+	description := formValue(form, "description") // Campo opcional
 
-	// Verificar extensão do arquivo
-	if !strings.HasSuffix(strings.ToLower(file.Filename), ".md") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Apenas arquivos .md são permitidos"})
+	// Criar dentro de uma pasta exige permissão de escrita nela
+	folderID := formValue(form, "folder_id")
+	if folderID != "" {
+		if _, ok := loadFolderChain(c, folderID, userID.(string), models.AccessWrite); !ok {
+			return
+		}
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
 		return
 	}
 
-	// Abrir o arquivo
+	allowed := filetype.Allowed()
+	results := make([]gin.H, 0, len(files))
+	created := 0
+	for _, file := range files {
+		result := createDocumentFromFile(store, file, allowed, userID.(string), folderID, description)
+		if result["success"] == true {
+			created++
+		}
+		results = append(results, result)
+	}
+
+	status := http.StatusCreated
+	message := "Documento(s) criado(s) com sucesso"
+	switch {
+	case created == 0:
+		status = http.StatusBadRequest
+		message = "Nenhum documento foi criado"
+	case created < len(files):
+		status = http.StatusMultiStatus
+		message = "Alguns arquivos não puderam ser processados"
+	}
+
+	response := gin.H{
+		"message":   message,
+		"documents": results,
+		"created":   created,
+		"failed":    len(files) - created,
+	}
+
+	// Manter os campos do formato anterior quando um único arquivo é enviado
+	if len(files) == 1 && created == 1 {
+		response["id"] = results[0]["id"]
+		response["title"] = results[0]["title"]
+	}
+
+	c.JSON(status, response)
+}
+
+// createDocumentFromFile armazena um arquivo enviado e cria o documento correspondente,
+// retornando o resultado do processamento desse arquivo
+func createDocumentFromFile(store storage.BlobStore, file *multipart.FileHeader, allowed map[string]bool, userID string, folderID string, description string) gin.H {
+	result := gin.H{
+		"filename": file.Filename,
+		"success":  false,
+	}
+
+	// Abrir o arquivo; arquivos grandes ficam em disco temporário, não em memória
 	src, err := file.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar arquivo"})
-		return
+		result["error"] = "Erro ao processar arquivo"
+		return result
 	}
 	defer src.Close()
 
-	// Ler o conteúdo do arquivo
-	content, err := io.ReadAll(src)
+	// Identificar o tipo real pelo conteúdo, independentemente da extensão informada
+	fileType, ok := filetype.DetectReader(file.Filename, src, file.Size)
+	if !ok || !allowed[fileType.Extension] {
+		result["error"] = "Tipo de arquivo não permitido. Tipos aceitos: " + strings.Join(filetype.AllowedList(), ", ")
+		return result
+	}
+
+	// Apenas conteúdo textual é mantido no MongoDB para a busca, limitado a maxIndexedTextSize
+	textContent, err := readIndexedText(fileType, src, file.Size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler arquivo"})
-		return
+		result["error"] = "Erro ao ler arquivo"
+		return result
 	}
 
-	// Extrair outros campos do form
-	description := form.Value["description"][0] // Campo opcional
-	title := strings.TrimSuffix(file.Filename, ".md") // Usar nome do arquivo como título por padrão
+	newDoc := newUploadedDocument(titleFromFilename(file.Filename), textContent, fileType, file.Size, userID, folderID, description)
+
+	// Armazenar o conteúdo por endereço; arquivos idênticos a um já armazenado não são regravados
+	blob, err := storeBlob(store, src, file.Size, fileType.ContentType)
+	if err != nil {
+		log.Printf("Erro ao fazer upload do documento %s: %v", file.Filename, err)
+		result["error"] = "Falha ao armazenar o documento"
+		return result
+	}
+
+	// Definir o caminho de armazenamento no documento
+	newDoc.StoragePath = blob.ObjectName
+	newDoc.ContentHash = blob.Hash
+
+	// Salvar o documento no MongoDB
+	err = db.DbCollections.Documents.InsertDocument(&newDoc)
+	if err != nil {
+		log.Printf("Erro ao inserir documento no MongoDB: %v", err)
+
+		// Liberar a referência ao conteúdo, que não será usada
+		releaseBlob(blob.Hash)
+
+		result["error"] = "Falha ao salvar o documento"
+		return result
+	}
+	syncSearchIndex(&newDoc)
+
+	result["success"] = true
+	result["id"] = newDoc.ID.Hex()
+	result["title"] = newDoc.Title
+	result["content_type"] = fileType.ContentType
+	result["extension"] = fileType.Extension
+	result["size"] = newDoc.Metadata.FileSize
+	return result
+}
+
+// titleFromFilename usa o nome do arquivo, sem a extensão, como título do documento
+func titleFromFilename(filename string) string {
+	title := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if strings.TrimSpace(title) == "" {
+		title = filename
+	}
+	return title
+}
+
+// readIndexedText lê o conteúdo textual mantido no MongoDB para a busca, limitado a maxIndexedTextSize.
+// Arquivos binários não têm conteúdo indexado.
+func readIndexedText(fileType filetype.Type, r io.ReaderAt, size int64) (string, error) {
+	if !fileType.Text {
+		return "", nil
+	}
+	content, err := io.ReadAll(io.LimitReader(io.NewSectionReader(r, 0, size), maxIndexedTextSize))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
 
-	// Criar um novo documento
+// indexedText limita o texto editado ao que é mantido no MongoDB para a busca
+func indexedText(content string) string {
+	if len(content) > maxIndexedTextSize {
+		// Descartar o caractere cortado no limite
+		return strings.ToValidUTF8(content[:maxIndexedTextSize], "")
+	}
+	return content
+}
+
+// newUploadedDocument monta um novo rascunho a partir de um arquivo recebido
+func newUploadedDocument(title string, textContent string, fileType filetype.Type, size int64, userID string, folderID string, description string) models.Document {
 	now := time.Now()
-	newDoc := models.Document{
+	return models.Document{
 		Title:      title,
-		Content:    string(content),
-		AuthorID:   userID.(string),
+		Content:    textContent,
+		AuthorID:   userID,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 		Tags:       []string{},
 		Categories: []string{},
 		Status:     models.StatusDraft,
+		FolderID:   folderID,
 		Permissions: models.DocumentPermissions{
-			OwnerID:  userID.(string),
-			IsPublic: false,
-			ReadAccess: []string{},
+			OwnerID:     userID,
+			IsPublic:    false,
+			ReadAccess:  []string{},
 			WriteAccess: []string{},
 			AdminAccess: []string{},
 		},
 		Metadata: models.DocumentMetadata{
-			FileSize:          file.Size,
-			OriginalExtension: "md",
+			FileSize:          size,
+			OriginalExtension: fileType.Extension,
+			ContentType:       fileType.ContentType,
 			LastViewedAt:      now,
 			ViewCount:         0,
 			IsTemplate:        false,
@@ -97,56 +237,22 @@ func CreateDocument(c *gin.Context) {
 			},
 		},
 	}
+}
 
-	// Salvar conteúdo no MinIO
-	minioClient, err := storage.GetMinioClient()
-	if err != nil {
-		log.Printf("Erro ao obter cliente MinIO: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
-		return
-	}
-
-	// Criar um ID temporário para o documento enquanto não temos o ID do MongoDB
-	tempID := primitive.NewObjectID().Hex()
-	objectPath, err := minioClient.UploadDocument(
-		content,
-		userID.(string),
-		tempID,
-		"text/markdown",
-	)
-	if err != nil {
-		log.Printf("Erro ao fazer upload do documento: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar o documento"})
-		return
-	}
-
-	// Definir o caminho de armazenamento no documento
-	newDoc.StoragePath = objectPath
-
-	// Salvar o documento no MongoDB
-	err = db.DbCollections.Documents.InsertDocument(&newDoc)
-	if err != nil {
-		log.Printf("Erro ao inserir documento no MongoDB: %v", err)
-		
-		// Tentar limpar o arquivo do MinIO em caso de falha
-		deleteErr := minioClient.DeleteDocument(objectPath)
-		if deleteErr != nil {
-			log.Printf("Erro ao limpar arquivo do MinIO após falha: %v", deleteErr)
-		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar o documento"})
-		return
+// formValue retorna o primeiro valor de um campo do formulário, ou vazio se ausente
+func formValue(form *multipart.Form, field string) string {
+	if values := form.Value[field]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
 	}
-
-	// Retornar o documento criado
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Documento criado com sucesso",
-		"id": newDoc.ID.Hex(),
-		"title": newDoc.Title,
-	})
+	return ""
 }
 
-// GetDocument busca um documento pelo ID
+// documentInlineLimit é o maior conteúdo textual devolvido junto com o documento
+const documentInlineLimit = 1 << 20
+
+// GetDocument busca um documento pelo ID. O conteúdo só acompanha a resposta para textos
+// de até documentInlineLimit bytes; nos demais casos content_omitted indica que ele deve
+// ser obtido por GET /:id/download.
 func GetDocument(c *gin.Context) {
 	docID := c.Param("id")
 	userID, exists := c.Get("userID")
@@ -168,27 +274,44 @@ func GetDocument(c *gin.Context) {
 		return
 	}
 
-	// Buscar conteúdo do documento no MinIO
-	minioClient, err := storage.GetMinioClient()
+	// Buscar conteúdo do documento no armazenamento
+	store, err := storage.GetBlobStore()
 	if err != nil {
-		log.Printf("Erro ao obter cliente MinIO: %v", err)
+		log.Printf("Erro ao obter o armazenamento: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
 		return
 	}
 
-	content, err := minioClient.GetDocument(doc.StoragePath)
+	// Só textos pequenos vão na resposta; os demais são baixados pela rota de download
+	info, err := store.Stat(doc.StoragePath)
 	if err != nil {
-		log.Printf("Erro ao buscar documento no MinIO: %v", err)
+		log.Printf("Erro ao consultar documento no armazenamento: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao recuperar conteúdo do documento"})
 		return
 	}
 
+	doc.Content = ""
+	doc.ContentOmitted = !isTextContentType(documentContentType(doc)) || info.Size > documentInlineLimit
+	if !doc.ContentOmitted {
+		content, err := storage.ReadAll(store, doc.StoragePath)
+		if err != nil {
+			if respondIntegrityError(c, err) {
+				return
+			}
+			log.Printf("Erro ao buscar documento no armazenamento: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao recuperar conteúdo do documento"})
+			return
+		}
+		doc.Content = string(content)
+	}
+
 	// Atualizar contadores de visualização
 	updateViewCountAsync(doc.ID.Hex())
 
 	// Preparar resposta
-	doc.Content = string(content)
+	doc.Lock = doc.ActiveLock(time.Now())
 
+	c.Header("ETag", doc.ETag())
 	c.JSON(http.StatusOK, doc)
 }
 
@@ -214,6 +337,12 @@ func UpdateDocument(c *gin.Context) {
 		return
 	}
 
+	// Verificar pré-condição de concorrência otimista
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
 	// Parse do corpo da requisição
 	var docUpdate models.DocumentUpdate
 	if err := c.ShouldBindJSON(&docUpdate); err != nil {
@@ -221,45 +350,90 @@ func UpdateDocument(c *gin.Context) {
 		return
 	}
 
-	// Se o conteúdo foi atualizado, salvar nova versão no MinIO
+	// O estado só muda pelo fluxo de aprovação, e apenas rascunhos podem ser editados
+	if docUpdate.Status != "" && docUpdate.Status != doc.Status.Normalize() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "O estado do documento só pode ser alterado pelo fluxo de aprovação",
+			"status": doc.Status.Normalize(),
+		})
+		return
+	}
+	docUpdate.Status = ""
+	if !doc.Status.IsEditable() {
+		respondNotEditable(c, doc)
+		return
+	}
+	if doc.Lock.BlocksUser(userID.(string), time.Now()) {
+		respondLocked(c, docID)
+		return
+	}
+
+	// Se o conteúdo foi atualizado, salvar nova versão no armazenamento
+	var newBlob *models.Blob
+	var newContent *models.VersionContent
 	if docUpdate.Content != "" {
-		minioClient, err := storage.GetMinioClient()
+		store, err := storage.GetBlobStore()
 		if err != nil {
-			log.Printf("Erro ao obter cliente MinIO: %v", err)
+			log.Printf("Erro ao obter o armazenamento: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
 			return
 		}
 
-		newObjectPath, err := minioClient.UploadDocument(
-			[]byte(docUpdate.Content),
-			userID.(string),
-			docID,
-			"text/markdown",
-		)
+		newBlob, err = storeBlobBytes(store, []byte(docUpdate.Content), "text/markdown")
 		if err != nil {
 			log.Printf("Erro ao fazer upload da nova versão: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar a nova versão"})
 			return
 		}
 
-		// Atualizar o caminho no documento
-		docUpdate.Content = newObjectPath
+		// A nova versão e o documento apontam para o blob; o texto fica no MongoDB para a busca
+		newContent = &models.VersionContent{
+			StoragePath: newBlob.ObjectName,
+			ContentHash: newBlob.Hash,
+			Text:        indexedText(docUpdate.Content),
+			Size:        newBlob.Size,
+			ContentType: newBlob.ContentType,
+		}
 	}
 
 	// Atualizar no MongoDB
-	err = db.DbCollections.Documents.UpdateDocument(docID, &docUpdate, userID.(string))
+	updated, err := db.DbCollections.Documents.UpdateDocument(docID, &docUpdate, newContent, userID.(string), expectedRevision)
 	if err != nil {
+		// Liberar a referência ao conteúdo recém-armazenado, que não será usada
+		if newBlob != nil {
+			releaseBlob(newBlob.Hash)
+		}
+
+		if err == db.ErrRevisionConflict {
+			respondRevisionConflict(c, docID)
+			return
+		}
+		if err == db.ErrDocumentNotEditable {
+			respondNotEditable(c, doc)
+			return
+		}
+		if err == db.ErrDocumentLocked {
+			respondLocked(c, docID)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar o documento"})
 		return
 	}
+	syncSearchIndex(updated)
 
+	c.Header("ETag", updated.ETag())
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Documento atualizado com sucesso",
 		"id": docID,
+		"etag": updated.ETag(),
+		"revision": updated.Revision,
+		"current_version": updated.CurrentVersion(),
 	})
 }
 
-// ListDocuments lista documentos do usuário com paginação e filtros
+// ListDocuments lista os documentos que o usuário pode ler, com paginação e filtros.
+// O parâmetro scope restringe a listagem a documentos próprios (owned), compartilhados
+// com o usuário (shared), públicos (public) ou todos os legíveis (all).
 func ListDocuments(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -274,38 +448,132 @@ func ListDocuments(c *gin.Context) {
 		return
 	}
 
-	// Se não for especificado, listar apenas documentos do usuário
-	if query.AuthorID == "" {
-		query.AuthorID = userID.(string)
+	listDocuments(c, userID.(string), &query)
+}
+
+// ListSharedDocuments lista os documentos compartilhados com o usuário
+func ListSharedDocuments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	var query models.DocumentSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Scope = models.ScopeShared
+
+	listDocuments(c, userID.(string), &query)
+}
+
+// listDocuments executa a busca restrita aos documentos legíveis pelo usuário
+func listDocuments(c *gin.Context, userID string, query *models.DocumentSearchQuery) {
+	// Sem escopo explícito, manter o comportamento anterior: apenas documentos do
+	// usuário, ou todos os legíveis quando um autor específico é solicitado
+	if query.Scope == "" {
+		query.Scope = models.ScopeOwned
+		if query.AuthorID != "" {
+			query.Scope = models.ScopeAll
+		}
+	}
+	if !models.IsValidScope(query.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Escopo inválido. Use owned, shared, public ou all"})
+		return
+	}
+	query.ViewerID = userID
+
+	// Documentos compartilhados por meio de pastas também são legíveis
+	if query.Scope == models.ScopeShared || query.Scope == models.ScopeAll {
+		readFolders, err := db.DbCollections.Folders.ReadableFolderIDs(userID)
+		if err != nil {
+			log.Printf("Erro ao buscar pastas legíveis: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar documentos"})
+			return
+		}
+		query.ReadFolders = readFolders
 	}
 
-	// Buscar documentos que o usuário tem acesso
-	docs, err := db.DbCollections.Documents.SearchDocuments(&query)
+	// Restringir à pasta informada e, opcionalmente, às suas subpastas
+	if query.FolderID != "" {
+		query.FolderIDs = []string{query.FolderID}
+		if query.Recursive {
+			descendants, err := db.DbCollections.Folders.ListDescendants(query.FolderID)
+			if err != nil {
+				log.Printf("Erro ao buscar subpastas de %s: %v", query.FolderID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar documentos"})
+				return
+			}
+			for _, f := range descendants {
+				query.FolderIDs = append(query.FolderIDs, f.ID.Hex())
+			}
+		}
+	}
+
+	// Buscar documentos que o usuário tem acesso no mecanismo de busca configurado
+	index, err := search.GetSearchIndex()
 	if err != nil {
+		log.Printf("Erro ao obter o mecanismo de busca: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar documentos"})
+		return
+	}
+	page, err := index.Search(query)
+	if err != nil {
+		if respondSearchError(c, err) {
+			return
+		}
 		log.Printf("Erro ao buscar documentos: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar documentos"})
 		return
 	}
 
-	// Contar total para paginação
-	filter := bson.M{"author_id": query.AuthorID}
-	if query.Query != "" {
-		filter["$text"] = bson.M{"$search": query.Query}
-	}
-	total, err := db.DbCollections.Documents.CountDocuments(filter)
+	// Contagens por faceta sobre o mesmo filtro da busca
+	facets, err := index.Facets(query)
 	if err != nil {
-		log.Printf("Erro ao contar documentos: %v", err)
+		log.Printf("Erro ao calcular facetas da busca: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar documentos"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"documents": docs,
-		"total": total,
+		"documents": page.Documents,
+		"total": page.Total,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"facets": facets,
 		"offset": query.Offset,
 		"limit": query.Limit,
+		"scope": query.Scope,
 	})
 }
 
-// DeleteDocument exclui um documento
+// respondSearchError responde 400 quando a busca falhou por erro na consulta, indicando o
+// trecho problemático, por uma ordenação não aceita ou por um cursor de paginação inválido.
+// Retorna false para os demais erros.
+func respondSearchError(c *gin.Context, err error) bool {
+	var syntaxErr *querylang.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Consulta inválida: " + syntaxErr.Error(),
+			"position": syntaxErr.Position,
+			"token":    syntaxErr.Token,
+		})
+		return true
+	}
+	if errors.Is(err, models.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ordenação inválida: " + strings.TrimPrefix(err.Error(), models.ErrInvalidSort.Error()+": ")})
+		return true
+	}
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor de paginação inválido ou de outra consulta; recomece a listagem sem o cursor"})
+		return true
+	}
+	return false
+}
+
+// DeleteDocument move um documento para a lixeira
 func DeleteDocument(c *gin.Context) {
 	docID := c.Param("id")
 	userID, exists := c.Get("userID")
@@ -327,40 +595,37 @@ func DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	// Excluir documento do MinIO
-	minioClient, err := storage.GetMinioClient()
-	if err != nil {
-		log.Printf("Erro ao obter cliente MinIO: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+	// Verificar pré-condição de concorrência otimista
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
 		return
 	}
 
-	// Excluir o arquivo principal e todas as versões
-	err = minioClient.DeleteDocument(doc.StoragePath)
-	if err != nil {
-		log.Printf("Aviso: Erro ao excluir arquivo do MinIO: %v", err)
-		// Continuar mesmo com erro, para pelo menos excluir do MongoDB
+	revision := doc.Revision
+	if expectedRevision != nil {
+		revision = *expectedRevision
 	}
 
-	// Tentar excluir todas as versões
-	for _, version := range doc.VersionHistory {
-		if version.StoragePath != doc.StoragePath {
-			err = minioClient.DeleteDocument(version.StoragePath)
-			if err != nil {
-				log.Printf("Aviso: Erro ao excluir versão %d do MinIO: %v", version.VersionNumber, err)
-			}
-		}
-	}
-
-	// Excluir do MongoDB
-	err = db.DbCollections.Documents.DeleteDocument(docID)
+	// Mover para a lixeira; a remoção definitiva ocorre pela lixeira ou após o período de retenção
+	deleted, err := db.DbCollections.Documents.SoftDeleteDocument(docID, userID.(string), revision)
 	if err != nil {
+		if err == db.ErrRevisionConflict {
+			respondRevisionConflict(c, docID)
+			return
+		}
+		if err == db.ErrDocumentLocked {
+			respondLocked(c, docID)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao excluir o documento"})
 		return
 	}
+	removeFromSearchIndex(docID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Documento excluído com sucesso",
+		"message":     "Documento movido para a lixeira",
+		"deleted_at":  deleted.DeletedAt,
+		"purge_after": deleted.DeletedAt.Add(trashRetention()),
 	})
 }
 
@@ -390,15 +655,19 @@ func DownloadDocument(c *gin.Context) {
 
 
 	// Gerar URL para download
-	minioClient, err := storage.GetMinioClient()
+	store, err := storage.GetBlobStore()
 	if err != nil {
-		log.Printf("Erro ao obter cliente MinIO: %v", err)
+		log.Printf("Erro ao obter o armazenamento: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
 		return
 	}
 
 	// URL válida por 1 hora
-	url, err := minioClient.GetDocumentURL(objectPath, time.Hour)
+	url, err := store.PresignGet(objectPath, time.Hour)
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		// Sem acesso direto ao armazenamento, o arquivo é entregue pelo próprio serviço
+		url, err = "/api/v1/documents/"+docID+"/download/file", nil
+	}
 	if err != nil {
 		log.Printf("Erro ao gerar URL de download: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao gerar link de download"})
@@ -408,10 +677,87 @@ func DownloadDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"download_url": url,
 		"expires_in": "1 hora",
-		"filename": doc.Title + ".md",
+		"filename": documentFilename(doc),
 	})
 }
 
+// Funções auxiliares para controle de concorrência
+
+// checkIfMatch avalia o cabeçalho If-Match em relação ao estado atual do documento.
+// Retorna a revisão esperada (nil quando o cabeçalho não foi enviado) e false quando
+// a resposta 412 já foi enviada.
+func checkIfMatch(c *gin.Context, doc *models.Document) (*int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, true
+	}
+
+	if !etagMatches(header, doc.ETag()) {
+		respondPreconditionFailed(c, doc)
+		return nil, false
+	}
+
+	revision := doc.Revision
+	return &revision, true
+}
+
+// etagMatches verifica se alguma das tags do cabeçalho corresponde à ETag atual
+func etagMatches(header string, current string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		// Comparação forte: tags fracas nunca correspondem em If-Match
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// respondRevisionConflict busca o estado atual do documento e responde com 412
+func respondRevisionConflict(c *gin.Context, docID string) {
+	current, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+	respondPreconditionFailed(c, current)
+}
+
+// respondPreconditionFailed responde com 412 e os metadados da versão atual do documento
+func respondPreconditionFailed(c *gin.Context, doc *models.Document) {
+	current := gin.H{
+		"id":              doc.ID.Hex(),
+		"etag":            doc.ETag(),
+		"revision":        doc.Revision,
+		"current_version": doc.CurrentVersion(),
+		"updated_at":      doc.UpdatedAt,
+	}
+	if version, found := doc.FindVersion(doc.CurrentVersion()); found {
+		current["version"] = version
+	}
+
+	c.Header("ETag", doc.ETag())
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "O documento foi modificado por outro usuário. Recarregue-o antes de salvar novamente",
+		"current": current,
+	})
+}
+
+// removeOrphanObject remove do armazenamento um objeto que não chegou a ser referenciado
+func removeOrphanObject(objectPath string) {
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento para limpeza de %s: %v", objectPath, err)
+		return
+	}
+	if err := store.Delete(objectPath); err != nil {
+		log.Printf("Erro ao limpar arquivo do armazenamento após falha: %v", err)
+	}
+}
+
 // Funções auxiliares para verificação de permissões
 
 // hasReadAccess verifica se um usuário tem permissão de leitura
@@ -443,7 +789,8 @@ func hasReadAccess(doc *models.Document, userID string) bool {
 		}
 	}
 
-	return false
+	// Acesso herdado das pastas ancestrais
+	return inheritedAccess(doc.FolderID, userID).Includes(models.AccessRead)
 }
 
 // hasWriteAccess verifica se um usuário tem permissão de escrita
@@ -465,7 +812,8 @@ func hasWriteAccess(doc *models.Document, userID string) bool {
 		}
 	}
 
-	return false
+	// Acesso herdado das pastas ancestrais
+	return inheritedAccess(doc.FolderID, userID).Includes(models.AccessWrite)
 }
 
 // hasAdminAccess verifica se um usuário tem permissões administrativas
@@ -482,7 +830,8 @@ func hasAdminAccess(doc *models.Document, userID string) bool {
 		}
 	}
 
-	return false
+	// Acesso herdado das pastas ancestrais
+	return inheritedAccess(doc.FolderID, userID).Includes(models.AccessAdmin)
 }
 
 // updateViewCountAsync atualiza o contador de visualizações de forma assíncrona
@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"bytes"
 	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
 	"gestor-e-docs/document-service/storage"
 	"log"
+	"mime"
 	"net/http"
 	"path/filepath"
 
@@ -35,29 +37,56 @@ func DownloadDocumentFile(c *gin.Context) {
 
 	objectPath := doc.StoragePath
 
-	// Obter o arquivo do MinIO
-	minioClient, err := storage.GetMinioClient()
+	// Obter o arquivo do armazenamento
+	store, err := storage.GetBlobStore()
 	if err != nil {
-		log.Printf("Erro ao obter cliente MinIO: %v", err)
+		log.Printf("Erro ao obter o armazenamento: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
 		return
 	}
 
-	// Obter o arquivo do MinIO
-	contentBytes, err := minioClient.GetDocument(objectPath)
+	// Abrir o arquivo no armazenamento para leitura em streaming
+	object, info, err := store.Get(objectPath)
 	if err != nil {
-		log.Printf("Erro ao obter objeto do MinIO: %v", err)
+		log.Printf("Erro ao obter objeto do armazenamento: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao acessar arquivo"})
 		return
 	}
-	
-	// Criar um reader a partir dos bytes
-	object := bytes.NewReader(contentBytes)
+	defer object.Close()
 
-	// Atualizar contador de visualizações de forma assíncrona
-	updateViewCountAsync(docID)
+	// Determinar o tipo de conteúdo e o nome do arquivo para download
+	contentType := documentContentType(doc)
+	filename := documentFilename(doc)
+
+	// Configurar cabeçalhos; o ETag vem do objeto armazenado e permite validar o cache
+	c.Header("Content-Disposition", contentDisposition("attachment", filename))
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Cache-Control", "private, no-cache")
+	if info.ETag != "" {
+		c.Header("ETag", "\""+info.ETag+"\"")
+	}
+
+	// ServeContent trata Range/If-Range (206), If-None-Match/If-Modified-Since (304)
+	// e define Last-Modified a partir da data do objeto
+	http.ServeContent(c.Writer, c.Request, filename, info.LastModified, object)
+
+	// Contabilizar a visualização apenas quando o arquivo completo foi entregue
+	if c.Writer.Status() == http.StatusOK {
+		updateViewCountAsync(docID)
+	}
+}
+
+// documentContentType determina o tipo de conteúdo identificado no upload ou, para documentos
+// antigos, a partir da extensão original
+func documentContentType(doc *models.Document) string {
+	if doc.Metadata.ContentType != "" {
+		return doc.Metadata.ContentType
+	}
+	if fileType, ok := filetype.ByExtension(doc.Metadata.OriginalExtension); ok {
+		return fileType.ContentType
+	}
 
-	// Determinar o tipo de conteúdo
 	contentType := http.DetectContentType([]byte{}) // Placeholder
 	if doc.Metadata.OriginalExtension != "" {
 		switch doc.Metadata.OriginalExtension {
@@ -73,23 +102,26 @@ func DownloadDocumentFile(c *gin.Context) {
 			contentType = "application/octet-stream"
 		}
 	}
+	return contentType
+}
 
-	// Nome do arquivo para download
+// documentFilename monta o nome do arquivo para download
+func documentFilename(doc *models.Document) string {
 	filename := doc.Title
 	if filepath.Ext(filename) == "" {
 		// Adicionar extensão se não tiver
 		filename = filename + "." + doc.Metadata.OriginalExtension
 	}
+	return filename
+}
 
-	// Configurar cabeçalhos para forçar download
-	c.Header("Content-Disposition", "attachment; filename=\""+filename+"\"")
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Expires", "0")
-	c.Header("Cache-Control", "must-revalidate")
-	c.Header("Pragma", "public")
-
-	// Stream do arquivo direto para o cliente
-	c.DataFromReader(http.StatusOK, doc.Metadata.FileSize, contentType, object, nil)
+// contentDisposition monta o cabeçalho Content-Disposition. O nome vem do título do documento,
+// então aspas e caracteres fora do ASCII são codificados em vez de copiados para o cabeçalho.
+func contentDisposition(disposition string, filename string) string {
+	value := mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+	if value == "" {
+		// Nome impossível de representar (por exemplo, com caracteres de controle)
+		return disposition
+	}
+	return value
 }
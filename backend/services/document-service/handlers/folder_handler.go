@@ -0,0 +1,661 @@
+package handlers
+
+import (
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/search"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CreateFolder cria uma nova pasta na raiz ou dentro de outra pasta
+func CreateFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	var req models.FolderCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O nome da pasta é obrigatório"})
+		return
+	}
+
+	// Criar dentro de outra pasta exige permissão de escrita nela
+	var parent *models.Folder
+	if req.ParentID != "" {
+		chain, ok := loadFolderChain(c, req.ParentID, userID.(string), models.AccessWrite)
+		if !ok {
+			return
+		}
+		parent = &chain[len(chain)-1]
+	}
+
+	folder := models.Folder{
+		Name: name,
+		Permissions: models.DocumentPermissions{
+			OwnerID:     userID.(string),
+			IsPublic:    false,
+			ReadAccess:  []string{},
+			WriteAccess: []string{},
+			AdminAccess: []string{},
+		},
+	}
+
+	if err := db.DbCollections.Folders.InsertFolder(&folder, parent); err != nil {
+		log.Printf("Erro ao inserir pasta no MongoDB: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar a pasta"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Pasta criada com sucesso",
+		"folder":  folder,
+	})
+}
+
+// ListFolders lista as subpastas de uma pasta ou, sem parent_id, as pastas da raiz visíveis ao usuário
+func ListFolders(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	parentID := c.Query("parent_id")
+	var allowedIDs []string
+	if parentID != "" {
+		// Quem lê a pasta lê todas as suas subpastas
+		if _, ok := loadFolderChain(c, parentID, userID.(string), models.AccessRead); !ok {
+			return
+		}
+	} else {
+		readable, err := db.DbCollections.Folders.ReadableFolderIDs(userID.(string))
+		if err != nil {
+			log.Printf("Erro ao buscar pastas legíveis: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar pastas"})
+			return
+		}
+		allowedIDs = readable
+	}
+
+	folders, err := db.DbCollections.Folders.ListChildren(parentID, allowedIDs)
+	if err != nil {
+		log.Printf("Erro ao listar pastas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar pastas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"parent_id": parentID,
+		"folders":   folders,
+	})
+}
+
+// GetFolder retorna uma pasta com o caminho desde a raiz
+func GetFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	chain, ok := loadFolderChain(c, c.Param("folderId"), userID.(string), models.AccessRead)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"folder":     chain[len(chain)-1],
+		"breadcrumb": breadcrumb(chain),
+		"access":     folderChainAccess(chain, userID.(string)),
+	})
+}
+
+// RenameFolder altera o nome de uma pasta
+func RenameFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	if _, ok := loadFolderChain(c, folderID, userID.(string), models.AccessWrite); !ok {
+		return
+	}
+
+	var req models.FolderUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O nome da pasta é obrigatório"})
+		return
+	}
+
+	if err := db.DbCollections.Folders.RenameFolder(folderID, name); err != nil {
+		log.Printf("Erro ao renomear pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao renomear a pasta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pasta renomeada com sucesso",
+		"id":      folderID,
+		"name":    name,
+	})
+}
+
+// MoveFolder move uma pasta para dentro de outra pasta ou para a raiz
+func MoveFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	chain, ok := loadFolderChain(c, folderID, userID.(string), models.AccessAdmin)
+	if !ok {
+		return
+	}
+	folder := chain[len(chain)-1]
+
+	var req models.FolderMove
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Mover para dentro de outra pasta exige permissão de escrita no destino
+	var newParent *models.Folder
+	if req.ParentID != "" {
+		parentChain, ok := loadFolderChain(c, req.ParentID, userID.(string), models.AccessWrite)
+		if !ok {
+			return
+		}
+		newParent = &parentChain[len(parentChain)-1]
+	}
+
+	err := db.DbCollections.Folders.MoveFolder(&folder, newParent)
+	if err == db.ErrFolderCycle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A pasta não pode ser movida para dentro de si mesma"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao mover pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao mover a pasta"})
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		FolderID: folderID,
+		ActorID:  userID.(string),
+		Action:   models.AuditFolderMoved,
+		OldValue: folder.ParentID,
+		NewValue: req.ParentID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Pasta movida com sucesso",
+		"id":        folderID,
+		"parent_id": req.ParentID,
+	})
+}
+
+// DeleteFolder exclui uma pasta vazia
+func DeleteFolder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	if _, ok := loadFolderChain(c, folderID, userID.(string), models.AccessAdmin); !ok {
+		return
+	}
+
+	// Apenas pastas vazias podem ser excluídas
+	children, err := db.DbCollections.Folders.CountChildren(folderID)
+	if err != nil {
+		log.Printf("Erro ao contar subpastas de %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao excluir a pasta"})
+		return
+	}
+	documents, err := db.DbCollections.Documents.CountDocuments(bson.M{"folder_id": folderID, "deleted_at": nil})
+	if err != nil {
+		log.Printf("Erro ao contar documentos da pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao excluir a pasta"})
+		return
+	}
+	if children > 0 || documents > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "A pasta não está vazia",
+			"folders":   children,
+			"documents": documents,
+		})
+		return
+	}
+
+	if err := db.DbCollections.Folders.DeleteFolder(folderID); err != nil {
+		log.Printf("Erro ao excluir pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao excluir a pasta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pasta excluída com sucesso",
+	})
+}
+
+// GetFolderContents lista as subpastas e os documentos de uma pasta.
+// Com recursive=true, inclui todas as pastas e documentos abaixo dela.
+func GetFolderContents(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	chain, ok := loadFolderChain(c, folderID, userID.(string), models.AccessRead)
+	if !ok {
+		return
+	}
+
+	var query models.DocumentSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var folders []models.Folder
+	var err error
+	if query.Recursive {
+		folders, err = db.DbCollections.Folders.ListDescendants(folderID)
+	} else {
+		folders, err = db.DbCollections.Folders.ListChildren(folderID, nil)
+	}
+	if err != nil {
+		log.Printf("Erro ao listar subpastas de %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar o conteúdo da pasta"})
+		return
+	}
+
+	query.FolderIDs = []string{folderID}
+	if query.Recursive {
+		for _, f := range folders {
+			query.FolderIDs = append(query.FolderIDs, f.ID.Hex())
+		}
+	}
+
+	// Quem lê a pasta lê todo o seu conteúdo por herança
+	query.Scope = models.ScopeAll
+	query.ViewerID = userID.(string)
+	query.ReadFolders = query.FolderIDs
+
+	index, err := search.GetSearchIndex()
+	if err != nil {
+		log.Printf("Erro ao obter o mecanismo de busca: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar o conteúdo da pasta"})
+		return
+	}
+	page, err := index.Search(&query)
+	if err != nil {
+		if respondSearchError(c, err) {
+			return
+		}
+		log.Printf("Erro ao buscar documentos da pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar o conteúdo da pasta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"folder":      chain[len(chain)-1],
+		"breadcrumb":  breadcrumb(chain),
+		"recursive":   query.Recursive,
+		"folders":     folders,
+		"documents":   page.Documents,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"offset":      query.Offset,
+		"limit":       query.Limit,
+	})
+}
+
+// GetFolderPermissions lista as permissões de uma pasta
+func GetFolderPermissions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	chain, ok := loadFolderChain(c, c.Param("folderId"), userID.(string), models.AccessAdmin)
+	if !ok {
+		return
+	}
+	folder := chain[len(chain)-1]
+
+	c.Header("ETag", folder.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"folder_id":   folder.ID.Hex(),
+		"revision":    folder.Revision,
+		"permissions": folder.Permissions,
+		"grants":      permissionGrants(&folder.Permissions),
+	})
+}
+
+// GrantFolderPermission concede acesso a uma pasta, herdado por todo o seu conteúdo
+func GrantFolderPermission(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	chain, ok := loadFolderChain(c, folderID, userID.(string), models.AccessAdmin)
+	if !ok {
+		return
+	}
+	folder := chain[len(chain)-1]
+	expectedRevision, ok := checkFolderIfMatch(c, &folder)
+	if !ok {
+		return
+	}
+
+	var grant models.PermissionGrant
+	if err := c.ShouldBindJSON(&grant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !grant.Access.IsGrantable() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nível de acesso inválido. Use read, write ou admin"})
+		return
+	}
+
+	target, ok := resolveUser(c, grant.UserID, grant.Email)
+	if !ok {
+		return
+	}
+	if target.ID == folder.Permissions.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O dono da pasta já possui acesso total"})
+		return
+	}
+
+	previous := folder.Permissions.AccessOf(target.ID)
+	permissions := folder.Permissions
+	permissions.SetAccess(target.ID, grant.Access)
+
+	updated, ok := saveFolderPermissions(c, &folder, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	action := models.AuditPermissionGranted
+	if previous != models.AccessNone {
+		action = models.AuditPermissionChanged
+	}
+	recordAudit(c, &models.AuditEntry{
+		FolderID:     folderID,
+		ActorID:      userID.(string),
+		Action:       action,
+		TargetUserID: target.ID,
+		OldValue:     string(previous),
+		NewValue:     string(grant.Access),
+		Details:      map[string]interface{}{"email": target.Email},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Acesso concedido com sucesso",
+		"user":        target,
+		"access":      grant.Access,
+		"permissions": updated.Permissions,
+	})
+}
+
+// RevokeFolderPermission remove o acesso explícito de um usuário a uma pasta
+func RevokeFolderPermission(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	folderID := c.Param("folderId")
+	chain, ok := loadFolderChain(c, folderID, userID.(string), models.AccessAdmin)
+	if !ok {
+		return
+	}
+	folder := chain[len(chain)-1]
+	expectedRevision, ok := checkFolderIfMatch(c, &folder)
+	if !ok {
+		return
+	}
+
+	targetID := c.Param("userId")
+	previous := folder.Permissions.AccessOf(targetID)
+	switch previous {
+	case models.AccessNone:
+		c.JSON(http.StatusNotFound, gin.H{"error": "O usuário não possui acesso a esta pasta"})
+		return
+	case models.AccessOwner:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O acesso do dono da pasta não pode ser revogado"})
+		return
+	}
+
+	permissions := folder.Permissions
+	permissions.SetAccess(targetID, models.AccessNone)
+
+	updated, ok := saveFolderPermissions(c, &folder, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		FolderID:     folderID,
+		ActorID:      userID.(string),
+		Action:       models.AuditPermissionRevoked,
+		TargetUserID: targetID,
+		OldValue:     string(previous),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Acesso revogado com sucesso",
+		"user_id":     targetID,
+		"permissions": updated.Permissions,
+	})
+}
+
+// MoveDocument move um documento para outra pasta ou para a raiz
+func MoveDocument(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req models.FolderMove
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Mover para uma pasta exige permissão de escrita nela
+	if req.FolderID != "" {
+		if _, ok := loadFolderChain(c, req.FolderID, userID, models.AccessWrite); !ok {
+			return
+		}
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+	revision := doc.Revision
+	if expectedRevision != nil {
+		revision = *expectedRevision
+	}
+
+	updated, err := db.DbCollections.Documents.MoveDocument(doc.ID.Hex(), req.FolderID, userID, revision)
+	if err == db.ErrRevisionConflict {
+		respondRevisionConflict(c, doc.ID.Hex())
+		return
+	}
+	if err == db.ErrDocumentLocked {
+		respondLocked(c, doc.ID.Hex())
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao mover documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao mover o documento"})
+		return
+	}
+	syncSearchIndex(updated)
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID: doc.ID.Hex(),
+		ActorID:    userID,
+		Action:     models.AuditDocumentMoved,
+		OldValue:   doc.FolderID,
+		NewValue:   req.FolderID,
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Documento movido com sucesso",
+		"id":        doc.ID.Hex(),
+		"folder_id": updated.FolderID,
+	})
+}
+
+// Funções auxiliares para pastas
+
+// loadFolderChain busca a pasta e seus ancestrais e verifica se o usuário tem o nível de acesso exigido.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadFolderChain(c *gin.Context, folderID string, userID string, required models.AccessLevel) ([]models.Folder, bool) {
+	chain, err := db.DbCollections.Folders.GetFolderChain(folderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pasta não encontrada"})
+		return nil, false
+	}
+
+	if !folderChainAccess(chain, userID).Includes(required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para esta operação na pasta"})
+		return nil, false
+	}
+
+	return chain, true
+}
+
+// checkFolderIfMatch confere o cabeçalho If-Match com a ETag da pasta, como checkIfMatch faz
+// para documentos. Retorna a revisão esperada (nil sem o cabeçalho) e false se a resposta já foi enviada.
+func checkFolderIfMatch(c *gin.Context, folder *models.Folder) (*int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, true
+	}
+
+	if !etagMatches(header, folder.ETag()) {
+		respondFolderPreconditionFailed(c, folder)
+		return nil, false
+	}
+
+	revision := folder.Revision
+	return &revision, true
+}
+
+// saveFolderPermissions grava as novas permissões da pasta, respondendo com 412 em caso de
+// conflito de revisão. Retorna false quando a resposta de erro já foi enviada.
+func saveFolderPermissions(c *gin.Context, folder *models.Folder, permissions models.DocumentPermissions, expectedRevision *int64) (*models.Folder, bool) {
+	revision := folder.Revision
+	if expectedRevision != nil {
+		revision = *expectedRevision
+	}
+
+	folderID := folder.ID.Hex()
+	updated, err := db.DbCollections.Folders.UpdatePermissions(folderID, permissions, revision)
+	if err == db.ErrRevisionConflict {
+		current, err := db.DbCollections.Folders.GetFolderByID(folderID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pasta não encontrada"})
+			return nil, false
+		}
+		respondFolderPreconditionFailed(c, current)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Erro ao atualizar permissões da pasta %s: %v", folderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar as permissões"})
+		return nil, false
+	}
+
+	c.Header("ETag", updated.ETag())
+	return updated, true
+}
+
+// respondFolderPreconditionFailed responde com 412 e a revisão atual das permissões da pasta
+func respondFolderPreconditionFailed(c *gin.Context, folder *models.Folder) {
+	c.Header("ETag", folder.ETag())
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": "As permissões da pasta foram modificadas por outro usuário. Recarregue-as antes de salvar novamente",
+		"current": gin.H{
+			"id":          folder.ID.Hex(),
+			"etag":        folder.ETag(),
+			"revision":    folder.Revision,
+			"updated_at":  folder.UpdatedAt,
+			"permissions": folder.Permissions,
+		},
+	})
+}
+
+// folderChainAccess retorna o maior nível de acesso do usuário na pasta ou em seus ancestrais
+func folderChainAccess(chain []models.Folder, userID string) models.AccessLevel {
+	level := models.AccessNone
+	for i := range chain {
+		level = level.Max(chain[i].Permissions.AccessOf(userID))
+		if chain[i].Permissions.IsPublic {
+			level = level.Max(models.AccessRead)
+		}
+	}
+	return level
+}
+
+// inheritedAccess retorna o nível de acesso que o usuário herda da pasta de um documento
+func inheritedAccess(folderID string, userID string) models.AccessLevel {
+	if folderID == "" {
+		return models.AccessNone
+	}
+
+	chain, err := db.DbCollections.Folders.GetFolderChain(folderID)
+	if err != nil {
+		log.Printf("Erro ao buscar pastas ancestrais de %s: %v", folderID, err)
+		return models.AccessNone
+	}
+
+	return folderChainAccess(chain, userID)
+}
+
+// breadcrumb resume o caminho da raiz até a pasta
+func breadcrumb(chain []models.Folder) []gin.H {
+	items := make([]gin.H, 0, len(chain))
+	for _, f := range chain {
+		items = append(items, gin.H{"id": f.ID.Hex(), "name": f.Name})
+	}
+	return items
+}
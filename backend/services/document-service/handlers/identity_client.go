@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ErrUserNotFound indica que o identity-service não encontrou o usuário
+var ErrUserNotFound = errors.New("usuário não encontrado")
+
+// IdentityUser contém os dados públicos de um usuário retornados pelo identity-service
+type IdentityUser struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// IdentityClient é um cliente para consulta de usuários no identity-service. As consultas
+// são chamadas entre serviços, autenticadas pelo segredo compartilhado de INTERNAL_SERVICE_TOKEN.
+type IdentityClient struct {
+	baseURL      string
+	serviceToken string
+	httpClient   *http.Client
+}
+
+// NewIdentityClient cria um novo cliente do identity-service
+func NewIdentityClient() *IdentityClient {
+	baseURL := os.Getenv("IDENTITY_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = "http://identity-service:8085"
+	}
+	return &IdentityClient{
+		baseURL:      baseURL,
+		serviceToken: os.Getenv("INTERNAL_SERVICE_TOKEN"),
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LookupByEmail busca um usuário pelo e-mail
+func (c *IdentityClient) LookupByEmail(email string) (*IdentityUser, error) {
+	return c.lookup(url.Values{"email": {email}})
+}
+
+// LookupByID busca um usuário pelo ID
+func (c *IdentityClient) LookupByID(id string) (*IdentityUser, error) {
+	return c.lookup(url.Values{"id": {id}})
+}
+
+// lookup executa a consulta no endpoint de busca de usuários
+func (c *IdentityClient) lookup(params url.Values) (*IdentityUser, error) {
+	if c.serviceToken == "" {
+		return nil, errors.New("INTERNAL_SERVICE_TOKEN não configurado")
+	}
+
+	req, err := http.NewRequest("GET", c.baseURL+"/api/v1/identity/users/lookup?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("X-Service-Token", c.serviceToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar identity-service: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrUserNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("erro na consulta de usuário: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		User IdentityUser `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta do identity-service: %v", err)
+	}
+
+	return &body.User, nil
+}
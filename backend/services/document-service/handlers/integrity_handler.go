@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/metrics"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultScrubInterval = 24 * time.Hour
+	maxScrubIssues       = 1000 // Limite de ocorrências detalhadas no relatório; as contagens seguem completas
+)
+
+// scrubState guarda a varredura em andamento e o relatório da última concluída
+var scrubState struct {
+	sync.Mutex
+	running bool
+	current *models.ScrubReport
+	last    *models.ScrubReport
+}
+
+// StartScrub inicia uma varredura de integridade em segundo plano
+func StartScrub(c *gin.Context) {
+	report, started := startScrub(context.Background())
+	if !started {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Já existe uma varredura de integridade em andamento",
+			"started_at": report.StartedAt,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Varredura de integridade iniciada",
+		"started_at": report.StartedAt,
+	})
+}
+
+// GetScrubReport retorna o relatório da última varredura de integridade concluída
+func GetScrubReport(c *gin.Context) {
+	scrubState.Lock()
+	defer scrubState.Unlock()
+
+	response := gin.H{
+		"running":     scrubState.running,
+		"last_report": scrubState.last,
+	}
+	if scrubState.running {
+		response["started_at"] = scrubState.current.StartedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// StartIntegrityScrubber inicia a rotina que verifica periodicamente o conteúdo de todas as versões
+func StartIntegrityScrubber(ctx context.Context) {
+	interval := envDuration("INTEGRITY_SCRUB_INTERVAL", defaultScrubInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, started := startScrub(ctx); !started {
+					log.Println("Varredura de integridade anterior ainda em andamento; execução ignorada")
+				}
+			}
+		}
+	}()
+}
+
+// Funções auxiliares para a verificação de integridade
+
+// startScrub dispara a varredura se nenhuma estiver em andamento. Retorna o relatório
+// em andamento e se uma nova varredura foi iniciada.
+func startScrub(ctx context.Context) (*models.ScrubReport, bool) {
+	scrubState.Lock()
+	defer scrubState.Unlock()
+
+	if scrubState.running {
+		return scrubState.current, false
+	}
+
+	report := &models.ScrubReport{
+		StartedAt: time.Now(),
+		Results:   map[models.ScrubResult]int{},
+		Issues:    []models.ScrubIssue{},
+	}
+	scrubState.running = true
+	scrubState.current = report
+
+	go func() {
+		runScrub(ctx, report)
+
+		scrubState.Lock()
+		scrubState.running = false
+		scrubState.current = nil
+		scrubState.last = report
+		scrubState.Unlock()
+	}()
+
+	return report, true
+}
+
+// scrubCheck guarda o resultado da verificação de um objeto, reaproveitado pelas demais
+// versões que apontam para o mesmo blob
+type scrubCheck struct {
+	result models.ScrubResult
+	actual string
+	err    string
+}
+
+// runScrub percorre as versões de todos os documentos, inclusive os da lixeira, e confere
+// o SHA-256 de cada objeto referenciado
+func runScrub(ctx context.Context, report *models.ScrubReport) {
+	log.Println("Varredura de integridade iniciada")
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento para a varredura de integridade: %v", err)
+		finishScrub(report)
+		return
+	}
+
+	checked := map[string]scrubCheck{}
+	err = db.DbCollections.Documents.ForEachStorageReference(ctx, func(doc *models.Document) error {
+		report.DocumentsScanned++
+
+		for _, version := range doc.VersionHistory {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if version.StoragePath == "" {
+				continue
+			}
+
+			expected := version.ContentHash
+			if expected == "" {
+				expected, _ = storage.BlobHash(version.StoragePath)
+			}
+
+			check, done := checked[version.StoragePath]
+			if !done {
+				check = scrubObject(store, version.StoragePath, expected)
+				checked[version.StoragePath] = check
+				report.ObjectsChecked++
+				report.Results[check.result]++
+				metrics.IntegrityChecks.WithLabelValues("scrub", string(check.result)).Inc()
+			}
+
+			if check.result == models.ScrubOK || check.result == models.ScrubUnverifiable {
+				continue
+			}
+			if len(report.Issues) >= maxScrubIssues {
+				report.IssuesTruncated = true
+				continue
+			}
+			report.Issues = append(report.Issues, models.ScrubIssue{
+				DocumentID:    doc.ID.Hex(),
+				VersionNumber: version.VersionNumber,
+				StoragePath:   version.StoragePath,
+				Result:        check.result,
+				ExpectedHash:  expected,
+				ActualHash:    check.actual,
+				Error:         check.err,
+			})
+		}
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		report.Cancelled = true
+	} else if err != nil {
+		log.Printf("Erro ao percorrer documentos na varredura de integridade: %v", err)
+	}
+
+	finishScrub(report)
+	log.Printf("Varredura de integridade concluída: %d objeto(s) verificado(s), %d ausente(s), %d corrompido(s), %d sem checksum",
+		report.ObjectsChecked, report.Results[models.ScrubMissing], report.Results[models.ScrubCorrupted], report.Results[models.ScrubUnverifiable])
+}
+
+// scrubObject verifica um objeto. Sem checksum registrado, apenas a existência é conferida.
+func scrubObject(store storage.BlobStore, objectName string, expected string) scrubCheck {
+	if expected == "" {
+		if _, err := store.Stat(objectName); err != nil {
+			return scrubFailure(err)
+		}
+		return scrubCheck{result: models.ScrubUnverifiable}
+	}
+
+	actual, err := storage.VerifyObject(store, objectName, expected)
+	if err != nil {
+		return scrubFailure(err)
+	}
+	return scrubCheck{result: models.ScrubOK, actual: actual}
+}
+
+// scrubFailure classifica o erro da verificação de um objeto
+func scrubFailure(err error) scrubCheck {
+	var integrityErr *storage.IntegrityError
+	switch {
+	case errors.As(err, &integrityErr):
+		return scrubCheck{result: models.ScrubCorrupted, actual: integrityErr.Actual}
+	case storage.IsObjectNotFound(err):
+		return scrubCheck{result: models.ScrubMissing}
+	default:
+		return scrubCheck{result: models.ScrubError, err: err.Error()}
+	}
+}
+
+// finishScrub registra o fim da varredura no relatório e nas métricas
+func finishScrub(report *models.ScrubReport) {
+	now := time.Now()
+	report.FinishedAt = &now
+	metrics.ScrubLastCompleted.Set(float64(now.Unix()))
+}
+
+// respondIntegrityError responde quando o conteúdo lido não confere com o checksum registrado.
+// Retorna false se o erro for de outro tipo, deixando a resposta para quem chamou.
+func respondIntegrityError(c *gin.Context, err error) bool {
+	if !errors.Is(err, storage.ErrIntegrity) {
+		return false
+	}
+
+	log.Printf("Falha de integridade na leitura: %v", err)
+	metrics.IntegrityChecks.WithLabelValues("read", string(models.ScrubCorrupted)).Inc()
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "O conteúdo armazenado está corrompido e não pode ser entregue"})
+	return true
+}
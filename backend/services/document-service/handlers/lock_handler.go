@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLockTTL           = 15 * time.Minute
+	defaultLockMaxTTL        = 4 * time.Hour
+	defaultLockSweepInterval = 5 * time.Minute
+)
+
+// GetLock retorna a reserva de edição vigente do documento
+func GetLock(c *gin.Context) {
+	doc, _, ok := loadDocumentForLock(c, models.AccessRead)
+	if !ok {
+		return
+	}
+
+	lock := doc.ActiveLock(time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"locked":      lock != nil,
+		"lock":        lock,
+	})
+}
+
+// AcquireLock reserva o documento para edição exclusiva do usuário por um prazo limitado.
+// Se o usuário já detém a reserva, o prazo é renovado. Só rascunhos podem ser reservados.
+func AcquireLock(c *gin.Context) {
+	doc, userID, ok := loadDocumentForLock(c, models.AccessWrite)
+	if !ok {
+		return
+	}
+	if !doc.Status.IsEditable() {
+		respondNotEditable(c, doc)
+		return
+	}
+
+	ttl, ok := lockDuration(c)
+	if !ok {
+		return
+	}
+
+	updated, err := db.DbCollections.Documents.AcquireLock(doc.ID.Hex(), userID, ttl)
+	if err == db.ErrDocumentLocked {
+		respondLocked(c, doc.ID.Hex())
+		return
+	}
+	if err == db.ErrDocumentNotEditable {
+		// Enviado para revisão ou excluído depois de lido
+		if current, err := db.DbCollections.Documents.GetDocumentByID(doc.ID.Hex()); err == nil {
+			respondNotEditable(c, current)
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao reservar documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao reservar o documento"})
+		return
+	}
+
+	log.Printf("Documento %s reservado para edição por %s até %v", doc.ID.Hex(), userID, updated.Lock.ExpiresAt)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Documento reservado para edição",
+		"lock":    updated.Lock,
+	})
+}
+
+// RenewLock estende o prazo da reserva. O detentor renova a própria reserva e
+// administradores do documento podem renovar a reserva de outro usuário.
+func RenewLock(c *gin.Context) {
+	doc, userID, ok := loadDocumentForLock(c, models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	ttl, ok := lockDuration(c)
+	if !ok {
+		return
+	}
+
+	holderID := userID
+	if lock := doc.ActiveLock(time.Now()); lock != nil && lock.HolderID != userID {
+		if !hasAdminAccess(doc, userID) {
+			respondLocked(c, doc.ID.Hex())
+			return
+		}
+		holderID = "" // Renovação administrativa
+	}
+
+	updated, err := db.DbCollections.Documents.RenewLock(doc.ID.Hex(), holderID, ttl)
+	if err == db.ErrLockNotHeld {
+		c.JSON(http.StatusConflict, gin.H{"error": "Não há reserva vigente para renovar"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao renovar reserva do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao renovar a reserva"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reserva renovada",
+		"lock":    updated.Lock,
+	})
+}
+
+// ReleaseLock libera a reserva do documento. O detentor libera a própria reserva e
+// administradores do documento podem quebrar a reserva de outro usuário.
+func ReleaseLock(c *gin.Context) {
+	doc, userID, ok := loadDocumentForLock(c, models.AccessWrite)
+	if !ok {
+		return
+	}
+
+	lock := doc.ActiveLock(time.Now())
+	if lock == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Não há reserva vigente para liberar"})
+		return
+	}
+
+	holderID := userID
+	forced := lock.HolderID != userID
+	if forced {
+		if !hasAdminAccess(doc, userID) {
+			respondLocked(c, doc.ID.Hex())
+			return
+		}
+		holderID = lock.HolderID
+	}
+
+	released, err := db.DbCollections.Documents.ReleaseLock(doc.ID.Hex(), holderID)
+	if err == db.ErrLockNotHeld {
+		c.JSON(http.StatusConflict, gin.H{"error": "Não há reserva vigente para liberar"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao liberar reserva do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao liberar a reserva"})
+		return
+	}
+
+	if forced {
+		log.Printf("Reserva de %s no documento %s quebrada por %s", released.HolderID, doc.ID.Hex(), userID)
+		recordAudit(c, &models.AuditEntry{
+			DocumentID:   doc.ID.Hex(),
+			ActorID:      userID,
+			Action:       models.AuditLockBroken,
+			TargetUserID: released.HolderID,
+			Details:      map[string]interface{}{"expires_at": released.ExpiresAt},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reserva liberada",
+		"forced":  forced,
+	})
+}
+
+// StartLockReaper inicia a rotina que remove as reservas de edição expiradas.
+// Reservas expiradas já não bloqueiam ninguém; a rotina apenas limpa o registro.
+func StartLockReaper(ctx context.Context) {
+	interval := envDuration("EDIT_LOCK_SWEEP_INTERVAL", defaultLockSweepInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				released, err := db.DbCollections.Documents.ReleaseExpiredLocks()
+				if err != nil {
+					log.Printf("Erro ao liberar reservas expiradas: %v", err)
+				} else if released > 0 {
+					log.Printf("%d reserva(s) de edição expirada(s) liberada(s)", released)
+				}
+			}
+		}
+	}()
+}
+
+// Funções auxiliares para reservas de edição
+
+// loadDocumentForLock busca o documento da rota e verifica o nível de acesso exigido.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadDocumentForLock(c *gin.Context, required models.AccessLevel) (*models.Document, string, bool) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return nil, "", false
+	}
+
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return nil, "", false
+	}
+
+	allowed := hasReadAccess(doc, userID.(string))
+	if required == models.AccessWrite {
+		allowed = hasWriteAccess(doc, userID.(string))
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para reservar este documento"})
+		return nil, "", false
+	}
+
+	return doc, userID.(string), true
+}
+
+// lockDuration determina o prazo da reserva a partir do pedido, respeitando o limite configurado.
+// O corpo é opcional: sem ele, vale o prazo padrão.
+func lockDuration(c *gin.Context) (time.Duration, bool) {
+	ttl := envDuration("EDIT_LOCK_TTL", defaultLockTTL)
+	maxTTL := envDuration("EDIT_LOCK_MAX_TTL", defaultLockMaxTTL)
+
+	var req models.LockRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return 0, false
+	}
+	if req.DurationMinutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A duração da reserva não pode ser negativa"})
+		return 0, false
+	}
+
+	// Comparar em minutos antes de converter, para que valores enormes não estourem a duração
+	if req.DurationMinutes > int(maxTTL/time.Minute) {
+		ttl = maxTTL
+	} else if req.DurationMinutes > 0 {
+		ttl = time.Duration(req.DurationMinutes) * time.Minute
+	}
+
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	return ttl, true
+}
+
+// respondLocked responde com 423 informando quem detém a reserva do documento
+func respondLocked(c *gin.Context, docID string) {
+	response := gin.H{"error": "O documento está reservado para edição por outro usuário"}
+	if current, err := db.DbCollections.Documents.GetDocumentByID(docID); err == nil {
+		if lock := current.ActiveLock(time.Now()); lock != nil {
+			response["lock"] = lock
+		}
+	}
+	c.JSON(http.StatusLocked, response)
+}
+
+// envDuration lê uma duração de uma variável de ambiente, usando o valor padrão se ausente ou inválida
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Aviso: %s inválido (%q), usando %v", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
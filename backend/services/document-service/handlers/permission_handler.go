@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPermissions lista as permissões de um documento
+func GetPermissions(c *gin.Context) {
+	doc, _, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", doc.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"permissions": doc.Permissions,
+		"grants":      permissionGrants(&doc.Permissions),
+	})
+}
+
+// GrantPermission concede acesso a um usuário identificado pelo ID ou e-mail
+func GrantPermission(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var grant models.PermissionGrant
+	if err := c.ShouldBindJSON(&grant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !grant.Access.IsGrantable() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nível de acesso inválido. Use read, write ou admin"})
+		return
+	}
+
+	target, ok := resolveUser(c, grant.UserID, grant.Email)
+	if !ok {
+		return
+	}
+	if target.ID == doc.Permissions.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O dono do documento já possui acesso total"})
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	previous := doc.Permissions.AccessOf(target.ID)
+	permissions := doc.Permissions
+	permissions.SetAccess(target.ID, grant.Access)
+
+	updated, ok := savePermissions(c, doc, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	action := models.AuditPermissionGranted
+	if previous != models.AccessNone {
+		action = models.AuditPermissionChanged
+	}
+	recordAudit(c, &models.AuditEntry{
+		DocumentID:   doc.ID.Hex(),
+		ActorID:      userID,
+		Action:       action,
+		TargetUserID: target.ID,
+		OldValue:     string(previous),
+		NewValue:     string(grant.Access),
+		Details:      map[string]interface{}{"email": target.Email},
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Acesso concedido com sucesso",
+		"user":        target,
+		"access":      grant.Access,
+		"permissions": updated.Permissions,
+	})
+}
+
+// UpdatePermission altera o nível de acesso de um usuário que já possui acesso
+func UpdatePermission(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	targetID := c.Param("userId")
+	var change models.PermissionChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !change.Access.IsGrantable() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nível de acesso inválido. Use read, write ou admin"})
+		return
+	}
+
+	previous := doc.Permissions.AccessOf(targetID)
+	switch previous {
+	case models.AccessNone:
+		c.JSON(http.StatusNotFound, gin.H{"error": "O usuário não possui acesso a este documento"})
+		return
+	case models.AccessOwner:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O acesso do dono não pode ser alterado. Transfira a propriedade primeiro"})
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	permissions := doc.Permissions
+	permissions.SetAccess(targetID, change.Access)
+
+	updated, ok := savePermissions(c, doc, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID:   doc.ID.Hex(),
+		ActorID:      userID,
+		Action:       models.AuditPermissionChanged,
+		TargetUserID: targetID,
+		OldValue:     string(previous),
+		NewValue:     string(change.Access),
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Acesso alterado com sucesso",
+		"user_id":     targetID,
+		"access":      change.Access,
+		"permissions": updated.Permissions,
+	})
+}
+
+// RevokePermission remove o acesso explícito de um usuário
+func RevokePermission(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	targetID := c.Param("userId")
+	previous := doc.Permissions.AccessOf(targetID)
+	switch previous {
+	case models.AccessNone:
+		c.JSON(http.StatusNotFound, gin.H{"error": "O usuário não possui acesso a este documento"})
+		return
+	case models.AccessOwner:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O acesso do dono não pode ser revogado. Transfira a propriedade primeiro"})
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	permissions := doc.Permissions
+	permissions.SetAccess(targetID, models.AccessNone)
+
+	updated, ok := savePermissions(c, doc, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID:   doc.ID.Hex(),
+		ActorID:      userID,
+		Action:       models.AuditPermissionRevoked,
+		TargetUserID: targetID,
+		OldValue:     string(previous),
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Acesso revogado com sucesso",
+		"user_id":     targetID,
+		"permissions": updated.Permissions,
+	})
+}
+
+// SetVisibility torna o documento público ou privado
+func SetVisibility(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var change models.VisibilityChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	previous := doc.Permissions.IsPublic
+	permissions := doc.Permissions
+	permissions.IsPublic = *change.IsPublic
+
+	updated, ok := savePermissions(c, doc, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID: doc.ID.Hex(),
+		ActorID:    userID,
+		Action:     models.AuditVisibilityChanged,
+		OldValue:   strconv.FormatBool(previous),
+		NewValue:   strconv.FormatBool(permissions.IsPublic),
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Visibilidade alterada com sucesso",
+		"is_public":   updated.Permissions.IsPublic,
+		"permissions": updated.Permissions,
+	})
+}
+
+// TransferOwnership transfere a propriedade do documento para outro usuário.
+// Apenas o dono atual pode transferir; ele permanece com acesso administrativo.
+func TransferOwnership(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	if doc.Permissions.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Apenas o dono pode transferir a propriedade do documento"})
+		return
+	}
+
+	var transfer models.OwnershipTransfer
+	if err := c.ShouldBindJSON(&transfer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, ok := resolveUser(c, transfer.UserID, transfer.Email)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O usuário informado já é o dono do documento"})
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	permissions := doc.Permissions
+	permissions.SetAccess(target.ID, models.AccessNone)
+	permissions.OwnerID = target.ID
+	permissions.SetAccess(userID, models.AccessAdmin)
+
+	updated, ok := savePermissions(c, doc, permissions, expectedRevision)
+	if !ok {
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID:   doc.ID.Hex(),
+		ActorID:      userID,
+		Action:       models.AuditOwnerTransferred,
+		TargetUserID: target.ID,
+		OldValue:     userID,
+		NewValue:     target.ID,
+		Details:      map[string]interface{}{"email": target.Email},
+	})
+
+	c.Header("ETag", updated.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Propriedade transferida com sucesso",
+		"owner":       target,
+		"permissions": updated.Permissions,
+	})
+}
+
+// ListPermissionAudit lista a trilha de auditoria das alterações de permissões do documento
+func ListPermissionAudit(c *gin.Context) {
+	doc, _, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, err := db.DbCollections.Audit.ListByDocument(doc.ID.Hex(), offset, limit)
+	if err != nil {
+		log.Printf("Erro ao buscar trilha de auditoria: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar trilha de auditoria"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"entries":     entries,
+		"offset":      offset,
+		"limit":       limit,
+	})
+}
+
+// Funções auxiliares para o gerenciamento de permissões
+
+// loadDocumentForAdmin busca o documento da rota e verifica se o usuário tem acesso administrativo.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadDocumentForAdmin(c *gin.Context) (*models.Document, string, bool) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return nil, "", false
+	}
+
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return nil, "", false
+	}
+
+	if !hasAdminAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para gerenciar o acesso a este documento"})
+		return nil, "", false
+	}
+
+	return doc, userID.(string), true
+}
+
+// resolveUser identifica o usuário pelo ID ou e-mail consultando o identity-service
+func resolveUser(c *gin.Context, userID string, email string) (*IdentityUser, bool) {
+	userID = strings.TrimSpace(userID)
+	email = strings.TrimSpace(email)
+	if userID == "" && email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o user_id ou o email do usuário"})
+		return nil, false
+	}
+
+	client := NewIdentityClient()
+	var user *IdentityUser
+	var err error
+	if userID != "" {
+		user, err = client.LookupByID(userID)
+	} else {
+		user, err = client.LookupByEmail(email)
+	}
+
+	if err == ErrUserNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Erro ao consultar usuário no identity-service: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Falha ao consultar o serviço de identidade"})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// savePermissions grava as novas permissões, respondendo com 412 em caso de conflito de revisão.
+// Retorna false quando a resposta de erro já foi enviada.
+func savePermissions(c *gin.Context, doc *models.Document, permissions models.DocumentPermissions, expectedRevision *int64) (*models.Document, bool) {
+	revision := doc.Revision
+	if expectedRevision != nil {
+		revision = *expectedRevision
+	}
+
+	updated, err := db.DbCollections.Documents.UpdatePermissions(doc.ID.Hex(), permissions, revision)
+	if err == db.ErrRevisionConflict {
+		respondRevisionConflict(c, doc.ID.Hex())
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Erro ao atualizar permissões do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar as permissões"})
+		return nil, false
+	}
+	syncSearchIndex(updated)
+
+	return updated, true
+}
+
+// recordAudit grava uma entrada na trilha de auditoria, apenas registrando falhas no log
+func recordAudit(c *gin.Context, entry *models.AuditEntry) {
+	entry.IPAddress = c.ClientIP()
+	entry.UserAgent = c.Request.UserAgent()
+
+	if err := db.DbCollections.Audit.InsertEntry(entry); err != nil {
+		log.Printf("Erro ao registrar auditoria (%s) do documento %s: %v", entry.Action, entry.DocumentID, err)
+	}
+}
+
+// permissionGrants lista os usuários com acesso explícito e seus níveis
+func permissionGrants(permissions *models.DocumentPermissions) []gin.H {
+	grants := []gin.H{{"user_id": permissions.OwnerID, "access": models.AccessOwner}}
+	for _, id := range permissions.AdminAccess {
+		grants = append(grants, gin.H{"user_id": id, "access": models.AccessAdmin})
+	}
+	for _, id := range permissions.WriteAccess {
+		grants = append(grants, gin.H{"user_id": id, "access": models.AccessWrite})
+	}
+	for _, id := range permissions.ReadAccess {
+		grants = append(grants, gin.H{"user_id": id, "access": models.AccessRead})
+	}
+	return grants
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sha256Pattern valida o checksum informado pelo cliente (64 dígitos hexadecimais)
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// presignedUploadNote explica ao cliente como usar o formulário e por que o envio direto não é
+// um PUT pré-assinado em /uploads: só a política de um POST permite ao MinIO recusar arquivos
+// de tamanho diferente do declarado (content-length-range), e /uploads pertence ao protocolo tus
+const presignedUploadNote = "Envie um POST multipart/form-data para upload_url com todos os campos de fields e o arquivo " +
+	"no campo file, depois chame complete_url. É usado um formulário POST, e não um PUT pré-assinado, porque só a " +
+	"política do formulário (content-length-range) faz o armazenamento recusar arquivos de tamanho diferente do declarado; " +
+	"as rotas ficam em /presigned-uploads porque /uploads é usado pelo protocolo tus."
+
+// CreatePresignedUpload reserva um documento e devolve um formulário pré-assinado para o
+// cliente gravar o arquivo diretamente no MinIO, sem passar pelo serviço
+func CreatePresignedUpload(c *gin.Context) {
+	userID, ok := uploadUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.PresignedUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	filename := filepath.Base(strings.TrimSpace(req.Filename))
+	if filename == "." || filename == "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nome de arquivo inválido"})
+		return
+	}
+	if req.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O tamanho do arquivo deve ser positivo"})
+		return
+	}
+	if req.Size > uploadMaxSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "O arquivo excede o tamanho máximo permitido"})
+		return
+	}
+
+	checksum := strings.ToLower(strings.TrimSpace(req.SHA256))
+	if checksum != "" && !sha256Pattern.MatchString(checksum) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Checksum SHA-256 inválido"})
+		return
+	}
+
+	// Criar dentro de uma pasta exige permissão de escrita nela
+	folderID := strings.TrimSpace(req.FolderID)
+	if folderID != "" {
+		if _, ok := loadFolderChain(c, folderID, userID, models.AccessWrite); !ok {
+			return
+		}
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	// O tipo real é verificado na conclusão; até lá vale o informado pela extensão
+	extension := "bin"
+	if declared, ok := filetype.ByExtension(filepath.Ext(filename)); ok {
+		extension = declared.Extension
+	}
+
+	// O ID do documento é reservado agora para que o objeto fique em userID/documentID/
+	documentID := primitive.NewObjectID().Hex()
+	objectName := storage.NewObjectName(userID, documentID, extension)
+	expiration := uploadExpiration()
+
+	form, err := store.PresignPost(objectName, req.Size, expiration)
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "O armazenamento configurado não aceita envios diretos; use o protocolo tus"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao gerar formulário de envio para %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao iniciar o envio"})
+		return
+	}
+
+	upload := models.Upload{
+		Protocol:    models.UploadPresigned,
+		UserID:      userID,
+		Filename:    filename,
+		FolderID:    folderID,
+		Description: strings.TrimSpace(req.Description),
+		Length:      req.Size,
+		ObjectName:  objectName,
+		DocumentID:  documentID,
+		SHA256:      checksum,
+		ExpiresAt:   time.Now().Add(expiration),
+	}
+	if err := db.DbCollections.Uploads.InsertUpload(&upload); err != nil {
+		log.Printf("Erro ao registrar envio pré-assinado: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao iniciar o envio"})
+		return
+	}
+
+	log.Printf("Envio pré-assinado %s iniciado por %s (%s, %d bytes)", upload.ID.Hex(), userID, filename, req.Size)
+	c.JSON(http.StatusCreated, gin.H{
+		"upload_id":    upload.ID.Hex(),
+		"document_id":  documentID,
+		"upload_url":   form.URL,
+		"method":       http.MethodPost,
+		"encoding":     "multipart/form-data",
+		"fields":       form.Fields,
+		"file_field":   "file",
+		"expires_at":   upload.ExpiresAt,
+		"complete_url": "/api/v1/documents/presigned-uploads/" + upload.ID.Hex() + "/complete",
+		"cancel_url":   "/api/v1/documents/presigned-uploads/" + upload.ID.Hex(),
+		"note":         presignedUploadNote,
+	})
+}
+
+// CompleteUpload conclui um envio pré-assinado: verifica se o objeto foi gravado, confere
+// tamanho, checksum e tipo real e cria o documento
+func CompleteUpload(c *gin.Context) {
+	userID, ok := uploadUser(c)
+	if !ok {
+		return
+	}
+
+	upload, err := db.DbCollections.Uploads.AcquireLease(c.Param("uploadId"), userID, models.UploadPresigned, 0, defaultUploadLeaseTTL)
+	switch {
+	case err == db.ErrUploadOffsetMismatch:
+		c.JSON(http.StatusConflict, gin.H{"error": "O envio já foi concluído"})
+		return
+	case err == db.ErrUploadBusy:
+		c.JSON(http.StatusLocked, gin.H{"error": "O envio está sendo processado por outra requisição"})
+		return
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envio não encontrado"})
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		releaseUploadLease(upload)
+		c.JSON(http.StatusGone, gin.H{"error": "O envio expirou"})
+		return
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		releaseUploadLease(upload)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	info, err := store.Stat(upload.ObjectName)
+	if err != nil {
+		releaseUploadLease(upload)
+		c.JSON(http.StatusConflict, gin.H{"error": "O arquivo ainda não foi enviado pelo formulário pré-assinado"})
+		return
+	}
+	// A política do formulário já limita o tamanho; conferir de novo antes de ler o objeto
+	// protege contra armazenamentos que não a aplicam
+	if info.Size != upload.Length {
+		removeOrphanObject(upload.ObjectName)
+		releaseUploadLease(upload)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":         "O tamanho do arquivo enviado não confere com o declarado",
+			"expected_size": upload.Length,
+			"received_size": info.Size,
+		})
+		return
+	}
+
+	doc, ok := finalizeUpload(c, store, upload)
+	if !ok {
+		releaseUploadLease(upload)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Documento criado com sucesso",
+		"id":           doc.ID.Hex(),
+		"title":        doc.Title,
+		"content_type": doc.Metadata.ContentType,
+		"extension":    doc.Metadata.OriginalExtension,
+		"size":         doc.Metadata.FileSize,
+	})
+}
+
+// DeletePresignedUpload cancela um envio pré-assinado e remove o arquivo, se já gravado
+func DeletePresignedUpload(c *gin.Context) {
+	cancelUpload(c, models.UploadPresigned)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"gestor-e-docs/document-service/reconcile"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reconcileMutex impede duas reconciliações simultâneas
+var reconcileMutex sync.Mutex
+
+// ReconcileRequest define as opções da reconciliação; sem apply, nada é alterado
+type ReconcileRequest struct {
+	Apply         bool   `json:"apply" form:"apply"`
+	DeleteOrphans bool   `json:"delete_orphans" form:"delete_orphans"`
+	Repair        bool   `json:"repair" form:"repair"`
+	Grace         string `json:"grace" form:"grace"`
+}
+
+// Reconcile compara os documentos registrados com os objetos armazenados e retorna o relatório
+// de órfãos, referências inexistentes e contagens de blobs divergentes
+func Reconcile(c *gin.Context) {
+	var req ReconcileRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetros inválidos: " + err.Error()})
+		return
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+			return
+		}
+	}
+
+	opts := reconcile.Options{
+		Apply:         req.Apply,
+		DeleteOrphans: req.DeleteOrphans,
+		Repair:        req.Repair,
+	}
+	if req.Grace != "" {
+		grace, err := time.ParseDuration(req.Grace)
+		if err != nil || grace < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Período de carência inválido: " + strconv.Quote(req.Grace)})
+			return
+		}
+		opts.GracePeriod = grace
+	}
+
+	if !reconcileMutex.TryLock() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Já existe uma reconciliação em andamento"})
+		return
+	}
+	defer reconcileMutex.Unlock()
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	log.Printf("Reconciliação solicitada por %v (apply=%t, delete_orphans=%t, repair=%t)", userID, opts.Apply, opts.DeleteOrphans, opts.Repair)
+
+	report, err := reconcile.Run(c.Request.Context(), store, opts)
+	if err != nil {
+		log.Printf("Erro na reconciliação: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao executar a reconciliação"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchRefreshInterval = 5 * time.Minute
+	// searchRefreshOverlap repete a janela anterior da atualização do índice, cobrindo as
+	// gravações datadas antes do início dela mas concluídas depois
+	searchRefreshOverlap = time.Minute
+)
+
+// reindexState guarda a reconstrução do índice de busca em andamento e o relatório da última concluída
+var reindexState struct {
+	sync.Mutex
+	running bool
+	current *models.ReindexReport
+	last    *models.ReindexReport
+}
+
+// StartReindex reconstrói o índice de busca em segundo plano a partir do MongoDB
+func StartReindex(c *gin.Context) {
+	report, started := startReindex(context.Background())
+	if !started {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Já existe uma reconstrução do índice de busca em andamento",
+			"started_at": report.StartedAt,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Reconstrução do índice de busca iniciada",
+		"engine":     report.Engine,
+		"started_at": report.StartedAt,
+	})
+}
+
+// GetReindexReport retorna o relatório da última reconstrução do índice de busca concluída
+func GetReindexReport(c *gin.Context) {
+	reindexState.Lock()
+	defer reindexState.Unlock()
+
+	response := gin.H{
+		"running":     reindexState.running,
+		"last_report": reindexState.last,
+	}
+	if reindexState.running {
+		response["started_at"] = reindexState.current.StartedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// StartSearchIndexBackfill preenche em segundo plano um índice do Bleve recém-criado com os
+// documentos existentes
+func StartSearchIndexBackfill(ctx context.Context) {
+	index, err := search.GetSearchIndex()
+	if err != nil {
+		return
+	}
+	if bleveIndex, ok := index.(*search.BleveIndex); ok && bleveIndex.IsNew() {
+		log.Println("Índice de busca vazio; indexando os documentos existentes")
+		startReindex(ctx)
+	}
+}
+
+// StartSearchIndexRefresh inicia a rotina que regrava no índice do Bleve os documentos
+// alterados ou visualizados desde a rodada anterior. O índice do MongoDB dispensa a rotina.
+func StartSearchIndexRefresh(ctx context.Context) {
+	index, err := search.GetSearchIndex()
+	if err != nil {
+		return
+	}
+	bleveIndex, ok := index.(*search.BleveIndex)
+	if !ok {
+		return
+	}
+	interval := envDuration("SEARCH_REFRESH_INTERVAL", defaultSearchRefreshInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		since := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				startedAt := time.Now()
+				refreshed, err := bleveIndex.Refresh(ctx, since.Add(-searchRefreshOverlap))
+				if err != nil {
+					log.Printf("Erro ao atualizar o índice de busca: %v", err)
+					continue
+				}
+				since = startedAt
+				if refreshed > 0 {
+					log.Printf("%d documento(s) atualizado(s) no índice de busca", refreshed)
+				}
+			}
+		}
+	}()
+}
+
+// Funções auxiliares para a manutenção do índice de busca
+
+// startReindex dispara a reconstrução se nenhuma estiver em andamento. Retorna o relatório
+// em andamento e se uma nova reconstrução foi iniciada.
+func startReindex(ctx context.Context) (*models.ReindexReport, bool) {
+	reindexState.Lock()
+	defer reindexState.Unlock()
+
+	if reindexState.running {
+		return reindexState.current, false
+	}
+
+	index, err := search.GetSearchIndex()
+	report := &models.ReindexReport{StartedAt: time.Now()}
+	if err == nil {
+		report.Engine = index.Engine()
+	}
+	reindexState.running = true
+	reindexState.current = report
+
+	go func() {
+		if err == nil {
+			report.Indexed, err = index.Rebuild(ctx)
+		}
+		finishedAt := time.Now()
+		if err != nil {
+			log.Printf("Erro ao reconstruir o índice de busca: %v", err)
+			report.Error = err.Error()
+		} else {
+			log.Printf("Índice de busca reconstruído: %d documentos em %s", report.Indexed, finishedAt.Sub(report.StartedAt))
+		}
+
+		reindexState.Lock()
+		report.FinishedAt = &finishedAt
+		reindexState.running = false
+		reindexState.current = nil
+		reindexState.last = report
+		reindexState.Unlock()
+	}()
+
+	return report, true
+}
+
+// syncSearchIndex atualiza o documento no índice de busca. Falhas são apenas registradas:
+// a gravação no MongoDB já foi concluída, e a reconstrução do índice corrige a diferença.
+func syncSearchIndex(doc *models.Document) {
+	index, err := search.GetSearchIndex()
+	if err == nil {
+		err = index.Index(doc)
+	}
+	if err != nil {
+		log.Printf("Erro ao atualizar o documento %s no índice de busca: %v", doc.ID.Hex(), err)
+	}
+}
+
+// removeFromSearchIndex retira o documento do índice de busca, registrando as falhas como
+// syncSearchIndex
+func removeFromSearchIndex(id string) {
+	index, err := search.GetSearchIndex()
+	if err == nil {
+		err = index.Remove(id)
+	}
+	if err != nil {
+		log.Printf("Erro ao remover o documento %s do índice de busca: %v", id, err)
+	}
+}
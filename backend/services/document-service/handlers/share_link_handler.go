@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/metrics"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	shareTokenBytes       = 32
+	shareTokenPrefixLen   = 8
+	shareLinkPasswordMax  = 72 // Limite do bcrypt
+	shareLinkPasswordMin  = 4
+	shareLinkPasswordHdr  = "X-Share-Password"
+	sharedContentBasePath = "/api/v1/documents/shared/"
+	sharedPreviewLimit    = 1 << 20 // Tamanho máximo exibido na visualização HTML
+
+	shareLinkMaxPasswordAttempts = 5                // Senhas incorretas seguidas de um cliente antes do bloqueio
+	shareLinkPasswordLockout     = 15 * time.Minute // Duração do bloqueio de novas senhas do cliente
+	shareDownloadTokenField      = "download_token"
+	shareDownloadTokenTTL        = 10 * time.Minute // Validade da autorização de download emitida após a senha
+)
+
+var (
+	shareDownloadKey     []byte
+	shareDownloadKeyOnce sync.Once
+)
+
+// sharedViewTemplate renderiza a visualização HTML de um documento compartilhado
+var sharedViewTemplate = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex, nofollow">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #222; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1rem; }
+pre { white-space: pre-wrap; word-wrap: break-word; background: #f7f7f7; padding: 1rem; border-radius: 4px; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>Atualizado em {{.UpdatedAt.Format "02/01/2006 15:04"}}{{if .CanDownload}} · {{if .DownloadToken}}<form method="post" action="?download=true" style="display:inline"><input type="hidden" name="download_token" value="{{.DownloadToken}}"><button type="submit">Baixar arquivo</button></form>{{else}}<a href="?download=true">Baixar arquivo</a>{{end}}{{end}}</p>
+</header>
+{{if .IsText}}<pre>{{.Content}}</pre>{{if .Truncated}}<p>Conteúdo truncado na pré-visualização.</p>{{end}}{{else}}<p>Pré-visualização indisponível para este tipo de arquivo.</p>{{end}}
+</body>
+</html>
+`))
+
+// CreateShareLink cria um link público para um documento
+func CreateShareLink(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req models.ShareLinkCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Scope == "" {
+		req.Scope = models.ShareScopeReadOnly
+	}
+	if !req.Scope.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Escopo inválido. Use read_only ou download"})
+		return
+	}
+	if req.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O limite de usos não pode ser negativo"})
+		return
+	}
+
+	link := &models.ShareLink{
+		DocumentID: doc.ID.Hex(),
+		Scope:      req.Scope,
+		MaxUses:    req.MaxUses,
+		CreatedBy:  userID,
+	}
+
+	// Validade: data explícita ou quantidade de horas a partir de agora
+	now := time.Now()
+	switch {
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A data de expiração deve estar no futuro"})
+			return
+		}
+		expiresAt := *req.ExpiresAt
+		link.ExpiresAt = &expiresAt
+	case req.ExpiresInHours < 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A validade não pode ser negativa"})
+		return
+	case req.ExpiresInHours > 0:
+		expiresAt := now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if req.Password != "" {
+		if len(req.Password) < shareLinkPasswordMin || len(req.Password) > shareLinkPasswordMax {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A senha deve ter entre 4 e 72 caracteres"})
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Printf("Erro ao gerar hash da senha do link: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar o link"})
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		log.Printf("Erro ao gerar token do link: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar o link"})
+		return
+	}
+	link.TokenHash = hashShareToken(token)
+	link.TokenPrefix = token[:shareTokenPrefixLen]
+
+	if err := db.DbCollections.ShareLinks.InsertLink(link); err != nil {
+		log.Printf("Erro ao salvar link do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar o link"})
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID: doc.ID.Hex(),
+		ActorID:    userID,
+		Action:     models.AuditShareLinkCreated,
+		NewValue:   string(link.Scope),
+		Details: map[string]interface{}{
+			"link_id":      link.ID.Hex(),
+			"expires_at":   link.ExpiresAt,
+			"max_uses":     link.MaxUses,
+			"has_password": link.HasPassword(),
+		},
+	})
+
+	// O token só é exibido neste momento
+	response := shareLinkResponse(link)
+	response["token"] = token
+	response["url"] = sharedContentBasePath + token
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListShareLinks lista os links públicos de um documento
+func ListShareLinks(c *gin.Context) {
+	doc, _, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	links, err := db.DbCollections.ShareLinks.ListByDocument(doc.ID.Hex())
+	if err != nil {
+		log.Printf("Erro ao listar links do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao listar os links"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(links))
+	for i := range links {
+		items = append(items, shareLinkResponse(&links[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"links":       items,
+	})
+}
+
+// RevokeShareLink revoga um link público de um documento
+func RevokeShareLink(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	linkID := c.Param("linkId")
+	link, err := db.DbCollections.ShareLinks.RevokeLink(linkID, doc.ID.Hex(), userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link não encontrado ou já revogado"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de link inválido"})
+		return
+	}
+
+	recordAudit(c, &models.AuditEntry{
+		DocumentID: doc.ID.Hex(),
+		ActorID:    userID,
+		Action:     models.AuditShareLinkRevoked,
+		OldValue:   string(link.Scope),
+		Details:    map[string]interface{}{"link_id": link.ID.Hex()},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Link revogado com sucesso",
+		"link":    shareLinkResponse(link),
+	})
+}
+
+// GetSharedContent serve um documento através de um link público, sem autenticação.
+// Links somente leitura exibem apenas a visualização HTML renderizada. Links com escopo
+// download entregam o arquivo inline, a visualização com format=html e o arquivo como
+// anexo com download=true. A senha, quando exigida, vai no cabeçalho X-Share-Password
+// ou no campo password de um POST, nunca na URL. Depois de conferida a senha, a visualização
+// leva uma autorização de download assinada e de curta duração em vez da própria senha.
+func GetSharedContent(c *gin.Context) {
+	// Nenhuma resposta pode ficar em cache: as de links com senha carregam a autorização de
+	// download e o conteúdo liberado por ela
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+
+	token := c.Param("token")
+	link, err := db.DbCollections.ShareLinks.GetByTokenHash(hashShareToken(token))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link não encontrado"})
+		return
+	}
+
+	if status := link.Status(time.Now()); status != "active" {
+		c.JSON(http.StatusGone, gin.H{"error": "Este link não está mais disponível", "status": status})
+		return
+	}
+
+	if !checkSharePassword(c, link) {
+		return
+	}
+
+	canDownload := link.Scope == models.ShareScopeDownload
+	download := c.Query("download") == "true"
+	if download && !canDownload {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Este link não permite download"})
+		return
+	}
+	// Sem o escopo download, o conteúdo original nunca é entregue
+	renderHTML := !download && (c.Query("format") == "html" || !canDownload)
+
+	doc, err := db.DbCollections.Documents.GetDocumentByID(link.DocumentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	object, info, err := store.Get(doc.StoragePath)
+	if err != nil {
+		log.Printf("Erro ao obter objeto do armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao acessar arquivo"})
+		return
+	}
+	defer object.Close()
+
+	// Registrar o uso somente quando o conteúdo será de fato entregue
+	if _, err := db.DbCollections.ShareLinks.ConsumeUse(link.ID); err != nil {
+		if err == db.ErrShareLinkUnavailable {
+			c.JSON(http.StatusGone, gin.H{"error": "Este link não está mais disponível"})
+			return
+		}
+		log.Printf("Erro ao registrar uso do link %s: %v", link.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao acessar o link"})
+		return
+	}
+
+	metrics.DocumentOperations.WithLabelValues("share_link_access").Inc()
+	updateViewCountAsync(doc.ID.Hex())
+
+	contentType := documentContentType(doc)
+
+	if renderHTML {
+		// A pré-visualização exibe no máximo sharedPreviewLimit bytes do conteúdo textual
+		isText := isTextContentType(contentType)
+		preview := ""
+		truncated := false
+		if isText {
+			content, err := io.ReadAll(io.LimitReader(object, sharedPreviewLimit+1))
+			if err != nil {
+				log.Printf("Erro ao ler documento compartilhado %s: %v", doc.ID.Hex(), err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao acessar arquivo"})
+				return
+			}
+			truncated = len(content) > sharedPreviewLimit
+			if truncated {
+				content = content[:sharedPreviewLimit]
+			}
+			preview = string(content)
+		}
+
+		downloadToken := ""
+		if canDownload && link.HasPassword() {
+			downloadToken = shareDownloadToken(link.ID, time.Now())
+		}
+
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		err := sharedViewTemplate.Execute(c.Writer, gin.H{
+			"Title":         doc.Title,
+			"UpdatedAt":     doc.UpdatedAt,
+			"Content":       preview,
+			"Truncated":     truncated,
+			"IsText":        isText,
+			"CanDownload":   canDownload,
+			"DownloadToken": downloadToken,
+		})
+		if err != nil {
+			log.Printf("Erro ao renderizar documento compartilhado %s: %v", doc.ID.Hex(), err)
+		}
+		return
+	}
+
+	disposition := "inline"
+	if download {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, object, map[string]string{
+		"Content-Disposition": contentDisposition(disposition, documentFilename(doc)),
+	})
+}
+
+// Funções auxiliares para links públicos
+
+// checkSharePassword confere a senha do link ou a autorização de download emitida após ela.
+// Cada cliente fica bloqueado após shareLinkMaxPasswordAttempts senhas incorretas seguidas,
+// sem afetar os demais. Retorna false se a resposta já foi enviada.
+func checkSharePassword(c *gin.Context, link *models.ShareLink) bool {
+	if !link.HasPassword() {
+		return true
+	}
+
+	now := time.Now()
+	if c.Request.Method == http.MethodPost {
+		if token := c.PostForm(shareDownloadTokenField); token != "" {
+			if validShareDownloadToken(token, link.ID, now) {
+				return true
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "Autorização de download expirada. Informe a senha novamente", "password_required": true})
+			return false
+		}
+	}
+
+	clientKey := hashShareToken(c.ClientIP())
+	lockedUntil, err := db.DbCollections.ShareLinks.PasswordLockedUntil(link.ID, clientKey)
+	if err != nil {
+		log.Printf("Erro ao consultar as senhas incorretas do link %s: %v", link.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao acessar o link"})
+		return false
+	}
+	if lockedUntil != nil {
+		respondPasswordLocked(c, *lockedUntil)
+		return false
+	}
+
+	password := c.GetHeader(shareLinkPasswordHdr)
+	if password == "" && c.Request.Method == http.MethodPost {
+		password = c.PostForm("password")
+	}
+	if password == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Este link exige senha", "password_required": true})
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		lockedUntil, err := db.DbCollections.ShareLinks.RecordFailedPassword(link.ID, clientKey, shareLinkMaxPasswordAttempts, shareLinkPasswordLockout)
+		if err != nil {
+			log.Printf("Erro ao registrar senha incorreta do link %s: %v", link.ID.Hex(), err)
+		}
+		if lockedUntil != nil {
+			log.Printf("Cliente bloqueado no link %s após %d senhas incorretas", link.ID.Hex(), shareLinkMaxPasswordAttempts)
+			respondPasswordLocked(c, *lockedUntil)
+			return false
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Senha incorreta", "password_required": true})
+		return false
+	}
+
+	if err := db.DbCollections.ShareLinks.ResetFailedPasswords(link.ID, clientKey); err != nil {
+		log.Printf("Erro ao zerar as senhas incorretas do link %s: %v", link.ID.Hex(), err)
+	}
+	return true
+}
+
+// shareDownloadToken emite a autorização de download de um link com senha, válida por
+// shareDownloadTokenTTL e apenas para esse link
+func shareDownloadToken(linkID primitive.ObjectID, now time.Time) string {
+	payload := make([]byte, len(linkID)+8)
+	copy(payload, linkID[:])
+	binary.BigEndian.PutUint64(payload[len(linkID):], uint64(now.Add(shareDownloadTokenTTL).Unix()))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signShareDownload(payload))
+}
+
+// validShareDownloadToken confere a assinatura, o link e a validade de uma autorização de download
+func validShareDownloadToken(token string, linkID primitive.ObjectID, now time.Time) bool {
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != len(linkID)+8 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signShareDownload(payload)) {
+		return false
+	}
+	if !bytes.Equal(payload[:len(linkID)], linkID[:]) {
+		return false
+	}
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload[len(linkID):])), 0)
+	return now.Before(expiresAt)
+}
+
+// signShareDownload assina as autorizações de download com uma chave derivada de JWT_SECRET_KEY
+func signShareDownload(payload []byte) []byte {
+	shareDownloadKeyOnce.Do(func() {
+		mac := hmac.New(sha256.New, []byte(getSecretKey()))
+		mac.Write([]byte("document-service/share-download"))
+		shareDownloadKey = mac.Sum(nil)
+	})
+	mac := hmac.New(sha256.New, shareDownloadKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// respondPasswordLocked informa que o cliente não pode tentar novas senhas até o fim do bloqueio
+func respondPasswordLocked(c *gin.Context, lockedUntil time.Time) {
+	retryAfter := int(time.Until(lockedUntil).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":        "Muitas senhas incorretas. Tente novamente mais tarde",
+		"locked_until": lockedUntil,
+	})
+}
+
+// generateShareToken gera um token aleatório seguro para uso em URLs
+func generateShareToken() (string, error) {
+	buf := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashShareToken calcula o hash armazenado para um token
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// shareLinkResponse monta a representação de um link para o proprietário
+func shareLinkResponse(link *models.ShareLink) gin.H {
+	return gin.H{
+		"id":           link.ID.Hex(),
+		"document_id":  link.DocumentID,
+		"token_prefix": link.TokenPrefix,
+		"scope":        link.Scope,
+		"has_password": link.HasPassword(),
+		"expires_at":   link.ExpiresAt,
+		"max_uses":     link.MaxUses,
+		"use_count":    link.UseCount,
+		"status":       link.Status(time.Now()),
+		"created_by":   link.CreatedBy,
+		"created_at":   link.CreatedAt,
+		"last_used_at": link.LastUsedAt,
+		"revoked_at":   link.RevokedAt,
+	}
+}
+
+// isTextContentType indica se o conteúdo pode ser exibido como texto
+func isTextContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/")
+}
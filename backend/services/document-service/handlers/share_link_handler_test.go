@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShareDownloadToken(t *testing.T) {
+	linkID := primitive.NewObjectID()
+	now := time.Now()
+	token := shareDownloadToken(linkID, now)
+
+	// Trocar o primeiro caractere altera o ID do link sem refazer a assinatura
+	tamper := func(token string) string {
+		first := "A"
+		if strings.HasPrefix(token, "A") {
+			first = "B"
+		}
+		return first + token[1:]
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		link  primitive.ObjectID
+		at    time.Time
+		want  bool
+	}{
+		{"válida", token, linkID, now, true},
+		{"perto de expirar", token, linkID, now.Add(shareDownloadTokenTTL - time.Second), true},
+		{"expirada", token, linkID, now.Add(shareDownloadTokenTTL + time.Second), false},
+		{"de outro link", token, primitive.NewObjectID(), now, false},
+		{"conteúdo adulterado", tamper(token), linkID, now, false},
+		{"sem assinatura", strings.SplitN(token, ".", 2)[0], linkID, now, false},
+		{"vazia", "", linkID, now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validShareDownloadToken(tt.token, tt.link, tt.at); got != tt.want {
+				t.Errorf("validShareDownloadToken() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
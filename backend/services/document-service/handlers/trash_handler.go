@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/metrics"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	defaultTrashPurgeInterval = time.Hour
+	trashPurgeBatchSize       = 100
+)
+
+// ListTrash lista os documentos na lixeira do usuário
+func ListTrash(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	docs, total, err := db.DbCollections.Documents.ListTrash(userID.(string), offset, limit)
+	if err != nil {
+		log.Printf("Erro ao buscar lixeira: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar a lixeira"})
+		return
+	}
+
+	retention := trashRetention()
+	items := make([]gin.H, 0, len(docs))
+	for _, doc := range docs {
+		items = append(items, gin.H{
+			"id":          doc.ID.Hex(),
+			"title":       doc.Title,
+			"owner_id":    doc.Permissions.OwnerID,
+			"folder_id":   doc.FolderID,
+			"deleted_at":  doc.DeletedAt,
+			"deleted_by":  doc.DeletedBy,
+			"purge_after": doc.DeletedAt.Add(retention),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": items,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// RestoreFromTrash retira um documento da lixeira
+func RestoreFromTrash(c *gin.Context) {
+	doc, userID, ok := loadTrashedDocument(c)
+	if !ok {
+		return
+	}
+
+	// Voltar para a pasta original exige permissão de escrita nela; se ela não existir mais
+	// ou o usuário não puder escrever nela, restaurar na raiz
+	folderID := doc.FolderID
+	if folderID != "" {
+		chain, err := db.DbCollections.Folders.GetFolderChain(folderID)
+		switch {
+		case err != nil:
+			log.Printf("Pasta %s do documento %s não encontrada, restaurando na raiz", folderID, doc.ID.Hex())
+			folderID = ""
+		case !folderChainAccess(chain, userID).Includes(models.AccessWrite):
+			log.Printf("Usuário %s sem permissão de escrita na pasta %s, restaurando o documento %s na raiz", userID, folderID, doc.ID.Hex())
+			folderID = ""
+		}
+	}
+
+	restored, err := db.DbCollections.Documents.RestoreDocument(doc.ID.Hex(), folderID, doc.Revision)
+	if err == db.ErrRevisionConflict {
+		respondTrashConflict(c, doc.ID.Hex())
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao restaurar documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao restaurar o documento"})
+		return
+	}
+	syncSearchIndex(restored)
+
+	log.Printf("Documento %s restaurado da lixeira por %s", doc.ID.Hex(), userID)
+	metrics.DocumentOperations.WithLabelValues("trash_restore").Inc()
+
+	c.Header("ETag", restored.ETag())
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Documento restaurado com sucesso",
+		"id":        restored.ID.Hex(),
+		"folder_id": restored.FolderID,
+	})
+}
+
+// PurgeFromTrash exclui definitivamente um documento que está na lixeira
+func PurgeFromTrash(c *gin.Context) {
+	doc, userID, ok := loadTrashedDocument(c)
+	if !ok {
+		return
+	}
+
+	err := purgeDocument(doc)
+	if err == db.ErrRevisionConflict {
+		respondTrashConflict(c, doc.ID.Hex())
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao excluir definitivamente o documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao excluir o documento"})
+		return
+	}
+
+	log.Printf("Documento %s excluído definitivamente por %s", doc.ID.Hex(), userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Documento excluído definitivamente",
+	})
+}
+
+// StartTrashPurger inicia a rotina que exclui definitivamente os documentos
+// que estão na lixeira há mais tempo que o período de retenção
+func StartTrashPurger(ctx context.Context) {
+	interval := envDuration("TRASH_PURGE_INTERVAL", defaultTrashPurgeInterval)
+
+	log.Printf("Limpeza da lixeira agendada a cada %v (retenção de %v)", interval, trashRetention())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeExpiredTrash()
+
+			select {
+			case <-ctx.Done():
+				log.Println("Limpeza da lixeira finalizada")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// purgeExpiredTrash exclui definitivamente os documentos cujo período de retenção expirou
+func purgeExpiredTrash() {
+	cutoff := time.Now().Add(-trashRetention())
+
+	for {
+		docs, err := db.DbCollections.Documents.ListExpiredTrash(cutoff, trashPurgeBatchSize)
+		if err != nil {
+			log.Printf("Erro ao buscar documentos expirados na lixeira: %v", err)
+			return
+		}
+
+		purged := 0
+		for i := range docs {
+			if err := purgeDocument(&docs[i]); err != nil {
+				log.Printf("Erro ao excluir definitivamente o documento %s: %v", docs[i].ID.Hex(), err)
+				continue
+			}
+			purged++
+		}
+
+		if purged > 0 {
+			log.Printf("Limpeza da lixeira: %d documento(s) excluído(s) definitivamente", purged)
+		}
+
+		// Parar quando o lote não estiver cheio ou nenhum documento puder ser excluído
+		if len(docs) < trashPurgeBatchSize || purged == 0 {
+			return
+		}
+	}
+}
+
+// purgeDocument remove definitivamente o registro do documento e libera o conteúdo das suas versões no armazenamento
+func purgeDocument(doc *models.Document) error {
+	// Excluir do MongoDB primeiro, garantindo que o documento não foi restaurado nesse meio-tempo
+	revision := doc.Revision
+	if err := db.DbCollections.Documents.DeleteDocument(doc.ID.Hex(), &revision); err != nil {
+		return err
+	}
+	removeFromSearchIndex(doc.ID.Hex())
+	metrics.DocumentOperations.WithLabelValues("trash_purge").Inc()
+
+	// Invalidar os links públicos do documento
+	if _, err := db.DbCollections.ShareLinks.RevokeByDocument(doc.ID.Hex(), doc.DeletedBy); err != nil {
+		log.Printf("Aviso: Erro ao revogar links públicos do documento %s: %v", doc.ID.Hex(), err)
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Aviso: Erro ao obter o armazenamento, arquivos do documento %s não foram removidos: %v", doc.ID.Hex(), err)
+		return nil
+	}
+
+	// Liberar o conteúdo de todas as versões; blobs compartilhados com outros documentos são mantidos
+	releaseDocumentObjects(store, doc)
+
+	return nil
+}
+
+// respondTrashConflict responde com 412 e o estado atual de um documento que mudou desde que
+// foi lido da lixeira: alterado ainda na lixeira ou já restaurado por outra requisição
+func respondTrashConflict(c *gin.Context, docID string) {
+	if current, err := db.DbCollections.Documents.GetDeletedDocumentByID(docID); err == nil {
+		respondPreconditionFailed(c, current)
+		return
+	}
+	respondRevisionConflict(c, docID)
+}
+
+// loadTrashedDocument busca um documento na lixeira e verifica se o usuário pode gerenciá-lo.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadTrashedDocument(c *gin.Context) (*models.Document, string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return nil, "", false
+	}
+
+	doc, err := db.DbCollections.Documents.GetDeletedDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado na lixeira"})
+		return nil, "", false
+	}
+
+	if !hasAdminAccess(doc, userID.(string)) && doc.DeletedBy != userID.(string) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para gerenciar este documento"})
+		return nil, "", false
+	}
+
+	return doc, userID.(string), true
+}
+
+// trashRetention retorna por quanto tempo os documentos permanecem na lixeira
+func trashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Aviso: TRASH_RETENTION_DAYS inválido (%q), usando %d dias", value, days)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
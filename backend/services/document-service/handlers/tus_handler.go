@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Implementação do protocolo tus 1.0 (https://tus.io/protocols/resumable-upload) com as
+// extensões creation, termination e expiration. Os bytes recebidos são gravados como
+// partes de um upload multipart do armazenamento e, ao final, viram um documento.
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+
+	// tusPartSize é o tamanho das partes enviadas ao armazenamento; precisa ser ao menos storage.MinPartSize
+	tusPartSize = 8 << 20
+)
+
+// TusOptions informa a versão e as extensões do protocolo suportadas
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(uploadMaxSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload inicia um envio retomável. O tamanho total vem em Upload-Length e o nome
+// do arquivo, a pasta e a descrição podem vir em Upload-Metadata.
+func CreateUpload(c *gin.Context) {
+	userID, ok := uploadUser(c)
+	if !ok {
+		return
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Defer-Length não é suportado; informe Upload-Length"})
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length inválido"})
+		return
+	}
+	if length > uploadMaxSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "O arquivo excede o tamanho máximo permitido"})
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Metadata inválido"})
+		return
+	}
+
+	filename := filepath.Base(strings.TrimSpace(metadata["filename"]))
+	if filename == "." || filename == "/" {
+		filename = ""
+	}
+	if filename == "" {
+		filename = "documento"
+	}
+
+	// Criar dentro de uma pasta exige permissão de escrita nela
+	folderID := strings.TrimSpace(metadata["folder_id"])
+	if folderID != "" {
+		if _, ok := loadFolderChain(c, folderID, userID, models.AccessWrite); !ok {
+			return
+		}
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	// O tipo real só é conhecido ao final; até lá vale o informado pela extensão
+	extension := "bin"
+	contentType := "application/octet-stream"
+	if declared, ok := filetype.ByExtension(filepath.Ext(filename)); ok {
+		extension = declared.Extension
+		contentType = declared.ContentType
+	}
+
+	objectName := storage.NewObjectName(userID, "uploads", extension)
+	multipartID, err := store.NewMultipartUpload(objectName, contentType)
+	if err != nil {
+		log.Printf("Erro ao iniciar envio retomável de %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao iniciar o envio"})
+		return
+	}
+
+	upload := models.Upload{
+		Protocol:    models.UploadTus,
+		UserID:      userID,
+		Filename:    filename,
+		FolderID:    folderID,
+		Description: strings.TrimSpace(metadata["description"]),
+		Length:      length,
+		ObjectName:  objectName,
+		MultipartID: multipartID,
+		ExpiresAt:   time.Now().Add(uploadExpiration()),
+	}
+	if err := db.DbCollections.Uploads.InsertUpload(&upload); err != nil {
+		log.Printf("Erro ao registrar envio retomável: %v", err)
+		if abortErr := store.AbortMultipartUpload(objectName, multipartID); abortErr != nil {
+			log.Printf("Erro ao cancelar upload multipart após falha: %v", abortErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao iniciar o envio"})
+		return
+	}
+
+	log.Printf("Envio retomável %s iniciado por %s (%s, %d bytes)", upload.ID.Hex(), userID, filename, length)
+	c.Header("Location", "/api/v1/documents/uploads/"+upload.ID.Hex())
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetUploadOffset informa quantos bytes do envio já foram recebidos
+func GetUploadOffset(c *gin.Context) {
+	upload, _, ok := loadUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Protocol != models.UploadTus {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envio não encontrado"})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+	if upload.DocumentID != "" {
+		c.Header("X-Document-Id", upload.DocumentID)
+	}
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload recebe um trecho do arquivo a partir de Upload-Offset. Quando o último
+// byte chega, o arquivo é montado no armazenamento e o documento é criado.
+func PatchUpload(c *gin.Context) {
+	userID, ok := uploadUser(c)
+	if !ok {
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type deve ser application/offset+octet-stream"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset inválido"})
+		return
+	}
+
+	upload, err := db.DbCollections.Uploads.AcquireLease(c.Param("uploadId"), userID, models.UploadTus, offset, defaultUploadLeaseTTL)
+	switch {
+	case err == db.ErrUploadOffsetMismatch:
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset não corresponde aos bytes já recebidos"})
+		return
+	case err == db.ErrUploadBusy:
+		c.JSON(http.StatusLocked, gin.H{"error": "O envio está recebendo dados de outra requisição"})
+		return
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envio não encontrado"})
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		releaseUploadLease(upload)
+		c.JSON(http.StatusGone, gin.H{"error": "O envio expirou"})
+		return
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		releaseUploadLease(upload)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	storeErr := receiveUploadData(store, upload, io.LimitReader(c.Request.Body, upload.Length-upload.Offset))
+
+	upload.ExpiresAt = time.Now().Add(uploadExpiration())
+	if err := db.DbCollections.Uploads.SaveProgress(upload); err != nil {
+		log.Printf("Erro ao salvar progresso do envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao registrar os dados recebidos"})
+		return
+	}
+	if storeErr != nil {
+		log.Printf("Erro ao armazenar dados do envio %s: %v", upload.ID.Hex(), storeErr)
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar os dados recebidos"})
+		return
+	}
+
+	if upload.Offset == upload.Length {
+		if !finishUpload(c, store, upload) {
+			return
+		}
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusNoContent)
+}
+
+// Funções auxiliares para envios retomáveis
+
+// TusResumable verifica a versão do protocolo pedida pelo cliente e a repete nas respostas
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+			c.Header("Tus-Version", tusVersion)
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "Versão do protocolo tus não suportada"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// receiveUploadData lê o corpo da requisição e o grava em partes de tusPartSize no armazenamento.
+// O trecho final que não completa uma parte fica no objeto auxiliar do envio, salvo quando
+// é o fim do arquivo. Os bytes lidos até um erro de leitura (conexão interrompida) são
+// mantidos; em caso de erro do armazenamento, o envio volta ao último estado consistente.
+func receiveUploadData(store storage.BlobStore, upload *models.Upload, body io.Reader) error {
+	chunk := make([]byte, tusPartSize)
+	filled := 0
+
+	// Retomar o trecho que ficou pendente no PATCH anterior
+	if upload.PendingSize > 0 {
+		object, _, err := store.Get(upload.PendingObjectName())
+		if err != nil {
+			return err
+		}
+		n, err := io.ReadFull(object, chunk[:upload.PendingSize])
+		object.Close()
+		if err != nil {
+			return err
+		}
+		filled = n
+	}
+	hadPending := upload.PendingSize > 0
+
+	for {
+		n, err := io.ReadFull(body, chunk[filled:])
+		filled += n
+
+		if filled == len(chunk) {
+			part, putErr := store.PutPart(upload.ObjectName, upload.MultipartID, len(upload.Parts)+1, chunk)
+			if putErr != nil {
+				// As partes anteriores continuam válidas; os bytes desta parte serão reenviados
+				upload.PendingSize = 0
+				return putErr
+			}
+			upload.Parts = append(upload.Parts, uploadPartFromStorage(part))
+			upload.PendingSize = 0
+			filled = 0
+		}
+
+		if err == nil {
+			continue
+		}
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Printf("Leitura do envio %s interrompida: %v", upload.ID.Hex(), err)
+		}
+		break
+	}
+
+	if filled == 0 {
+		upload.PendingSize = 0
+		if hadPending {
+			removeOrphanObject(upload.PendingObjectName())
+		}
+		return nil
+	}
+
+	// Última parte do arquivo: pode ser menor que o mínimo do armazenamento
+	if upload.PartsSize()+int64(filled) == upload.Length {
+		part, err := store.PutPart(upload.ObjectName, upload.MultipartID, len(upload.Parts)+1, chunk[:filled])
+		if err != nil {
+			upload.PendingSize = 0
+			return err
+		}
+		upload.Parts = append(upload.Parts, uploadPartFromStorage(part))
+		upload.PendingSize = 0
+		if hadPending {
+			removeOrphanObject(upload.PendingObjectName())
+		}
+		return nil
+	}
+
+	pending := chunk[:filled]
+	if err := store.Put(upload.PendingObjectName(), bytes.NewReader(pending), int64(len(pending)), storage.PutOptions{}); err != nil {
+		upload.PendingSize = 0
+		return err
+	}
+	upload.PendingSize = int64(filled)
+	return nil
+}
+
+// finishUpload monta o arquivo a partir das partes enviadas ao armazenamento e cria o documento.
+// Retorna false quando a resposta de erro já foi enviada.
+func finishUpload(c *gin.Context, store storage.BlobStore, upload *models.Upload) bool {
+	parts := make([]storage.UploadedPart, 0, len(upload.Parts))
+	for _, part := range upload.Parts {
+		parts = append(parts, storage.UploadedPart{Number: part.Number, ETag: part.ETag, Size: part.Size})
+	}
+	if err := store.CompleteMultipartUpload(upload.ObjectName, upload.MultipartID, parts); err != nil && !objectExists(store, upload.ObjectName) {
+		// Se o objeto já existe, o upload multipart foi concluído em uma tentativa anterior
+		log.Printf("Erro ao concluir envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao concluir o envio"})
+		return false
+	}
+
+	_, ok := finalizeUpload(c, store, upload)
+	return ok
+}
+
+// uploadPartFromStorage converte a parte enviada ao armazenamento no registro do envio
+func uploadPartFromStorage(part storage.UploadedPart) models.UploadPart {
+	return models.UploadPart{Number: part.Number, ETag: part.ETag, Size: part.Size}
+}
+
+// parseUploadMetadata decodifica o cabeçalho Upload-Metadata: pares "chave valor-base64" separados por vírgula
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, errors.New("par de metadados inválido")
+		}
+	}
+	return metadata, nil
+}
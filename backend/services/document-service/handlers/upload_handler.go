@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Envios de arquivos em duas etapas: pelo protocolo tus (tus_handler.go) ou diretamente
+// ao MinIO por formulário pré-assinado (presigned_upload_handler.go). Os dois compartilham o
+// registro do envio, o cancelamento e a limpeza dos envios expirados.
+
+const (
+	defaultUploadMaxSize         = 10 << 30
+	defaultUploadExpiration      = 24 * time.Hour
+	defaultUploadLeaseTTL        = 15 * time.Minute
+	defaultUploadCleanupInterval = time.Hour
+	uploadCleanupBatch           = 100
+)
+
+// DeleteUpload cancela um envio tus e descarta os dados já recebidos
+func DeleteUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	cancelUpload(c, models.UploadTus)
+}
+
+// cancelUpload cancela um envio do protocolo informado e descarta os dados já recebidos.
+// Envios do outro protocolo são tratados como inexistentes.
+func cancelUpload(c *gin.Context, protocol models.UploadProtocol) {
+	upload, _, ok := loadUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Protocol != protocol {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envio não encontrado"})
+		return
+	}
+
+	if upload.LeaseUntil != nil && time.Now().Before(*upload.LeaseUntil) {
+		c.JSON(http.StatusLocked, gin.H{"error": "O envio está sendo processado por outra requisição"})
+		return
+	}
+
+	if err := discardUpload(upload); err != nil {
+		log.Printf("Erro ao cancelar envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao cancelar o envio"})
+		return
+	}
+
+	log.Printf("Envio %s cancelado", upload.ID.Hex())
+	c.Status(http.StatusNoContent)
+}
+
+// StartUploadReaper inicia a rotina que descarta os envios expirados e os dados já recebidos deles
+func StartUploadReaper(ctx context.Context) {
+	interval := envDuration(uploadEnvName("UPLOAD_CLEANUP_INTERVAL", "TUS_CLEANUP_INTERVAL"), defaultUploadCleanupInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cleanupExpiredUploads()
+			}
+		}
+	}()
+}
+
+// cleanupExpiredUploads descarta um lote de envios expirados
+func cleanupExpiredUploads() {
+	uploads, err := db.DbCollections.Uploads.ListExpired(time.Now(), uploadCleanupBatch)
+	if err != nil {
+		log.Printf("Erro ao buscar envios expirados: %v", err)
+		return
+	}
+
+	removed := 0
+	for i := range uploads {
+		if err := discardUpload(&uploads[i]); err != nil {
+			log.Printf("Erro ao descartar envio expirado %s: %v", uploads[i].ID.Hex(), err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("%d envio(s) expirado(s) descartado(s)", removed)
+	}
+}
+
+// Funções auxiliares para envios de arquivos
+
+// uploadUser obtém o usuário autenticado. Retorna false quando a resposta de erro já foi enviada.
+func uploadUser(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return "", false
+	}
+	return userID.(string), true
+}
+
+// loadUpload busca o envio da rota, que só é visível para quem o iniciou.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadUpload(c *gin.Context) (*models.Upload, string, bool) {
+	userID, ok := uploadUser(c)
+	if !ok {
+		return nil, "", false
+	}
+
+	upload, err := db.DbCollections.Uploads.GetUpload(c.Param("uploadId"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envio não encontrado"})
+		return nil, "", false
+	}
+	if !upload.IsComplete() && time.Now().After(upload.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "O envio expirou"})
+		return nil, "", false
+	}
+
+	return upload, userID, true
+}
+
+// discardUpload remove os dados já gravados e apaga o registro do envio.
+// Envios concluídos já viraram documentos, então apenas o registro é apagado.
+func discardUpload(upload *models.Upload) error {
+	if !upload.IsComplete() {
+		store, err := storage.GetBlobStore()
+		if err != nil {
+			return err
+		}
+		switch upload.Protocol {
+		case models.UploadPresigned:
+			// O cliente pode ter gravado o objeto sem concluir o envio
+			if objectExists(store, upload.ObjectName) {
+				removeOrphanObject(upload.ObjectName)
+			}
+		default:
+			if err := store.AbortMultipartUpload(upload.ObjectName, upload.MultipartID); err != nil {
+				log.Printf("Aviso: %v", err)
+			}
+			if upload.PendingSize > 0 {
+				removeOrphanObject(upload.PendingObjectName())
+			}
+		}
+	}
+	return db.DbCollections.Uploads.DeleteUpload(upload.ID)
+}
+
+// finalizeUpload verifica o arquivo gravado no armazenamento (tamanho, checksum informado e tipo real)
+// e cria o documento correspondente. Retorna false quando a resposta de erro já foi enviada;
+// se o conteúdo não confere com o declarado, o objeto é removido para que o envio seja refeito.
+func finalizeUpload(c *gin.Context, store storage.BlobStore, upload *models.Upload) (*models.Document, bool) {
+	object, info, err := store.Get(upload.ObjectName)
+	if err != nil {
+		log.Printf("Erro ao abrir arquivo do envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao concluir o envio"})
+		return nil, false
+	}
+	defer object.Close()
+
+	if info.Size != upload.Length {
+		removeOrphanObject(upload.ObjectName)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "O tamanho do arquivo enviado não confere com o declarado",
+			"expected_size": upload.Length,
+			"received_size": info.Size,
+		})
+		return nil, false
+	}
+
+	// O hash identifica o blob do conteúdo e confere o checksum declarado pelo cliente
+	hash, _, err := storage.HashContent(io.NewSectionReader(object, 0, info.Size))
+	if err != nil {
+		log.Printf("Erro ao calcular checksum do envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao concluir o envio"})
+		return nil, false
+	}
+	if upload.SHA256 != "" && hash != upload.SHA256 {
+		removeOrphanObject(upload.ObjectName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O checksum SHA-256 do arquivo enviado não confere com o declarado"})
+		return nil, false
+	}
+
+	// Identificar o tipo real pelo conteúdo, independentemente da extensão informada
+	fileType, ok := filetype.DetectReader(upload.Filename, object, info.Size)
+	if !ok || !filetype.Allowed()[fileType.Extension] {
+		removeOrphanObject(upload.ObjectName)
+		if err := db.DbCollections.Uploads.DeleteUpload(upload.ID); err != nil {
+			log.Printf("Erro ao remover envio %s: %v", upload.ID.Hex(), err)
+		}
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Tipo de arquivo não permitido. Tipos aceitos: " + strings.Join(filetype.AllowedList(), ", "),
+		})
+		return nil, false
+	}
+
+	textContent, err := readIndexedText(fileType, object, info.Size)
+	if err != nil {
+		log.Printf("Erro ao ler arquivo do envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao concluir o envio"})
+		return nil, false
+	}
+
+	blob, err := adoptBlob(store, upload.ObjectName, hash, info.Size, fileType.ContentType)
+	if err != nil {
+		log.Printf("Erro ao armazenar o conteúdo do envio %s: %v", upload.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao concluir o envio"})
+		return nil, false
+	}
+
+	newDoc := newUploadedDocument(titleFromFilename(upload.Filename), textContent, fileType, info.Size, upload.UserID, upload.FolderID, upload.Description)
+	newDoc.StoragePath = blob.ObjectName
+	newDoc.ContentHash = blob.Hash
+	if upload.DocumentID != "" {
+		// Manter o ID reservado, que já faz parte do caminho do objeto
+		if reserved, err := primitive.ObjectIDFromHex(upload.DocumentID); err == nil {
+			newDoc.ID = reserved
+		}
+	}
+
+	if err := db.DbCollections.Documents.InsertDocument(&newDoc); err != nil {
+		log.Printf("Erro ao inserir documento do envio %s no MongoDB: %v", upload.ID.Hex(), err)
+		releaseBlob(blob.Hash)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar o documento"})
+		return nil, false
+	}
+	syncSearchIndex(&newDoc)
+
+	if err := db.DbCollections.Uploads.MarkCompleted(upload.ID, newDoc.ID.Hex()); err != nil {
+		log.Printf("Erro ao marcar envio %s como concluído: %v", upload.ID.Hex(), err)
+	}
+
+	log.Printf("Envio %s concluído no documento %s", upload.ID.Hex(), newDoc.ID.Hex())
+	c.Header("X-Document-Id", newDoc.ID.Hex())
+	return &newDoc, true
+}
+
+// objectExists verifica se o objeto já está disponível no armazenamento
+func objectExists(store storage.BlobStore, objectName string) bool {
+	_, err := store.Stat(objectName)
+	return err == nil
+}
+
+// releaseUploadLease libera a reserva do envio sem alterar os dados recebidos
+func releaseUploadLease(upload *models.Upload) {
+	if err := db.DbCollections.Uploads.SaveProgress(upload); err != nil {
+		log.Printf("Erro ao liberar envio %s: %v", upload.ID.Hex(), err)
+	}
+}
+
+// uploadMaxSize retorna o maior arquivo aceito, configurável por UPLOAD_MAX_SIZE (em bytes)
+func uploadMaxSize() int64 {
+	name := uploadEnvName("UPLOAD_MAX_SIZE", "TUS_MAX_SIZE")
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultUploadMaxSize
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		log.Printf("Aviso: %s inválido (%q), usando %d", name, value, int64(defaultUploadMaxSize))
+		return defaultUploadMaxSize
+	}
+	return size
+}
+
+// uploadExpiration retorna por quanto tempo um envio não concluído é mantido, configurável por UPLOAD_EXPIRATION
+func uploadExpiration() time.Duration {
+	return envDuration(uploadEnvName("UPLOAD_EXPIRATION", "TUS_UPLOAD_EXPIRATION"), defaultUploadExpiration)
+}
+
+// uploadEnvName escolhe a variável de ambiente de uma configuração dos envios. As variáveis
+// TUS_* da primeira versão dos envios continuam valendo quando a UPLOAD_* correspondente não
+// está definida.
+func uploadEnvName(name string, legacy string) string {
+	if os.Getenv(name) == "" && os.Getenv(legacy) != "" {
+		return legacy
+	}
+	return name
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListVersions lista o histórico de versões de um documento
+func ListVersions(c *gin.Context) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	// Buscar documento no MongoDB
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+
+	// Verificar permissões
+	if !hasReadAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para acessar este documento"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id":     doc.ID.Hex(),
+		"current_version": doc.CurrentVersion(),
+		"versions":        doc.VersionHistory,
+	})
+}
+
+// GetVersion retorna os metadados e o conteúdo Markdown de uma versão específica
+func GetVersion(c *gin.Context) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Número de versão inválido"})
+		return
+	}
+
+	// Buscar documento no MongoDB
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+
+	// Verificar permissões
+	if !hasReadAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para acessar este documento"})
+		return
+	}
+
+	version, found := doc.FindVersion(versionNumber)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Versão não encontrada"})
+		return
+	}
+
+	// Buscar conteúdo da versão no armazenamento
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	content, err := storage.ReadAll(store, version.StoragePath)
+	if err != nil {
+		if respondIntegrityError(c, err) {
+			return
+		}
+		log.Printf("Erro ao buscar versão %d do documento %s no armazenamento: %v", versionNumber, docID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conteúdo da versão não disponível no armazenamento"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID.Hex(),
+		"version":     version,
+		"content":     string(content),
+	})
+}
+
+// RestoreVersion cria uma nova versão do documento a partir do conteúdo de uma versão anterior
+func RestoreVersion(c *gin.Context) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Número de versão inválido"})
+		return
+	}
+
+	// Buscar documento no MongoDB
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return
+	}
+
+	// Verificar permissões de escrita
+	if !hasWriteAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para editar este documento"})
+		return
+	}
+
+	if !doc.Status.IsEditable() {
+		respondNotEditable(c, doc)
+		return
+	}
+	if doc.Lock.BlocksUser(userID.(string), time.Now()) {
+		respondLocked(c, docID)
+		return
+	}
+
+	version, found := doc.FindVersion(versionNumber)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Versão não encontrada"})
+		return
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Erro ao obter o armazenamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no sistema de armazenamento"})
+		return
+	}
+
+	content, err := storage.ReadAll(store, version.StoragePath)
+	if err != nil {
+		if respondIntegrityError(c, err) {
+			return
+		}
+		log.Printf("Erro ao buscar versão %d do documento %s no armazenamento: %v", versionNumber, docID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conteúdo da versão não disponível no armazenamento"})
+		return
+	}
+
+	// O tipo é identificado pelo conteúdo, para que o texto indexado e os metadados do
+	// documento descrevam o arquivo restaurado
+	fileType, _ := filetype.Detect("versao."+doc.Metadata.OriginalExtension, content)
+	contentType := fileType.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// A nova versão referencia o mesmo blob da versão restaurada, sem regravar o conteúdo
+	blob, err := storeBlobBytes(store, content, contentType)
+	if err != nil {
+		log.Printf("Erro ao fazer upload da versão restaurada: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar a versão restaurada"})
+		return
+	}
+
+	textContent, _ := readIndexedText(fileType, bytes.NewReader(content), int64(len(content)))
+	restored := &models.VersionContent{
+		StoragePath: blob.ObjectName,
+		ContentHash: blob.Hash,
+		Text:        textContent,
+		Size:        blob.Size,
+		ContentType: contentType,
+	}
+
+	description := fmt.Sprintf("Restaurado a partir da versão %d", versionNumber)
+	newVersion, err := db.DbCollections.Documents.AddVersion(docID, restored, userID.(string), description)
+	if err != nil {
+		log.Printf("Erro ao registrar versão restaurada: %v", err)
+
+		// Liberar a referência ao conteúdo em caso de falha
+		releaseBlob(blob.Hash)
+
+		if err == db.ErrRevisionConflict {
+			respondRevisionConflict(c, docID)
+			return
+		}
+		if err == db.ErrDocumentNotEditable {
+			respondNotEditable(c, doc)
+			return
+		}
+		if err == db.ErrDocumentLocked {
+			respondLocked(c, docID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao restaurar a versão"})
+		return
+	}
+	if current, err := db.DbCollections.Documents.GetDocumentByID(docID); err == nil {
+		syncSearchIndex(current)
+	} else {
+		log.Printf("Erro ao recarregar o documento %s para o índice de busca: %v", docID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Versão restaurada com sucesso",
+		"id":            docID,
+		"restored_from": versionNumber,
+		"version":       newVersion,
+	})
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"errors"
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/metrics"
+	"gestor-e-docs/document-service/models"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkflow retorna o estado do documento no fluxo de aprovação
+func GetWorkflow(c *gin.Context) {
+	doc, _, _, ok := loadDocumentForWorkflow(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(doc))
+}
+
+// SetReviewers designa os revisores responsáveis por aprovar o documento
+func SetReviewers(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewersUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	if doc.Status.Normalize() == models.StatusReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Os revisores não podem ser alterados durante a revisão"})
+		return
+	}
+
+	// Remover entradas vazias e repetidas, exigindo que todo revisor consiga ler o documento
+	reviewers := []string{}
+	withoutAccess := []string{}
+	for _, id := range req.Reviewers {
+		id = strings.TrimSpace(id)
+		if id == "" || containsString(reviewers, id) {
+			continue
+		}
+		if !hasReadAccess(doc, id) {
+			withoutAccess = append(withoutAccess, id)
+			continue
+		}
+		reviewers = append(reviewers, id)
+	}
+	if len(withoutAccess) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Os revisores precisam ter acesso de leitura ao documento",
+			"without_access": withoutAccess,
+		})
+		return
+	}
+
+	workflow := doc.Workflow
+	workflow.Reviewers = reviewers
+
+	updated, ok := saveWorkflow(c, doc, doc.Status.Normalize(), workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Revisores do documento %s definidos por %s: %v", doc.ID.Hex(), userID, reviewers)
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// SubmitForReview envia um rascunho para revisão
+func SubmitForReview(c *gin.Context) {
+	doc, userID, expectedRevision, ok := loadDocumentForWorkflow(c)
+	if !ok {
+		return
+	}
+
+	if !hasWriteAccess(doc, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para enviar este documento para revisão"})
+		return
+	}
+	if !requireTransition(c, doc, models.StatusReview) {
+		return
+	}
+
+	// O autor do envio não pode aprovar a própria submissão
+	independent := 0
+	for _, reviewer := range doc.Workflow.Reviewers {
+		if reviewer != userID {
+			independent++
+		}
+	}
+	if independent == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Designe ao menos um revisor diferente de você antes de enviar para revisão"})
+		return
+	}
+
+	var req models.WorkflowComment
+	if !bindOptionalComment(c, &req) {
+		return
+	}
+
+	now := time.Now()
+	workflow := doc.Workflow
+	workflow.Round++
+	workflow.SubmittedBy = userID
+	workflow.SubmittedAt = &now
+	workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowSubmit, userID, req.Comment, models.StatusReview, now))
+
+	updated, ok := saveWorkflow(c, doc, models.StatusReview, workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Documento %s enviado para revisão por %s (rodada %d)", doc.ID.Hex(), userID, workflow.Round)
+	metrics.DocumentOperations.WithLabelValues("workflow_submit").Inc()
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// ApproveDocument registra a aprovação de um revisor. O documento é publicado
+// quando todos os revisores designados aprovam a rodada atual.
+func ApproveDocument(c *gin.Context) {
+	doc, userID, expectedRevision, ok := loadDocumentForReviewer(c)
+	if !ok {
+		return
+	}
+
+	if containsString(doc.Workflow.ApprovalsInRound(doc.Workflow.Round), userID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Você já aprovou esta revisão"})
+		return
+	}
+
+	var req models.WorkflowComment
+	if !bindOptionalComment(c, &req) {
+		return
+	}
+
+	now := time.Now()
+	workflow := doc.Workflow
+	workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowApprove, userID, req.Comment, models.StatusReview, now))
+
+	status := models.StatusReview
+	if len(workflow.PendingReviewers()) == 0 {
+		status = models.StatusPublished
+		workflow.PublishedAt = &now
+		workflow.PublishedVersion = doc.CurrentVersion()
+		workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowPublish, userID, "", models.StatusPublished, now))
+	}
+
+	updated, ok := saveWorkflow(c, doc, status, workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Documento %s aprovado por %s (rodada %d)", doc.ID.Hex(), userID, workflow.Round)
+	metrics.DocumentOperations.WithLabelValues("workflow_approve").Inc()
+	if status == models.StatusPublished {
+		log.Printf("Documento %s publicado na versão %d", doc.ID.Hex(), workflow.PublishedVersion)
+		metrics.DocumentOperations.WithLabelValues("workflow_publish").Inc()
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// RejectDocument reprova a revisão, devolvendo o documento para rascunho. O comentário é obrigatório.
+func RejectDocument(c *gin.Context) {
+	doc, userID, expectedRevision, ok := loadDocumentForReviewer(c)
+	if !ok {
+		return
+	}
+
+	var req models.WorkflowComment
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe um comentário explicando a reprovação"})
+		return
+	}
+
+	now := time.Now()
+	workflow := doc.Workflow
+	workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowReject, userID, strings.TrimSpace(req.Comment), models.StatusDraft, now))
+
+	updated, ok := saveWorkflow(c, doc, models.StatusDraft, workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Documento %s reprovado por %s (rodada %d)", doc.ID.Hex(), userID, workflow.Round)
+	metrics.DocumentOperations.WithLabelValues("workflow_reject").Inc()
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// ArchiveDocument arquiva um documento em rascunho ou publicado
+func ArchiveDocument(c *gin.Context) {
+	doc, userID, ok := loadDocumentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return
+	}
+	if !requireTransition(c, doc, models.StatusArchived) {
+		return
+	}
+
+	var req models.WorkflowComment
+	if !bindOptionalComment(c, &req) {
+		return
+	}
+
+	workflow := doc.Workflow
+	workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowArchive, userID, req.Comment, models.StatusArchived, time.Now()))
+
+	updated, ok := saveWorkflow(c, doc, models.StatusArchived, workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Documento %s arquivado por %s", doc.ID.Hex(), userID)
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// CreateDraft abre um novo rascunho a partir de um documento publicado ou arquivado,
+// permitindo editá-lo novamente. A versão publicada continua registrada no histórico.
+func CreateDraft(c *gin.Context) {
+	doc, userID, expectedRevision, ok := loadDocumentForWorkflow(c)
+	if !ok {
+		return
+	}
+
+	if !hasWriteAccess(doc, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para editar este documento"})
+		return
+	}
+	if doc.Status.Normalize() == models.StatusReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "O documento está em revisão e precisa ser aprovado ou reprovado antes"})
+		return
+	}
+	if !requireTransition(c, doc, models.StatusDraft) {
+		return
+	}
+
+	var req models.WorkflowComment
+	if !bindOptionalComment(c, &req) {
+		return
+	}
+
+	workflow := doc.Workflow
+	workflow.Decisions = append(workflow.Decisions, newDecision(doc, workflow.Round, models.WorkflowNewDraft, userID, req.Comment, models.StatusDraft, time.Now()))
+
+	updated, ok := saveWorkflow(c, doc, models.StatusDraft, workflow, userID, expectedRevision)
+	if !ok {
+		return
+	}
+
+	log.Printf("Novo rascunho do documento %s criado por %s", doc.ID.Hex(), userID)
+	c.JSON(http.StatusOK, workflowResponse(updated))
+}
+
+// Funções auxiliares do fluxo de aprovação
+
+// loadDocumentForWorkflow busca o documento da rota, exige acesso de leitura e avalia o If-Match.
+// Retorna false quando a resposta de erro já foi enviada.
+func loadDocumentForWorkflow(c *gin.Context) (*models.Document, string, *int64, bool) {
+	docID := c.Param("id")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return nil, "", nil, false
+	}
+
+	doc, err := db.DbCollections.Documents.GetDocumentByID(docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento não encontrado"})
+		return nil, "", nil, false
+	}
+
+	if !hasReadAccess(doc, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não tem permissão para acessar este documento"})
+		return nil, "", nil, false
+	}
+
+	expectedRevision, ok := checkIfMatch(c, doc)
+	if !ok {
+		return nil, "", nil, false
+	}
+
+	return doc, userID.(string), expectedRevision, true
+}
+
+// loadDocumentForReviewer carrega um documento em revisão e verifica se o usuário
+// é um revisor designado apto a decidir sobre ele
+func loadDocumentForReviewer(c *gin.Context) (*models.Document, string, *int64, bool) {
+	doc, userID, expectedRevision, ok := loadDocumentForWorkflow(c)
+	if !ok {
+		return nil, "", nil, false
+	}
+
+	if doc.Status.Normalize() != models.StatusReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "O documento não está em revisão", "status": doc.Status.Normalize()})
+		return nil, "", nil, false
+	}
+	if !doc.Workflow.IsReviewer(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não é revisor deste documento"})
+		return nil, "", nil, false
+	}
+	if doc.Workflow.SubmittedBy == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não pode decidir sobre uma revisão que você mesmo enviou"})
+		return nil, "", nil, false
+	}
+
+	return doc, userID, expectedRevision, true
+}
+
+// requireTransition responde com 409 quando o fluxo não permite a mudança de estado
+func requireTransition(c *gin.Context, doc *models.Document, target models.DocumentStatus) bool {
+	if doc.Status.CanTransitionTo(target) {
+		return true
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":               "Transição de estado não permitida",
+		"status":              doc.Status.Normalize(),
+		"target":              target,
+		"allowed_transitions": doc.Status.AllowedTransitions(),
+	})
+	return false
+}
+
+// bindOptionalComment lê o comentário opcional do corpo da requisição. Um corpo vazio, com
+// ou sem Content-Length (envio em chunks), equivale a nenhum comentário.
+func bindOptionalComment(c *gin.Context, req *models.WorkflowComment) bool {
+	err := c.ShouldBindJSON(req)
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return false
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	return true
+}
+
+// newDecision monta o registro de uma decisão do fluxo de aprovação
+func newDecision(doc *models.Document, round int, action models.WorkflowAction, actorID string, comment string, to models.DocumentStatus, now time.Time) models.WorkflowDecision {
+	return models.WorkflowDecision{
+		Action:        action,
+		ActorID:       actorID,
+		Comment:       comment,
+		FromStatus:    doc.Status.Normalize(),
+		ToStatus:      to,
+		Round:         round,
+		VersionNumber: doc.CurrentVersion(),
+		Timestamp:     now,
+	}
+}
+
+// saveWorkflow grava o novo estado do fluxo, respondendo com 412 em caso de conflito de revisão
+// e com 423 se outro usuário detém a reserva de edição. A reserva só vale para rascunhos:
+// aprovações e reprovações de documentos em revisão não dependem dela.
+// Retorna false quando a resposta de erro já foi enviada.
+func saveWorkflow(c *gin.Context, doc *models.Document, status models.DocumentStatus, workflow models.DocumentWorkflow, userID string, expectedRevision *int64) (*models.Document, bool) {
+	revision := doc.Revision
+	if expectedRevision != nil {
+		revision = *expectedRevision
+	}
+
+	lockHolder := ""
+	if doc.Status.IsEditable() {
+		lockHolder = userID
+	}
+
+	updated, err := db.DbCollections.Documents.UpdateWorkflow(doc.ID.Hex(), status, workflow, lockHolder, revision)
+	if err == db.ErrRevisionConflict {
+		respondRevisionConflict(c, doc.ID.Hex())
+		return nil, false
+	}
+	if err == db.ErrDocumentLocked {
+		respondLocked(c, doc.ID.Hex())
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Erro ao atualizar fluxo de aprovação do documento %s: %v", doc.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar o fluxo de aprovação"})
+		return nil, false
+	}
+	syncSearchIndex(updated)
+
+	c.Header("ETag", updated.ETag())
+	return updated, true
+}
+
+// respondNotEditable informa que o documento precisa voltar a rascunho antes de ser editado
+func respondNotEditable(c *gin.Context, doc *models.Document) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":  "O documento só pode ser editado como rascunho. Crie um novo rascunho antes de editar",
+		"status": doc.Status.Normalize(),
+	})
+}
+
+// workflowResponse monta a representação do fluxo de aprovação de um documento
+func workflowResponse(doc *models.Document) gin.H {
+	decisions := doc.Workflow.Decisions
+	if decisions == nil {
+		decisions = []models.WorkflowDecision{}
+	}
+	reviewers := doc.Workflow.Reviewers
+	if reviewers == nil {
+		reviewers = []string{}
+	}
+
+	response := gin.H{
+		"document_id":         doc.ID.Hex(),
+		"status":              doc.Status.Normalize(),
+		"allowed_transitions": doc.Status.AllowedTransitions(),
+		"reviewers":           reviewers,
+		"round":               doc.Workflow.Round,
+		"submitted_by":        doc.Workflow.SubmittedBy,
+		"submitted_at":        doc.Workflow.SubmittedAt,
+		"published_at":        doc.Workflow.PublishedAt,
+		"published_version":   doc.Workflow.PublishedVersion,
+		"decisions":           decisions,
+		"etag":                doc.ETag(),
+	}
+	if doc.Status.Normalize() == models.StatusReview {
+		response["approved_by"] = doc.Workflow.ApprovalsInRound(doc.Workflow.Round)
+		response["pending_reviewers"] = doc.Workflow.PendingReviewers()
+	}
+	return response
+}
+
+// containsString verifica se o valor está na lista
+func containsString(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+	return false
+}
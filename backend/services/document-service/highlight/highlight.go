@@ -0,0 +1,178 @@
+// Package highlight extrai trechos do conteúdo ao redor dos termos buscados, destacando
+// as ocorrências com <mark>. O restante do texto é escapado, então os trechos podem ser
+// exibidos diretamente como HTML.
+package highlight
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxExcerpts é o número máximo de trechos retornados por documento
+	MaxExcerpts = 3
+	// excerptContext é a quantidade de caracteres mantida antes e depois de cada ocorrência
+	excerptContext = 80
+	// minTermLength ignora termos curtos demais para destacar
+	minTermLength = 2
+	// maxScanLength limita, em bytes, o início do conteúdo em que as ocorrências são procuradas,
+	// para que documentos grandes não pesem em cada página de resultados
+	maxScanLength = 64 << 10
+)
+
+// match é a posição, em runas, de uma ocorrência no conteúdo
+type match struct {
+	start int
+	end   int
+}
+
+// Excerpts retorna até MaxExcerpts trechos do conteúdo com as ocorrências dos termos
+// destacadas, na ordem em que aparecem. A comparação ignora maiúsculas e minúsculas, e
+// termos com menos de dois caracteres são ignorados. Só os primeiros maxScanLength bytes
+// do conteúdo são examinados.
+func Excerpts(content string, terms []string) []string {
+	if content == "" || len(terms) == 0 {
+		return nil
+	}
+	if len(content) > maxScanLength {
+		// Cortar no início de um caractere para não partir uma sequência UTF-8
+		cut := maxScanLength
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut]
+	}
+
+	text := []rune(content)
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var patterns [][]rune
+	for _, term := range terms {
+		if pattern := []rune(strings.ToLower(term)); len(pattern) >= minTermLength {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	matches := findMatches(lower, patterns)
+	var excerpts []string
+	for i := 0; i < len(matches) && len(excerpts) < MaxExcerpts; {
+		start := wordStart(text, matches[i].start-excerptContext, matches[i].start)
+		end := matches[i].end + excerptContext
+
+		// As ocorrências seguintes que cabem no mesmo trecho são destacadas nele
+		j := i + 1
+		for j < len(matches) && matches[j].end <= end {
+			j++
+		}
+		end = wordEnd(text, end, matches[j-1].end)
+
+		excerpts = append(excerpts, render(text, start, end, matches[i:j]))
+		i = j
+	}
+	return excerpts
+}
+
+// findMatches localiza as ocorrências de todos os termos, sem sobreposição
+func findMatches(lower []rune, patterns [][]rune) []match {
+	var found []match
+	for _, pattern := range patterns {
+		for i := 0; i+len(pattern) <= len(lower); i++ {
+			if hasPrefixAt(lower, pattern, i) {
+				found = append(found, match{start: i, end: i + len(pattern)})
+				i += len(pattern) - 1
+			}
+		}
+	}
+
+	sort.Slice(found, func(a, b int) bool {
+		if found[a].start != found[b].start {
+			return found[a].start < found[b].start
+		}
+		return found[a].end > found[b].end
+	})
+
+	merged := found[:0]
+	for _, m := range found {
+		if len(merged) > 0 && m.start < merged[len(merged)-1].end {
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+// hasPrefixAt verifica se pattern ocorre em text na posição informada
+func hasPrefixAt(text []rune, pattern []rune, at int) bool {
+	for k, r := range pattern {
+		if text[at+k] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// wordStart ajusta o início do trecho para não cortar uma palavra ao meio
+func wordStart(text []rune, start int, limit int) int {
+	if start <= 0 {
+		return 0
+	}
+	for i := start; i < limit; i++ {
+		if unicode.IsSpace(text[i]) {
+			return i + 1
+		}
+	}
+	return start
+}
+
+// wordEnd ajusta o fim do trecho para não cortar uma palavra ao meio
+func wordEnd(text []rune, end int, limit int) int {
+	if end >= len(text) {
+		return len(text)
+	}
+	for i := end; i > limit; i-- {
+		if unicode.IsSpace(text[i]) {
+			return i
+		}
+	}
+	return end
+}
+
+// render monta o trecho em HTML, com quebras de linha convertidas em espaços
+func render(text []rune, start int, end int, matches []match) string {
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	pos := start
+	for _, m := range matches {
+		b.WriteString(escape(text[pos:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(escape(text[m.start:m.end]))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(escape(text[pos:end]))
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// escape converte um pedaço do texto em HTML seguro, em uma única linha
+func escape(text []rune) string {
+	flat := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, string(text))
+	return html.EscapeString(flat)
+}
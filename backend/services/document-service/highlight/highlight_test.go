@@ -0,0 +1,77 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExcerpts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		want    []string
+	}{
+		{"sem conteúdo", "", []string{"plano"}, nil},
+		{"sem termos", "plano anual", nil, nil},
+		{"sem ocorrências", "plano anual", []string{"meta"}, nil},
+		{"termos curtos ignorados", "a casa", []string{"a"}, nil},
+		{"maiúsculas e minúsculas", "O Plano anual", []string{"plano"}, []string{"O <mark>Plano</mark> anual"}},
+		{"acentos", "Plano de AÇÃO", []string{"ação"}, []string{"Plano de <mark>AÇÃO</mark>"}},
+		{"HTML escapado", "<b>plano</b> & cia", []string{"plano"}, []string{"&lt;b&gt;<mark>plano</mark>&lt;/b&gt; &amp; cia"}},
+		{"termo dentro de outro", "planejamento", []string{"plan", "planejamento"}, []string{"<mark>planejamento</mark>"}},
+		{"ocorrências próximas no mesmo trecho", "plano e meta", []string{"meta", "plano"}, []string{"<mark>plano</mark> e <mark>meta</mark>"}},
+		{"quebras de linha viram espaços", "um\nplano\tdois", []string{"plano"}, []string{"um <mark>plano</mark> dois"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpts(tt.content, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Excerpts(%q, %q) = %q, esperado %q", tt.content, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerptsContext(t *testing.T) {
+	filler := strings.Repeat("palavra ", 30)
+	got := Excerpts(filler+"alvo "+filler, []string{"alvo"})
+	if len(got) != 1 {
+		t.Fatalf("Excerpts() retornou %d trechos, esperado 1", len(got))
+	}
+
+	excerpt := got[0]
+	if !strings.HasPrefix(excerpt, "…palavra ") || !strings.HasSuffix(excerpt, " palavra…") {
+		t.Errorf("trecho %q deveria começar e terminar em palavras inteiras com reticências", excerpt)
+	}
+	if !strings.Contains(excerpt, "<mark>alvo</mark>") {
+		t.Errorf("trecho %q sem o termo destacado", excerpt)
+	}
+	if length := utf8.RuneCountInString(excerpt); length > 2*excerptContext+len("alvo")+len("<mark></mark>")+2 {
+		t.Errorf("trecho com %d caracteres excede o contexto", length)
+	}
+}
+
+func TestExcerptsLimits(t *testing.T) {
+	separator := strings.Repeat("x ", excerptContext*2)
+	repeated := strings.Repeat("alvo "+separator, MaxExcerpts+2)
+	if got := Excerpts(repeated, []string{"alvo"}); len(got) != MaxExcerpts {
+		t.Errorf("Excerpts() retornou %d trechos, esperado %d", len(got), MaxExcerpts)
+	}
+
+	// Ocorrências depois de maxScanLength bytes não são procuradas
+	late := strings.Repeat("x", maxScanLength) + " alvo"
+	if got := Excerpts(late, []string{"alvo"}); got != nil {
+		t.Errorf("Excerpts() = %q, esperado nenhum trecho além do limite", got)
+	}
+
+	// O corte no limite não parte um caractere de vários bytes: o "ç" começa no último
+	// byte examinado e não pode virar um caractere inválido no fim do trecho
+	multibyte := strings.Repeat("x", maxScanLength-10) + " alvo xxxção"
+	got := Excerpts(multibyte, []string{"alvo"})
+	if len(got) != 1 || strings.ContainsRune(got[0], utf8.RuneError) || !strings.HasSuffix(got[0], " xxx") {
+		t.Errorf("Excerpts() = %q, esperado um trecho terminado antes do caractere cortado", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"gestor-e-docs/document-service/db"
 	"gestor-e-docs/document-service/handlers"
 	"gestor-e-docs/document-service/metrics"
+	"gestor-e-docs/document-service/reconcile"
+	"gestor-e-docs/document-service/search"
 	"gestor-e-docs/document-service/storage"
 	"log"
 	"net/http"
@@ -28,15 +30,38 @@ func main() {
 	}
 	defer db.DisconnectDatabase()
 
-	// Inicializar cliente MinIO
-	_, err = storage.GetMinioClient()
+	// Inicializar o armazenamento de objetos (MinIO, diretório local ou memória)
+	store, err := storage.GetBlobStore()
 	if err != nil {
-		log.Fatalf("Falha ao inicializar cliente MinIO: %v", err)
+		log.Fatalf("Falha ao inicializar o armazenamento: %v", err)
 	}
 
+	// Corrigir documentos gravados pelo modelo antigo de versões antes de atender requisições
+	if _, err := reconcile.MigrateVersionStorage(context.Background(), store); err != nil {
+		log.Printf("Aviso: migração das versões não concluída: %v", err)
+	}
+
+	// Subcomando de manutenção: go run . reconcile [-apply] [-delete-orphans] [-repair] [-grace 24h]
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := runReconcileCommand(os.Args[2:])
+		db.DisconnectDatabase()
+		os.Exit(code)
+	}
+
+	// Inicializar o mecanismo de busca (MongoDB ou índice do Bleve em disco)
+	index, err := search.GetSearchIndex()
+	if err != nil {
+		log.Fatalf("Falha ao inicializar o mecanismo de busca: %v", err)
+	}
+	defer index.Close()
+
 	// Configurar o router
 	r := gin.Default()
 
+	// Limitar a memória usada por formulários multipart; arquivos maiores são
+	// gravados em disco temporário e enviados ao MinIO em streaming
+	r.MaxMultipartMemory = 8 << 20
+
 	// Adicionar middleware de métricas do Prometheus
 	r.Use(metrics.PrometheusMiddleware())
 
@@ -51,10 +76,10 @@ func main() {
 		"http://127.0.0.1",
 		"https://127.0.0.1",
 	}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Accept"}
+	config.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Accept", "If-Match", "If-None-Match", "If-Modified-Since", "Range", "If-Range", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Defer-Length", "X-Share-Password"}
 	config.AllowCredentials = true  // Permitir envio de cookies
-	config.ExposeHeaders = []string{"Content-Length", "Set-Cookie"}
+	config.ExposeHeaders = []string{"Content-Length", "Set-Cookie", "ETag", "Last-Modified", "Accept-Ranges", "Content-Range", "Content-Disposition", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires", "X-Document-Id", "Retry-After"}
 	config.MaxAge = 12 * time.Hour
 	r.Use(cors.New(config))
 
@@ -74,6 +99,13 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"message": "Document Service API"})
 	})
 
+	// Acesso a documentos por link público (autorizado pelo próprio token)
+	api.GET("/shared/:token", handlers.GetSharedContent)
+	api.POST("/shared/:token", handlers.GetSharedContent) // Senha no corpo do formulário
+
+	// Descoberta das capacidades do protocolo tus de envio retomável
+	api.OPTIONS("/uploads", handlers.TusOptions)
+
 	// Rotas protegidas
 	protected := api.Group("/")
 	protected.Use(handlers.AuthMiddleware())
@@ -84,10 +116,96 @@ func main() {
 		protected.PUT("/:id", handlers.UpdateDocument)
 		protected.DELETE("/:id", handlers.DeleteDocument)
 		protected.GET("/list", handlers.ListDocuments)
+		protected.GET("/shared", handlers.ListSharedDocuments)
 		protected.GET("/:id/download", handlers.DownloadDocument)
 		protected.GET("/:id/download/file", handlers.DownloadDocumentFile)
+
+		// Histórico de versões
+		protected.GET("/:id/versions", handlers.ListVersions)
+		protected.GET("/:id/versions/:version", handlers.GetVersion)
+		protected.POST("/:id/versions/:version/restore", handlers.RestoreVersion)
+		protected.GET("/:id/diff", handlers.GetDocumentDiff)
+
+		// Compartilhamento e permissões
+		protected.GET("/:id/permissions", handlers.GetPermissions)
+		protected.POST("/:id/permissions", handlers.GrantPermission)
+		protected.GET("/:id/permissions/audit", handlers.ListPermissionAudit)
+		protected.PUT("/:id/permissions/visibility", handlers.SetVisibility)
+		protected.POST("/:id/permissions/owner", handlers.TransferOwnership)
+		protected.PUT("/:id/permissions/:userId", handlers.UpdatePermission)
+		protected.DELETE("/:id/permissions/:userId", handlers.RevokePermission)
+
+		// Links públicos
+		protected.GET("/:id/share-links", handlers.ListShareLinks)
+		protected.POST("/:id/share-links", handlers.CreateShareLink)
+		protected.DELETE("/:id/share-links/:linkId", handlers.RevokeShareLink)
+
+		// Fluxo de aprovação
+		protected.GET("/:id/workflow", handlers.GetWorkflow)
+		protected.PUT("/:id/workflow/reviewers", handlers.SetReviewers)
+		protected.POST("/:id/submit", handlers.SubmitForReview)
+		protected.POST("/:id/approve", handlers.ApproveDocument)
+		protected.POST("/:id/reject", handlers.RejectDocument)
+		protected.POST("/:id/archive", handlers.ArchiveDocument)
+		protected.POST("/:id/draft", handlers.CreateDraft)
+
+		// Reserva de edição exclusiva
+		protected.GET("/:id/lock", handlers.GetLock)
+		protected.POST("/:id/lock", handlers.AcquireLock)
+		protected.POST("/:id/lock/renew", handlers.RenewLock)
+		protected.DELETE("/:id/lock", handlers.ReleaseLock)
+
+		// Pastas
+		protected.POST("/:id/move", handlers.MoveDocument)
+		protected.POST("/folders", handlers.CreateFolder)
+		protected.GET("/folders", handlers.ListFolders)
+		protected.GET("/folders/:folderId", handlers.GetFolder)
+		protected.PUT("/folders/:folderId", handlers.RenameFolder)
+		protected.DELETE("/folders/:folderId", handlers.DeleteFolder)
+		protected.POST("/folders/:folderId/move", handlers.MoveFolder)
+		protected.GET("/folders/:folderId/contents", handlers.GetFolderContents)
+		protected.GET("/folders/:folderId/permissions", handlers.GetFolderPermissions)
+		protected.POST("/folders/:folderId/permissions", handlers.GrantFolderPermission)
+		protected.DELETE("/folders/:folderId/permissions/:userId", handlers.RevokeFolderPermission)
+
+		// Lixeira
+		protected.GET("/trash", handlers.ListTrash)
+		protected.POST("/trash/:id/restore", handlers.RestoreFromTrash)
+		protected.DELETE("/trash/:id", handlers.PurgeFromTrash)
+
+		// Envio retomável (protocolo tus)
+		uploads := protected.Group("/uploads")
+		uploads.Use(handlers.TusResumable())
+		uploads.POST("", handlers.CreateUpload)
+		uploads.HEAD("/:uploadId", handlers.GetUploadOffset)
+		uploads.PATCH("/:uploadId", handlers.PatchUpload)
+		uploads.DELETE("/:uploadId", handlers.DeleteUpload)
+
+		// Envio direto ao MinIO por formulário pré-assinado
+		protected.POST("/presigned-uploads", handlers.CreatePresignedUpload)
+		protected.POST("/presigned-uploads/:uploadId/complete", handlers.CompleteUpload)
+		protected.DELETE("/presigned-uploads/:uploadId", handlers.DeletePresignedUpload)
+
+		// Administração do serviço
+		admin := protected.Group("/admin")
+		admin.Use(handlers.RequireServiceAdmin())
+		admin.POST("/integrity/scrub", handlers.StartScrub)
+		admin.GET("/integrity/scrub", handlers.GetScrubReport)
+		admin.POST("/reconcile", handlers.Reconcile)
+		admin.POST("/search/reindex", handlers.StartReindex)
+		admin.GET("/search/reindex", handlers.GetReindexReport)
 	}
 
+	// Iniciar as rotinas de manutenção em segundo plano
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	handlers.StartTrashPurger(jobsCtx)
+	handlers.StartLockReaper(jobsCtx)
+	handlers.StartUploadReaper(jobsCtx)
+	handlers.StartIntegrityScrubber(jobsCtx)
+	handlers.StartSearchIndexBackfill(jobsCtx)
+	handlers.StartSearchIndexRefresh(jobsCtx)
+
 	// Determinar a porta do servidor
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -116,6 +234,7 @@ func main() {
 	// Bloquear até receber um sinal
 	<-quit
 	log.Println("Desligando o servidor...")
+	stopJobs()
 
 	// Contexto com timeout para shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -55,6 +55,24 @@ var (
 		},
 		[]string{"operation", "success"},
 	)
+
+	// IntegrityChecks conta as verificações de checksum dos objetos armazenados,
+	// na leitura ("read") ou na varredura periódica ("scrub"), por resultado
+	IntegrityChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "document_service_integrity_checks_total",
+			Help: "Total de verificações de integridade dos objetos armazenados por origem e resultado.",
+		},
+		[]string{"source", "result"},
+	)
+
+	// ScrubLastCompleted registra quando a última varredura de integridade terminou
+	ScrubLastCompleted = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "document_service_integrity_scrub_last_completed_timestamp_seconds",
+			Help: "Momento em que a última varredura de integridade terminou (Unix).",
+		},
+	)
 )
 
 // Init registra as métricas no Prometheus
@@ -65,6 +83,8 @@ func Init() {
 	prometheus.MustRegister(DocumentOperations)
 	prometheus.MustRegister(MinIOOperations)
 	prometheus.MustRegister(MongoDBOperations)
+	prometheus.MustRegister(IntegrityChecks)
+	prometheus.MustRegister(ScrubLastCompleted)
 }
 
 // PrometheusHandler retorna um handler HTTP para o Prometheus
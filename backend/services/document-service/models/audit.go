@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditAction identifica o tipo de alteração registrada na trilha de auditoria
+type AuditAction string
+
+const (
+	AuditPermissionGranted AuditAction = "permission_granted"
+	AuditPermissionChanged AuditAction = "permission_changed"
+	AuditPermissionRevoked AuditAction = "permission_revoked"
+	AuditVisibilityChanged AuditAction = "visibility_changed"
+	AuditOwnerTransferred  AuditAction = "ownership_transferred"
+	AuditFolderMoved       AuditAction = "folder_moved"
+	AuditDocumentMoved     AuditAction = "document_moved"
+	AuditShareLinkCreated  AuditAction = "share_link_created"
+	AuditShareLinkRevoked  AuditAction = "share_link_revoked"
+	AuditLockBroken        AuditAction = "lock_broken"
+)
+
+// AuditEntry registra uma alteração administrativa em um documento
+type AuditEntry struct {
+	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	DocumentID   string                 `bson:"document_id,omitempty" json:"document_id,omitempty"`
+	FolderID     string                 `bson:"folder_id,omitempty" json:"folder_id,omitempty"`
+	ActorID      string                 `bson:"actor_id" json:"actor_id"`
+	Action       AuditAction            `bson:"action" json:"action"`
+	TargetUserID string                 `bson:"target_user_id,omitempty" json:"target_user_id,omitempty"`
+	OldValue     string                 `bson:"old_value,omitempty" json:"old_value,omitempty"`
+	NewValue     string                 `bson:"new_value,omitempty" json:"new_value,omitempty"`
+	Details      map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	IPAddress    string                 `bson:"ip_address" json:"ip_address"`
+	UserAgent    string                 `bson:"user_agent" json:"user_agent"`
+	Timestamp    time.Time              `bson:"timestamp" json:"timestamp"`
+}
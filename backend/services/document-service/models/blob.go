@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Blob representa um conteúdo armazenado uma única vez no MinIO, identificado pelo seu
+// SHA-256. Cada versão de documento que aponta para o blob conta uma referência; o objeto
+// só é removido quando nenhuma versão o referencia mais.
+type Blob struct {
+	Hash        string     `bson:"_id" json:"hash"`
+	ObjectName  string     `bson:"object_name" json:"object_name"`
+	Size        int64      `bson:"size" json:"size"`
+	ContentType string     `bson:"content_type" json:"content_type"`
+	RefCount    int64      `bson:"ref_count" json:"ref_count"`
+	State       BlobState  `bson:"state,omitempty" json:"state,omitempty"` // Vazio em registros anteriores ao estado, que já estão gravados
+	DeletingAt  *time.Time `bson:"deleting_at,omitempty" json:"deleting_at,omitempty"`
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// BlobState indica se o objeto do blob já foi confirmado no armazenamento
+type BlobState string
+
+const (
+	// BlobPending indica que o objeto ainda está sendo gravado; quem registra uma referência
+	// a um blob pendente grava o objeto também, o que é inofensivo pelo nome ser o hash
+	BlobPending BlobState = "pending"
+	// BlobStored indica que o objeto foi gravado e pode ser referenciado sem nova gravação
+	BlobStored BlobState = "stored"
+	// BlobDeleting marca o registro enquanto o objeto sem referências é removido; novas
+	// referências esperam a remoção terminar antes de gravar o conteúdo de novo
+	BlobDeleting BlobState = "deleting"
+)
+
+// IsStored indica se o objeto do blob já está gravado
+func (b *Blob) IsStored() bool {
+	return b.State == BlobStored || b.State == ""
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,6 +12,7 @@ type Document struct {
 	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
 	Title           string               `bson:"title" json:"title"`
 	Content         string               `bson:"content" json:"content"`
+	ContentOmitted  bool                 `bson:"-" json:"content_omitted,omitempty"` // Conteúdo binário ou grande demais, disponível apenas em /download
 	AuthorID        string               `bson:"author_id" json:"author_id"`
 	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
 	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
@@ -18,9 +20,17 @@ type Document struct {
 	Categories      []string             `bson:"categories" json:"categories"`
 	Status          DocumentStatus       `bson:"status" json:"status"`
 	VersionHistory  []Version            `bson:"version_history" json:"version_history"`
+	VersionCount    int                  `bson:"version_count" json:"version_count"` // Tamanho de version_history, mantido para ordenar a listagem
 	StoragePath     string               `bson:"storage_path" json:"storage_path"`
+	ContentHash     string               `bson:"content_hash,omitempty" json:"content_hash,omitempty"` // SHA-256 do conteúdo atual, chave do blob no armazenamento
 	Permissions     DocumentPermissions  `bson:"permissions" json:"permissions"`
 	Metadata        DocumentMetadata     `bson:"metadata" json:"metadata"`
+	Revision        int64                `bson:"revision" json:"revision"` // Incrementado a cada gravação, usado no controle de concorrência
+	FolderID        string               `bson:"folder_id" json:"folder_id"` // Vazio para documentos na raiz
+	DeletedAt       *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Preenchido enquanto o documento está na lixeira
+	DeletedBy       string               `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	Workflow        DocumentWorkflow     `bson:"workflow" json:"workflow"` // Revisores designados e decisões do fluxo de aprovação
+	Lock            *EditLock            `bson:"lock,omitempty" json:"lock,omitempty"` // Reserva de edição exclusiva, liberada ao expirar
 }
 
 // Version representa uma versão específica do documento
@@ -30,6 +40,17 @@ type Version struct {
 	AuthorID      string    `bson:"author_id" json:"author_id"`
 	Description   string    `bson:"description" json:"description"`
 	StoragePath   string    `bson:"storage_path" json:"storage_path"`
+	ContentHash   string    `bson:"content_hash,omitempty" json:"content_hash,omitempty"` // Blob referenciado; vazio em versões anteriores ao armazenamento por conteúdo
+}
+
+// VersionContent descreve o conteúdo gravado para uma nova versão. Ao registrá-la, o documento
+// passa a apontar para o mesmo objeto e o texto indexado substitui Document.Content.
+type VersionContent struct {
+	StoragePath string // Objeto do MinIO com o conteúdo da versão
+	ContentHash string // Blob referenciado pela versão
+	Text        string // Texto mantido no MongoDB para a busca; vazio para arquivos binários
+	Size        int64
+	ContentType string
 }
 
 // DocumentStatus representa o estado atual do documento
@@ -51,10 +72,104 @@ type DocumentPermissions struct {
 	AdminAccess   []string `bson:"admin_access" json:"admin_access"` // IDs de usuários com acesso administrativo
 }
 
+// AccessLevel representa o nível de acesso concedido a um usuário em um documento
+type AccessLevel string
+
+const (
+	AccessNone  AccessLevel = ""
+	AccessRead  AccessLevel = "read"
+	AccessWrite AccessLevel = "write"
+	AccessAdmin AccessLevel = "admin"
+	AccessOwner AccessLevel = "owner"
+)
+
+// accessRank ordena os níveis de acesso do menor para o maior
+var accessRank = map[AccessLevel]int{
+	AccessNone:  0,
+	AccessRead:  1,
+	AccessWrite: 2,
+	AccessAdmin: 3,
+	AccessOwner: 4,
+}
+
+// Includes indica se o nível de acesso engloba o nível informado
+func (a AccessLevel) Includes(other AccessLevel) bool {
+	return accessRank[a] >= accessRank[other]
+}
+
+// Max retorna o maior entre os dois níveis de acesso
+func (a AccessLevel) Max(other AccessLevel) AccessLevel {
+	if accessRank[other] > accessRank[a] {
+		return other
+	}
+	return a
+}
+
+// IsGrantable indica se o nível pode ser concedido pela API de compartilhamento
+func (a AccessLevel) IsGrantable() bool {
+	return a == AccessRead || a == AccessWrite || a == AccessAdmin
+}
+
+// AccessOf retorna o nível de acesso explícito de um usuário
+func (p *DocumentPermissions) AccessOf(userID string) AccessLevel {
+	if p.OwnerID == userID {
+		return AccessOwner
+	}
+	if containsID(p.AdminAccess, userID) {
+		return AccessAdmin
+	}
+	if containsID(p.WriteAccess, userID) {
+		return AccessWrite
+	}
+	if containsID(p.ReadAccess, userID) {
+		return AccessRead
+	}
+	return AccessNone
+}
+
+// SetAccess define o nível de acesso de um usuário, removendo-o dos demais níveis.
+// AccessNone revoga qualquer acesso explícito.
+func (p *DocumentPermissions) SetAccess(userID string, level AccessLevel) {
+	p.ReadAccess = removeID(p.ReadAccess, userID)
+	p.WriteAccess = removeID(p.WriteAccess, userID)
+	p.AdminAccess = removeID(p.AdminAccess, userID)
+
+	switch level {
+	case AccessRead:
+		p.ReadAccess = append(p.ReadAccess, userID)
+	case AccessWrite:
+		p.WriteAccess = append(p.WriteAccess, userID)
+	case AccessAdmin:
+		p.AdminAccess = append(p.AdminAccess, userID)
+	}
+}
+
+// containsID verifica se o ID está na lista
+func containsID(ids []string, id string) bool {
+	for _, current := range ids {
+		if current == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeID retorna a lista sem o ID informado, nunca nil
+func removeID(ids []string, id string) []string {
+	result := make([]string, 0, len(ids))
+	for _, current := range ids {
+		if current != id {
+			result = append(result, current)
+		}
+	}
+	return result
+}
+
 // DocumentMetadata contém informações adicionais sobre o documento
 type DocumentMetadata struct {
 	FileSize          int64     `bson:"file_size" json:"file_size"`
 	OriginalExtension string    `bson:"original_extension" json:"original_extension"`
+	ContentType       string    `bson:"content_type" json:"content_type"` // Tipo MIME identificado pelo conteúdo no upload
 	LastViewedAt      time.Time `bson:"last_viewed_at" json:"last_viewed_at"`
 	ViewCount         int       `bson:"view_count" json:"view_count"`
 	IsTemplate        bool      `bson:"is_template" json:"is_template"`
@@ -82,6 +197,29 @@ type DocumentUpdate struct {
 	Description string         `json:"description"` // Descrição da alteração para histórico de versões
 }
 
+// PermissionGrant representa a concessão de acesso a um usuário, identificado pelo ID ou e-mail
+type PermissionGrant struct {
+	UserID string      `json:"user_id"`
+	Email  string      `json:"email"`
+	Access AccessLevel `json:"access" binding:"required"`
+}
+
+// PermissionChange representa a alteração do nível de acesso de um usuário
+type PermissionChange struct {
+	Access AccessLevel `json:"access" binding:"required"`
+}
+
+// VisibilityChange representa a alteração da visibilidade pública do documento
+type VisibilityChange struct {
+	IsPublic *bool `json:"is_public" binding:"required"`
+}
+
+// OwnershipTransfer representa a transferência de propriedade para outro usuário
+type OwnershipTransfer struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
 // DocumentListItem representa um item resumido na listagem de documentos
 type DocumentListItem struct {
 	ID           primitive.ObjectID `json:"id"`
@@ -94,6 +232,11 @@ type DocumentListItem struct {
 	Tags         []string           `json:"tags"`
 	Categories   []string           `json:"categories"`
 	VersionCount int                `json:"version_count"`
+	OwnerID      string             `json:"owner_id"`
+	IsPublic     bool               `json:"is_public"`
+	Access       AccessLevel        `json:"access,omitempty"`     // Nível de acesso de quem fez a consulta
+	Score        float64            `json:"score,omitempty"`      // Relevância na busca textual
+	Highlights   []string           `json:"highlights,omitempty"` // Trechos do conteúdo com os termos buscados em <mark>
 }
 
 // DocumentSearchQuery representa os parâmetros para busca de documentos
@@ -103,10 +246,102 @@ type DocumentSearchQuery struct {
 	Categories  []string `form:"categories"`
 	AuthorID    string   `form:"author_id"`
 	Status      string   `form:"status"`
-	SortBy      string   `form:"sort_by"`
-	SortOrder   string   `form:"sort_order"`
+	SortBy      string   `form:"sort_by"`    // Campos separados por vírgula; "-" antes do campo ordena de forma decrescente
+	SortOrder   string   `form:"sort_order"` // Sentido dos campos sem prefixo: asc (padrão) ou desc
 	DateFrom    string   `form:"date_from"`
 	DateTo      string   `form:"date_to"`
 	Offset      int      `form:"offset"`
 	Limit       int      `form:"limit"`
+	Cursor      string   `form:"cursor"` // Cursor de next_cursor ou prev_cursor; substitui o offset
+	Scope       string   `form:"scope"`  // owned, shared, public ou all
+	FolderID    string   `form:"folder_id"`
+	Recursive   bool     `form:"recursive"` // Inclui documentos das subpastas de FolderID
+	ViewerID    string   `form:"-"`         // Usuário que faz a consulta; definido pelo handler
+	FolderIDs   []string `form:"-"`         // Pastas em que buscar; definido pelo handler
+	ReadFolders []string `form:"-"`         // Pastas legíveis pelo usuário por herança; definido pelo handler
+}
+
+// NormalizeLimit aplica o limite padrão (10) e o máximo (100) de documentos por página
+func (q *DocumentSearchQuery) NormalizeLimit() {
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	if q.Limit > 100 {
+		q.Limit = 100
+	}
+}
+
+// ListItem resume o documento para a listagem, com o nível de acesso de viewerID quando informado
+func (d *Document) ListItem(viewerID string) DocumentListItem {
+	item := DocumentListItem{
+		ID:           d.ID,
+		Title:        d.Title,
+		AuthorID:     d.AuthorID,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
+		Status:       d.Status,
+		Tags:         d.Tags,
+		Categories:   d.Categories,
+		VersionCount: len(d.VersionHistory),
+		OwnerID:      d.Permissions.OwnerID,
+		IsPublic:     d.Permissions.IsPublic,
+	}
+	if viewerID != "" {
+		item.Access = d.Permissions.AccessOf(viewerID)
+		if item.Access == AccessNone && d.Permissions.IsPublic {
+			item.Access = AccessRead
+		}
+	}
+	return item
+}
+
+// Escopos de listagem de documentos em relação a quem faz a consulta
+const (
+	ScopeOwned  = "owned"
+	ScopeShared = "shared"
+	ScopePublic = "public"
+	ScopeAll    = "all"
+)
+
+// IsValidScope verifica se o escopo de listagem é suportado
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeOwned, ScopeShared, ScopePublic, ScopeAll:
+		return true
+	}
+	return false
+}
+
+// ActiveLock retorna a reserva de edição vigente, ou nil se não houver
+func (d *Document) ActiveLock(now time.Time) *EditLock {
+	if d.Lock.IsActive(now) {
+		return d.Lock
+	}
+	return nil
+}
+
+// FindVersion retorna a versão com o número informado, se existir no histórico
+func (d *Document) FindVersion(number int) (*Version, bool) {
+	for i := range d.VersionHistory {
+		if d.VersionHistory[i].VersionNumber == number {
+			return &d.VersionHistory[i], true
+		}
+	}
+	return nil, false
+}
+
+// CurrentVersion retorna o número da versão mais recente do documento
+func (d *Document) CurrentVersion() int {
+	current := 0
+	for _, v := range d.VersionHistory {
+		if v.VersionNumber > current {
+			current = v.VersionNumber
+		}
+	}
+	return current
+}
+
+// ETag retorna o identificador de concorrência do estado atual do documento
+func (d *Document) ETag() string {
+	return fmt.Sprintf("\"v%d-r%d\"", d.CurrentVersion(), d.Revision)
 }
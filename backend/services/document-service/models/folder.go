@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Folder representa uma pasta na hierarquia de documentos
+type Folder struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name        string              `bson:"name" json:"name"`
+	ParentID    string              `bson:"parent_id" json:"parent_id"` // Vazio para pastas na raiz
+	Ancestors   []string            `bson:"ancestors" json:"ancestors"` // IDs dos ancestrais, da raiz até o pai
+	Permissions DocumentPermissions `bson:"permissions" json:"permissions"`
+	Revision    int64               `bson:"revision" json:"revision"` // Incrementado a cada alteração das permissões
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// ETag identifica a revisão das permissões da pasta, usada nas gravações condicionais (If-Match)
+func (f *Folder) ETag() string {
+	return fmt.Sprintf("\"r%d\"", f.Revision)
+}
+
+// Path retorna os IDs da raiz até a própria pasta
+func (f *Folder) Path() []string {
+	path := make([]string, 0, len(f.Ancestors)+1)
+	path = append(path, f.Ancestors...)
+	return append(path, f.ID.Hex())
+}
+
+// FolderCreate representa os dados necessários para criar uma pasta
+type FolderCreate struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID string `json:"parent_id"`
+}
+
+// FolderUpdate representa os dados para renomear uma pasta
+type FolderUpdate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// FolderMove representa o destino de uma pasta ou documento movido; vazio move para a raiz
+type FolderMove struct {
+	ParentID string `json:"parent_id"`
+	FolderID string `json:"folder_id"`
+}
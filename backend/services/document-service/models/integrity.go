@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ScrubResult classifica a verificação de um objeto armazenado
+type ScrubResult string
+
+const (
+	ScrubOK           ScrubResult = "ok"           // Conteúdo confere com o checksum registrado
+	ScrubMissing      ScrubResult = "missing"      // Objeto referenciado não existe no armazenamento
+	ScrubCorrupted    ScrubResult = "corrupted"    // Conteúdo não confere com o checksum registrado
+	ScrubUnverifiable ScrubResult = "unverifiable" // Objeto existe, mas não há checksum registrado
+	ScrubError        ScrubResult = "error"        // Falha ao acessar o armazenamento
+)
+
+// ScrubIssue descreve uma referência a um objeto ausente, corrompido ou inacessível
+type ScrubIssue struct {
+	DocumentID    string      `json:"document_id"`
+	VersionNumber int         `json:"version_number"`
+	StoragePath   string      `json:"storage_path"`
+	Result        ScrubResult `json:"result"`
+	ExpectedHash  string      `json:"expected_hash,omitempty"`
+	ActualHash    string      `json:"actual_hash,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// ScrubReport resume uma varredura de integridade sobre as versões de todos os documentos
+type ScrubReport struct {
+	StartedAt        time.Time           `json:"started_at"`
+	FinishedAt       *time.Time          `json:"finished_at,omitempty"`
+	DocumentsScanned int                 `json:"documents_scanned"`
+	ObjectsChecked   int                 `json:"objects_checked"` // Objetos distintos; blobs compartilhados contam uma vez
+	Results          map[ScrubResult]int `json:"results"`
+	Issues           []ScrubIssue        `json:"issues"`
+	IssuesTruncated  bool                `json:"issues_truncated"`
+	Cancelled        bool                `json:"cancelled,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// EditLock representa a reserva temporária de edição exclusiva de um documento
+type EditLock struct {
+	HolderID   string    `bson:"holder_id" json:"holder_id"`
+	AcquiredAt time.Time `bson:"acquired_at" json:"acquired_at"`
+	RenewedAt  time.Time `bson:"renewed_at" json:"renewed_at"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// IsActive indica se a reserva ainda está válida. Reservas expiradas são consideradas liberadas.
+func (l *EditLock) IsActive(now time.Time) bool {
+	return l != nil && now.Before(l.ExpiresAt)
+}
+
+// BlocksUser indica se a reserva impede o usuário de editar o documento
+func (l *EditLock) BlocksUser(userID string, now time.Time) bool {
+	return l.IsActive(now) && l.HolderID != userID
+}
+
+// LockRequest representa o pedido de reserva ou renovação de edição
+type LockRequest struct {
+	DurationMinutes int `json:"duration_minutes"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// ReconcileAction descreve o que a reconciliação fez (ou faria, em simulação) com uma divergência
+type ReconcileAction string
+
+const (
+	ReconcileNone         ReconcileAction = "none"         // Apenas relatado
+	ReconcileWouldDelete  ReconcileAction = "would_delete" // Simulação: seria removido
+	ReconcileDeleted      ReconcileAction = "deleted"      // Objeto ou registro removido
+	ReconcileWouldRepair  ReconcileAction = "would_repair" // Simulação: seria corrigido
+	ReconcileRepaired     ReconcileAction = "repaired"     // Referência ou contagem corrigida
+	ReconcileUnrepairable ReconcileAction = "unrepairable" // Não há como corrigir automaticamente
+	ReconcileChanged      ReconcileAction = "changed"      // Alterado por outra operação durante a reconciliação
+	ReconcileFailed       ReconcileAction = "failed"       // A correção falhou; ver o campo error
+)
+
+// OrphanObject é um objeto no armazenamento que nenhum documento, blob ou envio referencia
+type OrphanObject struct {
+	ObjectName   string          `json:"object_name"`
+	Size         int64           `json:"size"`
+	LastModified time.Time       `json:"last_modified"`
+	Action       ReconcileAction `json:"action"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// DanglingReference é um caminho registrado em um documento que não existe no armazenamento.
+// VersionNumber 0 indica o ponteiro atual do documento (storage_path).
+type DanglingReference struct {
+	DocumentID    string          `json:"document_id"`
+	VersionNumber int             `json:"version_number"`
+	StoragePath   string          `json:"storage_path"`
+	ContentHash   string          `json:"content_hash,omitempty"`
+	RepairedPath  string          `json:"repaired_path,omitempty"`
+	Action        ReconcileAction `json:"action"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// BlobRefMismatch é um blob cuja contagem de referências não corresponde às versões que o usam
+type BlobRefMismatch struct {
+	Hash         string          `json:"hash"`
+	RecordedRefs int64           `json:"recorded_refs"` // -1 quando não há registro do blob
+	ActualRefs   int64           `json:"actual_refs"`
+	Action       ReconcileAction `json:"action"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// ReconcileReport resume uma reconciliação entre o MongoDB e o MinIO
+type ReconcileReport struct {
+	DryRun             bool                `json:"dry_run"`
+	StartedAt          time.Time           `json:"started_at"`
+	FinishedAt         time.Time           `json:"finished_at"`
+	DocumentsScanned   int                 `json:"documents_scanned"`
+	ObjectsScanned     int                 `json:"objects_scanned"`
+	OrphanCount        int                 `json:"orphan_count"`
+	OrphanBytes        int64               `json:"orphan_bytes"`
+	Orphans            []OrphanObject      `json:"orphans"`
+	DanglingCount      int                 `json:"dangling_count"`
+	DanglingReferences []DanglingReference `json:"dangling_references"`
+	BlobMismatches     []BlobRefMismatch   `json:"blob_mismatches"`
+	Truncated          bool                `json:"truncated"` // Listas limitadas; as contagens seguem completas
+}
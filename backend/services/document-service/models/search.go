@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DocumentPage é uma página da listagem de documentos. NextCursor e PrevCursor ficam
+// vazios quando não há página seguinte ou anterior, e na ordenação por relevância.
+type DocumentPage struct {
+	Documents  []DocumentListItem
+	Total      int64
+	NextCursor string
+	PrevCursor string
+}
+
+// FacetCount é a quantidade de documentos que compartilham um valor de faceta
+type FacetCount struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// SearchFacets agrupa as contagens por faceta dos documentos que atendem a uma busca.
+// CreatedMonth usa o formato AAAA-MM, em UTC.
+type SearchFacets struct {
+	Tags         []FacetCount `bson:"tags" json:"tags"`
+	Categories   []FacetCount `bson:"categories" json:"categories"`
+	Status       []FacetCount `bson:"status" json:"status"`
+	Authors      []FacetCount `bson:"author" json:"author"`
+	CreatedMonth []FacetCount `bson:"created_month" json:"created_month"`
+}
+
+// Campos aceitos na ordenação da listagem de documentos
+const (
+	SortTitle        = "title"
+	SortCreatedAt    = "created_at"
+	SortUpdatedAt    = "updated_at"
+	SortViewCount    = "view_count"
+	SortVersionCount = "version_count"
+	SortRelevance    = "relevance" // Relevância da busca textual, sempre decrescente
+)
+
+// maxSortKeys limita os campos de uma ordenação
+const maxSortKeys = 3
+
+// ErrInvalidSort indica uma ordenação fora do vocabulário aceito
+var ErrInvalidSort = errors.New("ordenação inválida")
+
+// SortKey é um campo da ordenação e o seu sentido
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// IndexedSorts são as ordenações por mais de um campo aceitas na listagem. Cada uma tem um
+// índice no MongoDB e vale também com todos os sentidos invertidos, que percorrem o mesmo
+// índice de trás para frente. A relevância dispensa índice: a busca textual ordena em memória.
+var IndexedSorts = [][]SortKey{
+	{{Field: SortTitle}, {Field: SortUpdatedAt, Descending: true}},
+	{{Field: SortViewCount, Descending: true}, {Field: SortUpdatedAt, Descending: true}},
+	{{Field: SortVersionCount, Descending: true}, {Field: SortUpdatedAt, Descending: true}},
+	{{Field: SortViewCount, Descending: true}, {Field: SortVersionCount, Descending: true}, {Field: SortUpdatedAt, Descending: true}},
+}
+
+// ParseSort interpreta sort_by e sort_order. sort_by lista os campos separados por vírgula; o
+// prefixo "-" ordena o campo de forma decrescente e "+", crescente. Os demais seguem sort_order.
+// Sem campos, retorna nil para que a listagem use a ordenação padrão.
+func ParseSort(sortBy string, sortOrder string) ([]SortKey, error) {
+	if strings.TrimSpace(sortBy) == "" {
+		return nil, nil
+	}
+
+	descending := false
+	switch sortOrder {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return nil, fmt.Errorf("%w: sort_order deve ser asc ou desc", ErrInvalidSort)
+	}
+
+	fields := strings.Split(sortBy, ",")
+	if len(fields) > maxSortKeys {
+		return nil, fmt.Errorf("%w: no máximo %d campos", ErrInvalidSort, maxSortKeys)
+	}
+
+	keys := make([]SortKey, 0, len(fields))
+	seen := map[string]bool{}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		key := SortKey{Field: field, Descending: descending}
+		if rest, found := strings.CutPrefix(field, "-"); found {
+			key = SortKey{Field: rest, Descending: true}
+		} else if rest, found := strings.CutPrefix(field, "+"); found {
+			key = SortKey{Field: rest, Descending: false}
+		}
+
+		switch key.Field {
+		case SortTitle, SortCreatedAt, SortUpdatedAt, SortViewCount, SortVersionCount:
+		case SortRelevance:
+			key.Descending = true
+		default:
+			return nil, fmt.Errorf("%w: campo %q; use title, created_at, updated_at, view_count, version_count ou relevance", ErrInvalidSort, field)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("%w: campo %q repetido", ErrInvalidSort, key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	if len(keys) > 1 && !seen[SortRelevance] && !indexedSort(keys) {
+		accepted := make([]string, len(IndexedSorts))
+		for i, combination := range IndexedSorts {
+			accepted[i] = FormatSort(combination)
+		}
+		return nil, fmt.Errorf("%w: combinação %q não é aceita; use um campo só ou uma de %s (ou com todos os sentidos invertidos)",
+			ErrInvalidSort, FormatSort(keys), strings.Join(accepted, "; "))
+	}
+	return keys, nil
+}
+
+// indexedSort indica se a ordenação é uma de IndexedSorts, no sentido original ou invertido
+func indexedSort(keys []SortKey) bool {
+	for _, combination := range IndexedSorts {
+		if len(combination) != len(keys) {
+			continue
+		}
+		same, inverted := true, true
+		for i, key := range combination {
+			same = same && keys[i] == key
+			inverted = inverted && keys[i].Field == key.Field && keys[i].Descending != key.Descending
+		}
+		if same || inverted {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterKey lista os critérios que definem o conjunto de documentos da consulta, normalizados,
+// para amarrar o cursor de paginação à consulta que o gerou
+func (q *DocumentSearchQuery) FilterKey() []string {
+	normalized := func(values []string) string {
+		sorted := make([]string, 0, len(values))
+		for _, value := range values {
+			sorted = append(sorted, strings.TrimSpace(value))
+		}
+		sort.Strings(sorted)
+		return strings.Join(sorted, ",")
+	}
+	return []string{
+		strings.TrimSpace(q.Query),
+		normalized(q.Tags),
+		normalized(q.Categories),
+		q.AuthorID,
+		q.Status,
+		q.DateFrom,
+		q.DateTo,
+		q.Scope,
+		q.FolderID,
+		strconv.FormatBool(q.Recursive),
+		q.ViewerID,
+	}
+}
+
+// FormatSort monta a forma canônica da ordenação, como em sort_by
+func FormatSort(keys []SortKey) string {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = key.Field
+		if key.Descending {
+			fields[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// ReindexReport descreve uma reconstrução do índice de busca
+type ReindexReport struct {
+	Engine     string     `json:"engine"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Indexed    int        `json:"indexed"`
+	Error      string     `json:"error,omitempty"`
+}
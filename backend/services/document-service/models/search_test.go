@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		sortBy    string
+		sortOrder string
+		want      []SortKey
+	}{
+		{"sem campos", "", "desc", nil},
+		{"um campo no sentido de sort_order", "title", "desc", []SortKey{{Field: SortTitle, Descending: true}}},
+		{"prefixo prevalece sobre sort_order", "+title", "desc", []SortKey{{Field: SortTitle}}},
+		{"relevância sempre decrescente", "relevance", "asc", []SortKey{{Field: SortRelevance, Descending: true}}},
+		{"combinação indexada", "title,-updated_at", "", []SortKey{{Field: SortTitle}, {Field: SortUpdatedAt, Descending: true}}},
+		{"combinação indexada invertida", "-title,updated_at", "", []SortKey{{Field: SortTitle, Descending: true}, {Field: SortUpdatedAt}}},
+		{"três campos indexados", "-view_count,-version_count,-updated_at", "", []SortKey{
+			{Field: SortViewCount, Descending: true},
+			{Field: SortVersionCount, Descending: true},
+			{Field: SortUpdatedAt, Descending: true},
+		}},
+		{"relevância dispensa índice", "relevance,created_at", "", []SortKey{{Field: SortRelevance, Descending: true}, {Field: SortCreatedAt}}},
+		{"espaços ao redor dos campos", " title , -updated_at ", "", []SortKey{{Field: SortTitle}, {Field: SortUpdatedAt, Descending: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.sortBy, tt.sortOrder)
+			if err != nil {
+				t.Fatalf("ParseSort(%q, %q) retornou erro: %v", tt.sortBy, tt.sortOrder, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort(%q, %q) = %v, esperado %v", tt.sortBy, tt.sortOrder, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		sortBy    string
+		sortOrder string
+	}{
+		{"campo desconhecido", "author_id", ""},
+		{"campo de operador do MongoDB", "$where", ""},
+		{"sort_order inválido", "title", "up"},
+		{"campo repetido", "title,-title", ""},
+		{"campos demais", "title,created_at,updated_at,view_count", ""},
+		{"combinação sem índice", "created_at,title", ""},
+		{"sentidos parcialmente invertidos", "title,updated_at", ""},
+		{"campo vazio", "title,", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseSort(tt.sortBy, tt.sortOrder)
+			if !errors.Is(err, ErrInvalidSort) {
+				t.Errorf("ParseSort(%q, %q) = %v, %v; esperado ErrInvalidSort", tt.sortBy, tt.sortOrder, keys, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLinkScope define o que um link público permite fazer com o documento
+type ShareLinkScope string
+
+const (
+	ShareScopeReadOnly ShareLinkScope = "read_only" // Apenas visualização
+	ShareScopeDownload ShareLinkScope = "download"  // Visualização e download do arquivo
+)
+
+// IsValid verifica se o escopo é conhecido
+func (s ShareLinkScope) IsValid() bool {
+	return s == ShareScopeReadOnly || s == ShareScopeDownload
+}
+
+// ShareLink representa um link público de acesso a um documento.
+// Apenas o hash do token é armazenado; o token em si é exibido somente na criação.
+type ShareLink struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DocumentID   string             `bson:"document_id" json:"document_id"`
+	TokenHash    string             `bson:"token_hash" json:"-"`
+	TokenPrefix  string             `bson:"token_prefix" json:"token_prefix"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
+	Scope        ShareLinkScope     `bson:"scope" json:"scope"`
+	ExpiresAt    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	MaxUses      int64              `bson:"max_uses" json:"max_uses"`
+	UseCount     int64              `bson:"use_count" json:"use_count"`
+	CreatedBy    string             `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt   *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RevokedBy    string             `bson:"revoked_by,omitempty" json:"revoked_by,omitempty"`
+}
+
+// ShareLinkAttempts conta as senhas incorretas de um cliente em um link. O bloqueio vale
+// apenas para esse cliente, para que as tentativas de terceiros não impeçam o acesso
+// dos destinatários legítimos.
+type ShareLinkAttempts struct {
+	LinkID      primitive.ObjectID `bson:"link_id"`
+	ClientKey   string             `bson:"client_key"` // Hash do endereço do cliente
+	Failed      int64              `bson:"failed"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty"`
+	ExpiresAt   time.Time          `bson:"expires_at"` // Removido automaticamente pelo índice TTL
+}
+
+// HasPassword indica se o link exige senha
+func (l *ShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// Status descreve a situação atual do link
+func (l *ShareLink) Status(now time.Time) string {
+	switch {
+	case l.RevokedAt != nil:
+		return "revoked"
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return "expired"
+	case l.MaxUses > 0 && l.UseCount >= l.MaxUses:
+		return "exhausted"
+	default:
+		return "active"
+	}
+}
+
+// ShareLinkCreate representa os dados para criação de um link público
+type ShareLinkCreate struct {
+	Scope          ShareLinkScope `json:"scope"`
+	Password       string         `json:"password"`
+	ExpiresAt      *time.Time     `json:"expires_at"`
+	ExpiresInHours int            `json:"expires_in_hours"`
+	MaxUses        int64          `json:"max_uses"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadProtocol identifica como os bytes de um envio chegam ao armazenamento
+type UploadProtocol string
+
+const (
+	UploadTus       UploadProtocol = "tus"       // Envio retomável em trechos pelo serviço (protocolo tus)
+	UploadPresigned UploadProtocol = "presigned" // Envio direto ao MinIO por formulário pré-assinado
+)
+
+// Upload representa um envio de arquivo ainda em andamento ou recém-concluído.
+// No protocolo tus, os bytes recebidos ficam nas partes de um upload multipart do MinIO e o
+// trecho final que ainda não completa uma parte fica em um objeto auxiliar até o próximo PATCH.
+// No envio pré-assinado, o cliente grava o objeto diretamente e o serviço só o verifica ao final.
+type Upload struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Protocol    UploadProtocol     `bson:"protocol" json:"protocol"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Filename    string             `bson:"filename" json:"filename"`
+	FolderID    string             `bson:"folder_id" json:"folder_id"`
+	Description string             `bson:"description" json:"description"`
+	Length      int64              `bson:"length" json:"length"` // Tamanho total declarado na criação
+	Offset      int64              `bson:"offset" json:"offset"` // Bytes já persistidos (partes + trecho pendente)
+	ObjectName  string             `bson:"object_name" json:"object_name"`
+	DocumentID  string             `bson:"document_id,omitempty" json:"document_id,omitempty"` // No envio pré-assinado, reservado na criação
+	SHA256      string             `bson:"sha256,omitempty" json:"sha256,omitempty"`           // Checksum informado pelo cliente
+	MultipartID string             `bson:"multipart_id" json:"-"`
+	Parts       []UploadPart       `bson:"parts" json:"-"`
+	PendingSize int64              `bson:"pending_size" json:"-"`          // Bytes guardados no objeto auxiliar
+	LeaseUntil  *time.Time         `bson:"lease_until,omitempty" json:"-"` // Impede PATCHs concorrentes
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// UploadPart registra uma parte já enviada ao MinIO
+type UploadPart struct {
+	Number int    `bson:"number" json:"number"`
+	ETag   string `bson:"etag" json:"etag"`
+	Size   int64  `bson:"size" json:"size"`
+}
+
+// PendingObjectName retorna o nome do objeto auxiliar com o trecho que ainda não completa uma parte
+func (u *Upload) PendingObjectName() string {
+	return "uploads/" + u.ID.Hex() + "/pending.part"
+}
+
+// PartsSize soma o tamanho das partes já enviadas
+func (u *Upload) PartsSize() int64 {
+	var total int64
+	for _, part := range u.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+// IsComplete indica se todos os bytes declarados já foram recebidos
+func (u *Upload) IsComplete() bool {
+	return u.CompletedAt != nil
+}
+
+// PresignedUploadRequest representa o pedido de um envio direto ao armazenamento
+type PresignedUploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	Size        int64  `json:"size" binding:"required"`
+	SHA256      string `json:"sha256"`
+	FolderID    string `json:"folder_id"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// WorkflowAction identifica uma ação do fluxo de aprovação
+type WorkflowAction string
+
+const (
+	WorkflowSubmit   WorkflowAction = "submit"    // Rascunho enviado para revisão
+	WorkflowApprove  WorkflowAction = "approve"   // Aprovação de um revisor
+	WorkflowReject   WorkflowAction = "reject"    // Reprovação de um revisor, volta a rascunho
+	WorkflowPublish  WorkflowAction = "publish"   // Publicação após todas as aprovações
+	WorkflowArchive  WorkflowAction = "archive"   // Arquivamento do documento
+	WorkflowNewDraft WorkflowAction = "new_draft" // Novo rascunho a partir de um documento publicado ou arquivado
+)
+
+// workflowTransitions define as mudanças de estado permitidas
+var workflowTransitions = map[DocumentStatus][]DocumentStatus{
+	StatusDraft:     {StatusReview, StatusArchived},
+	StatusReview:    {StatusPublished, StatusDraft},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+// Normalize trata documentos sem estado definido como rascunho
+func (s DocumentStatus) Normalize() DocumentStatus {
+	if s == "" {
+		return StatusDraft
+	}
+	return s
+}
+
+// CanTransitionTo verifica se a mudança de estado é permitida pelo fluxo de aprovação
+func (s DocumentStatus) CanTransitionTo(target DocumentStatus) bool {
+	for _, allowed := range workflowTransitions[s.Normalize()] {
+		if allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTransitions lista os estados que podem ser alcançados a partir do atual
+func (s DocumentStatus) AllowedTransitions() []DocumentStatus {
+	return append([]DocumentStatus{}, workflowTransitions[s.Normalize()]...)
+}
+
+// IsEditable indica se o conteúdo pode ser alterado diretamente neste estado
+func (s DocumentStatus) IsEditable() bool {
+	return s.Normalize() == StatusDraft
+}
+
+// DocumentWorkflow guarda os revisores designados e o histórico de decisões do documento
+type DocumentWorkflow struct {
+	Reviewers        []string           `bson:"reviewers" json:"reviewers"`
+	Round            int                `bson:"round" json:"round"` // Incrementado a cada envio para revisão
+	SubmittedBy      string             `bson:"submitted_by,omitempty" json:"submitted_by,omitempty"`
+	SubmittedAt      *time.Time         `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
+	PublishedAt      *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	PublishedVersion int                `bson:"published_version,omitempty" json:"published_version,omitempty"`
+	Decisions        []WorkflowDecision `bson:"decisions" json:"decisions"`
+}
+
+// WorkflowDecision registra uma ação do fluxo de aprovação
+type WorkflowDecision struct {
+	Action        WorkflowAction `bson:"action" json:"action"`
+	ActorID       string         `bson:"actor_id" json:"actor_id"`
+	Comment       string         `bson:"comment,omitempty" json:"comment,omitempty"`
+	FromStatus    DocumentStatus `bson:"from_status" json:"from_status"`
+	ToStatus      DocumentStatus `bson:"to_status" json:"to_status"`
+	Round         int            `bson:"round" json:"round"`
+	VersionNumber int            `bson:"version_number" json:"version_number"`
+	Timestamp     time.Time      `bson:"timestamp" json:"timestamp"`
+}
+
+// IsReviewer verifica se o usuário é um dos revisores designados
+func (w *DocumentWorkflow) IsReviewer(userID string) bool {
+	return containsID(w.Reviewers, userID)
+}
+
+// ApprovalsInRound retorna os revisores que já aprovaram a rodada de revisão informada
+func (w *DocumentWorkflow) ApprovalsInRound(round int) []string {
+	approved := []string{}
+	for _, decision := range w.Decisions {
+		if decision.Round == round && decision.Action == WorkflowApprove && !containsID(approved, decision.ActorID) {
+			approved = append(approved, decision.ActorID)
+		}
+	}
+	return approved
+}
+
+// PendingReviewers retorna os revisores que ainda não aprovaram a rodada atual.
+// Quem enviou o documento para revisão não participa da aprovação.
+func (w *DocumentWorkflow) PendingReviewers() []string {
+	approved := w.ApprovalsInRound(w.Round)
+	pending := []string{}
+	for _, reviewer := range w.Reviewers {
+		if reviewer != w.SubmittedBy && !containsID(approved, reviewer) {
+			pending = append(pending, reviewer)
+		}
+	}
+	return pending
+}
+
+// WorkflowComment representa o corpo das ações do fluxo de aprovação
+type WorkflowComment struct {
+	Comment string `json:"comment"`
+}
+
+// ReviewersUpdate representa a designação dos revisores de um documento
+type ReviewersUpdate struct {
+	Reviewers []string `json:"reviewers" binding:"required"`
+}
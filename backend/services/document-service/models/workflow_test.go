@@ -0,0 +1,103 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanTransitionTo(t *testing.T) {
+	statuses := []DocumentStatus{StatusDraft, StatusReview, StatusPublished, StatusArchived}
+
+	// Transições permitidas; todas as demais combinações devem ser recusadas
+	allowed := map[DocumentStatus][]DocumentStatus{
+		StatusDraft:     {StatusReview, StatusArchived},
+		StatusReview:    {StatusPublished, StatusDraft},
+		StatusPublished: {StatusDraft, StatusArchived},
+		StatusArchived:  {StatusDraft},
+		"":              {StatusReview, StatusArchived}, // Sem estado equivale a rascunho
+	}
+
+	for from, targets := range allowed {
+		for _, to := range statuses {
+			want := false
+			for _, target := range targets {
+				want = want || target == to
+			}
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%q.CanTransitionTo(%q) = %v, esperado %v", from, to, got, want)
+			}
+		}
+		if got := from.AllowedTransitions(); !reflect.DeepEqual(got, targets) {
+			t.Errorf("%q.AllowedTransitions() = %v, esperado %v", from, got, targets)
+		}
+	}
+}
+
+func TestIsEditable(t *testing.T) {
+	tests := []struct {
+		status DocumentStatus
+		want   bool
+	}{
+		{"", true},
+		{StatusDraft, true},
+		{StatusReview, false},
+		{StatusPublished, false},
+		{StatusArchived, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.IsEditable(); got != tt.want {
+			t.Errorf("%q.IsEditable() = %v, esperado %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestPendingReviewers(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow DocumentWorkflow
+		want     []string
+	}{
+		{
+			name:     "ninguém aprovou",
+			workflow: DocumentWorkflow{Reviewers: []string{"ana", "bia"}, Round: 1},
+			want:     []string{"ana", "bia"},
+		},
+		{
+			name: "aprovações da rodada atual",
+			workflow: DocumentWorkflow{Reviewers: []string{"ana", "bia"}, Round: 1, Decisions: []WorkflowDecision{
+				{Action: WorkflowApprove, ActorID: "ana", Round: 1},
+			}},
+			want: []string{"bia"},
+		},
+		{
+			name: "aprovações de rodadas anteriores não contam",
+			workflow: DocumentWorkflow{Reviewers: []string{"ana", "bia"}, Round: 2, Decisions: []WorkflowDecision{
+				{Action: WorkflowApprove, ActorID: "ana", Round: 1},
+				{Action: WorkflowReject, ActorID: "bia", Round: 1},
+			}},
+			want: []string{"ana", "bia"},
+		},
+		{
+			name:     "quem enviou não aprova",
+			workflow: DocumentWorkflow{Reviewers: []string{"ana", "bia"}, Round: 1, SubmittedBy: "ana"},
+			want:     []string{"bia"},
+		},
+		{
+			name: "todos aprovaram",
+			workflow: DocumentWorkflow{Reviewers: []string{"ana"}, Round: 1, Decisions: []WorkflowDecision{
+				{Action: WorkflowApprove, ActorID: "ana", Round: 1},
+				{Action: WorkflowApprove, ActorID: "ana", Round: 1},
+			}},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.workflow.PendingReviewers(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PendingReviewers() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
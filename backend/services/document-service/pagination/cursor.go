@@ -0,0 +1,106 @@
+// Package pagination gera e confere os cursores opacos da paginação das listagens. O cursor
+// guarda a ordenação e um hash dos filtros da consulta, os valores dos campos ordenados e o _id
+// do documento na borda da página, e é assinado com HMAC para que o cliente não possa alterá-lo.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor indica um cursor malformado, adulterado ou de outra consulta
+var ErrInvalidCursor = errors.New("cursor de paginação inválido")
+
+// Cursor marca a posição de uma página na listagem
+type Cursor struct {
+	Sort     string             `bson:"s"` // Ordenação na forma canônica de sort_by
+	Filter   string             `bson:"f"` // Hash dos filtros da consulta, de FilterHash
+	Values   []bson.RawValue    `bson:"v"` // Valores dos campos ordenados no documento da borda
+	ID       primitive.ObjectID `bson:"i"` // Desempate entre documentos com os mesmos valores
+	Backward bool               `bson:"b,omitempty"`
+}
+
+var (
+	signingKey     []byte
+	signingKeyOnce sync.Once
+)
+
+// key obtém a chave de assinatura de CURSOR_SECRET_KEY ou, na falta dela, de JWT_SECRET_KEY
+func key() []byte {
+	signingKeyOnce.Do(func() {
+		secret := os.Getenv("CURSOR_SECRET_KEY")
+		if secret == "" {
+			secret = os.Getenv("JWT_SECRET_KEY")
+		}
+		if secret == "" {
+			secret = "chave_secreta_insegura_para_desenvolvimento" // Em produção, deve ser configurada no ambiente
+			log.Println("Aviso: Usando chave padrão de desenvolvimento para os cursores de paginação")
+		}
+		// Derivar uma chave própria para não reutilizar a chave dos tokens diretamente
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("document-service/pagination"))
+		signingKey = mac.Sum(nil)
+	})
+	return signingKey
+}
+
+// Encode serializa e assina o cursor
+func Encode(cursor Cursor) (string, error) {
+	payload, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(payload)), nil
+}
+
+// FilterHash resume os filtros normalizados de uma consulta para guardá-los no cursor
+func FilterHash(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// Decode confere a assinatura e desserializa o cursor, recusando-o se ele foi gerado para
+// outra ordenação ou outros filtros
+func Decode(token string, sort string, filter string) (*Cursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := bson.Unmarshal(payload, &cursor); err != nil || cursor.Sort == "" || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Filter != filter {
+		return nil, fmt.Errorf("%w: o cursor é de outra consulta", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+func sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, key())
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,101 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecode(t *testing.T) {
+	filter := FilterHash("status:draft", "tag:ops")
+	valid := Cursor{Sort: "title", Filter: filter, ID: primitive.NewObjectID()}
+	token, err := Encode(valid)
+	if err != nil {
+		t.Fatalf("Encode(): %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// resign monta um token com outro conteúdo e a assinatura original
+	resign := func(cursor Cursor) string {
+		data, err := bson.Marshal(cursor)
+		if err != nil {
+			t.Fatalf("Marshal(): %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data) + "." + signature
+	}
+	// forge assina corretamente um cursor arbitrário, como faria o próprio serviço
+	forge := func(cursor Cursor) string {
+		token, err := Encode(cursor)
+		if err != nil {
+			t.Fatalf("Encode(): %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		sort       string
+		filter     string
+		wantErr    bool
+		otherQuery bool
+	}{
+		{name: "válido", token: token, sort: "title", filter: filter},
+		{name: "sem assinatura", token: payload, sort: "title", filter: filter, wantErr: true},
+		{name: "assinatura vazia", token: payload + ".", sort: "title", filter: filter, wantErr: true},
+		{name: "base64 inválido", token: "%%%." + signature, sort: "title", filter: filter, wantErr: true},
+		{name: "conteúdo adulterado", token: resign(Cursor{Sort: "title", Filter: filter, ID: primitive.NewObjectID()}), sort: "title", filter: filter, wantErr: true},
+		{name: "assinatura de outro cursor", token: payload + "." + strings.SplitN(forge(Cursor{Sort: "title", Filter: filter, ID: primitive.NewObjectID()}), ".", 2)[1], sort: "title", filter: filter, wantErr: true},
+		{name: "outra ordenação", token: token, sort: "-updated_at", filter: filter, wantErr: true, otherQuery: true},
+		{name: "outros filtros", token: token, sort: "title", filter: FilterHash("status:draft"), wantErr: true, otherQuery: true},
+		{name: "sem ordenação", token: forge(Cursor{Filter: filter, ID: primitive.NewObjectID()}), sort: "", filter: filter, wantErr: true},
+		{name: "sem ID", token: forge(Cursor{Sort: "title", Filter: filter}), sort: "title", filter: filter, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := Decode(tt.token, tt.sort, tt.filter)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Decode() retornou erro: %v", err)
+				}
+				if cursor.ID != valid.ID || cursor.Sort != valid.Sort || cursor.Filter != valid.Filter {
+					t.Errorf("Decode() = %+v, esperado %+v", cursor, valid)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("Decode() = %v, esperado ErrInvalidCursor", err)
+			}
+			if got := strings.Contains(err.Error(), "outra consulta"); got != tt.otherQuery {
+				t.Errorf("Decode() = %q, cursor de outra consulta: %v, esperado %v", err, got, tt.otherQuery)
+			}
+		})
+	}
+}
+
+func TestFilterHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     []string
+		b     []string
+		equal bool
+	}{
+		{"mesmos filtros", []string{"status:draft", "tag:ops"}, []string{"status:draft", "tag:ops"}, true},
+		{"ordem importa", []string{"a", "b"}, []string{"b", "a"}, false},
+		{"separação entre as partes", []string{"ab", "c"}, []string{"a", "bc"}, false},
+		{"parte vazia conta", []string{"a"}, []string{"a", ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterHash(tt.a...) == FilterHash(tt.b...); got != tt.equal {
+				t.Errorf("FilterHash(%q) == FilterHash(%q) = %v, esperado %v", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+}
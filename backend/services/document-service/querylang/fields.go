@@ -0,0 +1,101 @@
+package querylang
+
+import (
+	"strings"
+	"time"
+
+	"gestor-e-docs/document-service/models"
+)
+
+// Campos aceitos nos filtros
+const (
+	FieldTag      = "tag"
+	FieldCategory = "category"
+	FieldStatus   = "status"
+	FieldAuthor   = "author"
+	FieldTitle    = "title"
+	FieldCreated  = "created"
+	FieldUpdated  = "updated"
+)
+
+// AuthorMe é o valor de author que indica quem faz a consulta
+const AuthorMe = "me"
+
+// parseField converte um filtro campo:valor no nó correspondente
+func parseField(tok token) (Expr, error) {
+	switch tok.field {
+	case FieldTag, FieldCategory, FieldAuthor, FieldTitle:
+		return &Match{Field: tok.field, Value: tok.value, Position: tok.pos}, nil
+
+	case FieldStatus:
+		status := models.DocumentStatus(strings.ToLower(tok.value))
+		switch status {
+		case models.StatusDraft, models.StatusReview, models.StatusPublished, models.StatusArchived:
+			return &Match{Field: tok.field, Value: string(status), Position: tok.pos}, nil
+		}
+		return nil, &SyntaxError{Position: tok.pos, Token: tok.text, Message: "status inválido; use draft, review, published ou archived"}
+
+	case FieldCreated, FieldUpdated:
+		from, to, ok := parseDateFilter(tok.value)
+		if !ok || tok.phrase {
+			return nil, &SyntaxError{Position: tok.pos, Token: tok.text, Message: "data inválida; use AAAA-MM-DD, AAAA-MM ou AAAA, precedida de >, >=, < ou <=, ou um intervalo inicio..fim"}
+		}
+		return &DateRange{Field: tok.field, From: from, To: to, Position: tok.pos}, nil
+	}
+
+	return nil, &SyntaxError{Position: tok.pos, Token: tok.text, Message: "campo desconhecido; use tag, category, status, author, title, created ou updated"}
+}
+
+// parseDateFilter converte o valor de um filtro de data no intervalo [from, to)
+func parseDateFilter(value string) (time.Time, time.Time, bool) {
+	if start, end, found := strings.Cut(value, ".."); found {
+		from, _, okFrom := parseDate(start)
+		_, to, okTo := parseDate(end)
+		if !okFrom || !okTo || !from.Before(to) {
+			return time.Time{}, time.Time{}, false
+		}
+		return from, to, true
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		rest, found := strings.CutPrefix(value, op)
+		if !found {
+			continue
+		}
+		start, end, ok := parseDate(rest)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		switch op {
+		case ">=":
+			return start, time.Time{}, true
+		case ">":
+			return end, time.Time{}, true
+		case "<=":
+			return time.Time{}, end, true
+		default:
+			return time.Time{}, start, true
+		}
+	}
+
+	start, end, ok := parseDate(value)
+	return start, end, ok
+}
+
+// parseDate interpreta uma data em UTC e retorna o período que ela cobre: o ano, o mês ou o
+// dia inteiro, ou um único milissegundo para um instante RFC 3339
+func parseDate(value string) (time.Time, time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, t.Add(time.Millisecond), true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, t.AddDate(0, 0, 1), true
+	}
+	if t, err := time.Parse("2006-01", value); err == nil {
+		return t, t.AddDate(0, 1, 0), true
+	}
+	if t, err := time.Parse("2006", value); err == nil {
+		return t, t.AddDate(1, 0, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,156 @@
+package querylang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifica o tipo de um token da consulta
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// token é um trecho da consulta já classificado. text guarda o trecho como foi digitado,
+// usado nas mensagens de erro.
+type token struct {
+	kind   tokenKind
+	text   string
+	pos    int
+	field  string // Nome do campo, em tokField
+	value  string // Termo, frase ou valor do campo
+	phrase bool   // O valor do campo foi informado entre aspas
+}
+
+// lex divide a consulta em tokens. As posições são contadas em caracteres, a partir de 1.
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+
+		case r == '-':
+			if i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == ')' {
+				return nil, &SyntaxError{Position: pos, Token: "-", Message: "o sinal de exclusão deve vir junto do termo excluído"}
+			}
+			tokens = append(tokens, token{kind: tokNot, text: "-", pos: pos})
+			i++
+
+		case r == '"':
+			value, end, err := lexPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: string(runes[i:end]), pos: pos, value: value})
+			i = end
+
+		default:
+			end := i
+			for end < len(runes) && !isDelimiter(runes[end]) {
+				end++
+			}
+			tok, next, err := lexWord(runes, i, end)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// lexPhrase lê a frase entre aspas que começa em start e retorna o conteúdo e a posição
+// seguinte às aspas de fechamento
+func lexPhrase(runes []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] != '"' {
+			continue
+		}
+		value := strings.Join(strings.Fields(string(runes[start+1:end])), " ")
+		if value == "" {
+			return "", 0, &SyntaxError{Position: start + 1, Token: string(runes[start : end+1]), Message: "frase vazia"}
+		}
+		return value, end + 1, nil
+	}
+	return "", 0, &SyntaxError{Position: start + 1, Token: string(runes[start:]), Message: "aspas sem fechamento"}
+}
+
+// lexWord classifica a palavra entre start e end como operador, filtro por campo ou termo
+func lexWord(runes []rune, start int, end int) (token, int, error) {
+	word := string(runes[start:end])
+	pos := start + 1
+
+	switch word {
+	case "AND":
+		return token{kind: tokAnd, text: word, pos: pos}, end, nil
+	case "OR":
+		return token{kind: tokOr, text: word, pos: pos}, end, nil
+	case "NOT":
+		return token{kind: tokNot, text: word, pos: pos}, end, nil
+	}
+
+	// Só é filtro o que tem nome de campo antes dos dois pontos; "10:30" continua sendo termo
+	name, value, found := strings.Cut(word, ":")
+	if !found || !isFieldName(name) {
+		return token{kind: tokWord, text: word, pos: pos, value: word}, end, nil
+	}
+
+	tok := token{kind: tokField, text: word, pos: pos, field: strings.ToLower(name), value: value}
+	if value == "" && end < len(runes) && runes[end] == '"' {
+		phrase, next, err := lexPhrase(runes, end)
+		if err != nil {
+			return token{}, 0, err
+		}
+		tok.text = string(runes[start:next])
+		tok.value = phrase
+		tok.phrase = true
+		end = next
+	}
+	if tok.value == "" {
+		return token{}, 0, &SyntaxError{Position: pos, Token: word, Message: "valor ausente no filtro"}
+	}
+	return tok, end, nil
+}
+
+// isDelimiter indica os caracteres que encerram uma palavra
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// isFieldName indica se o texto antes dos dois pontos pode ser um nome de campo
+func isFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
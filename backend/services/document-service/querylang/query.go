@@ -0,0 +1,292 @@
+// Package querylang interpreta a linguagem de consulta da busca de documentos:
+//
+//	tag:ops status:published author:me created:>2026-01-01 "frase exata" -excluido title:runbook
+//
+// Termos lado a lado são combinados com AND; AND, OR e NOT (em maiúsculas) e parênteses
+// permitem outras combinações, e o prefixo "-" exclui o termo, a frase ou o grupo seguinte.
+// Filtros aceitos: tag, category, status, author (me indica quem consulta), title, created e
+// updated. As datas aceitam AAAA, AAAA-MM, AAAA-MM-DD ou RFC 3339, precedidas de >, >=, < ou
+// <=, ou um intervalo inicio..fim.
+package querylang
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limites que protegem o servidor de consultas abusivas
+const (
+	MaxLength = 1000 // Caracteres
+	maxDepth  = 32   // Níveis de parênteses e negações
+)
+
+// Expr é um nó da consulta interpretada
+type Expr interface {
+	Pos() int
+}
+
+// And exige que todos os itens sejam atendidos
+type And struct {
+	Items    []Expr
+	Position int
+}
+
+// Or exige que ao menos um dos itens seja atendido
+type Or struct {
+	Items    []Expr
+	Position int
+}
+
+// Not exige que o item não seja atendido
+type Not struct {
+	Item     Expr
+	Position int
+}
+
+// Text é um termo ou frase livre, procurado no título e no conteúdo
+type Text struct {
+	Value    string
+	Phrase   bool
+	Position int
+}
+
+// Match compara um campo de texto com o valor informado. Em title, o valor é procurado
+// em qualquer parte do título; nos demais campos, a comparação é exata.
+type Match struct {
+	Field    string
+	Value    string
+	Position int
+}
+
+// DateRange restringe um campo de data ao intervalo [From, To). Um limite zero fica em aberto.
+type DateRange struct {
+	Field    string
+	From     time.Time
+	To       time.Time
+	Position int
+}
+
+func (e *And) Pos() int       { return e.Position }
+func (e *Or) Pos() int        { return e.Position }
+func (e *Not) Pos() int       { return e.Position }
+func (e *Text) Pos() int      { return e.Position }
+func (e *Match) Pos() int     { return e.Position }
+func (e *DateRange) Pos() int { return e.Position }
+
+// SyntaxError descreve um erro na consulta, apontando o trecho problemático
+type SyntaxError struct {
+	Position int    `json:"position"` // Posição do trecho, em caracteres, a partir de 1
+	Token    string `json:"token"`    // Trecho problemático; vazio no fim da consulta
+	Message  string `json:"message"`
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s no fim da consulta (posição %d)", e.Message, e.Position)
+	}
+	return fmt.Sprintf("%s em %q (posição %d)", e.Message, e.Token, e.Position)
+}
+
+// Parse interpreta a consulta. Uma consulta vazia retorna nil sem erro.
+func Parse(input string) (Expr, error) {
+	if length := len([]rune(input)); length > MaxLength {
+		return nil, &SyntaxError{Position: MaxLength + 1, Message: fmt.Sprintf("consulta longa demais (máximo de %d caracteres)", MaxLength)}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok, "parêntese fechado sem abertura")
+	}
+	return expr, nil
+}
+
+// Terms retorna, em minúsculas e sem repetição, os termos e frases livres que um documento
+// precisa conter para atender à consulta, ou seja, os que não estão sob uma negação
+func Terms(expr Expr) []string {
+	var terms []string
+	seen := map[string]bool{}
+	var walk func(e Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case *And:
+			for _, item := range e.Items {
+				walk(item)
+			}
+		case *Or:
+			for _, item := range e.Items {
+				walk(item)
+			}
+		case *Text:
+			term := strings.ToLower(e.Value)
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	walk(expr)
+	return terms
+}
+
+// parser percorre os tokens por descida recursiva
+type parser struct {
+	tokens []token
+	next   int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, message string) *SyntaxError {
+	return &SyntaxError{Position: tok.pos, Token: tok.text, Message: message}
+}
+
+// parseOr lê itens separados por OR
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Expr{first}
+	for p.peek().kind == tokOr {
+		p.advance()
+		item, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 1 {
+		return first, nil
+	}
+	return &Or{Items: items, Position: first.Pos()}, nil
+}
+
+// parseAnd lê itens separados por AND ou apenas justapostos
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Expr{first}
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(items) == 1 {
+				return first, nil
+			}
+			return &And{Items: items, Position: first.Pos()}, nil
+		case tokAnd:
+			p.advance()
+		}
+
+		item, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// parseUnary lê um item, possivelmente negado com NOT ou "-"
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.peek()
+	if tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+
+	p.advance()
+	if err := p.enter(tok); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	item, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Not{Item: item, Position: tok.pos}, nil
+}
+
+// parsePrimary lê um termo, frase, filtro ou grupo entre parênteses
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokWord:
+		return &Text{Value: tok.value, Position: tok.pos}, nil
+
+	case tokPhrase:
+		return &Text{Value: tok.value, Phrase: true, Position: tok.pos}, nil
+
+	case tokField:
+		return parseField(tok)
+
+	case tokLParen:
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		if p.peek().kind == tokRParen {
+			return nil, p.errorAt(tok, "parênteses sem conteúdo")
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.errorAt(tok, "parêntese aberto sem fechamento")
+		}
+		p.advance()
+		return expr, nil
+
+	case tokRParen:
+		return nil, p.errorAt(tok, "parêntese fechado sem abertura")
+
+	case tokAnd, tokOr:
+		return nil, p.errorAt(tok, "operador sem termo à esquerda")
+
+	default:
+		return nil, p.errorAt(p.tokens[p.next-1], "termo esperado após este trecho")
+	}
+}
+
+// enter controla a profundidade de parênteses e negações
+func (p *parser) enter(tok token) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return p.errorAt(tok, fmt.Sprintf("consulta aninhada demais (máximo de %d níveis)", maxDepth))
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
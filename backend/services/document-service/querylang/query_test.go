@@ -0,0 +1,234 @@
+package querylang
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// describe resume a consulta interpretada em uma forma compacta para comparação
+func describe(expr Expr) string {
+	list := func(items []Expr) string {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = describe(item)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	switch e := expr.(type) {
+	case nil:
+		return "<nil>"
+	case *And:
+		return "and(" + list(e.Items) + ")"
+	case *Or:
+		return "or(" + list(e.Items) + ")"
+	case *Not:
+		return "not(" + describe(e.Item) + ")"
+	case *Text:
+		if e.Phrase {
+			return fmt.Sprintf("phrase(%s)", e.Value)
+		}
+		return fmt.Sprintf("text(%s)", e.Value)
+	case *Match:
+		return fmt.Sprintf("%s:%s", e.Field, e.Value)
+	case *DateRange:
+		return fmt.Sprintf("%s[%s,%s)", e.Field, formatBound(e.From), formatBound(e.To))
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return "*"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"vazia", "", "<nil>"},
+		{"só espaços", "   ", "<nil>"},
+		{"termo", "runbook", "text(runbook)"},
+		{"termos justapostos", "plano anual", "and(text(plano) text(anual))"},
+		{"AND explícito", "plano AND anual", "and(text(plano) text(anual))"},
+		{"frase com espaços normalizados", `"plano   anual"`, "phrase(plano anual)"},
+		{"OR tem precedência menor que AND", "a OR b c", "or(text(a) and(text(b) text(c)))"},
+		{"parênteses", "(a OR b) c", "and(or(text(a) text(b)) text(c))"},
+		{"operadores só em maiúsculas", "a or b", "and(text(a) text(or) text(b))"},
+		{"hora não é filtro", "10:30", "text(10:30)"},
+		{"campo e status sem diferenciar maiúsculas", "Status:Published", "status:published"},
+		{"filtro com frase", `title:"plano anual"`, "title:plano anual"},
+		{"author me", "author:me tag:ops", "and(author:me tag:ops)"},
+		{"hífen no meio da palavra", "e-mail", "text(e-mail)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) retornou erro: %v", tt.input, err)
+			}
+			if got := describe(expr); got != tt.want {
+				t.Errorf("Parse(%q) = %s, esperado %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNegation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"sinal de exclusão", "-rascunho", "not(text(rascunho))"},
+		{"NOT", "NOT rascunho", "not(text(rascunho))"},
+		{"exclusão de frase", `-"plano anual"`, "not(phrase(plano anual))"},
+		{"exclusão de filtro", "relatorio -tag:interno", "and(text(relatorio) not(tag:interno))"},
+		{"exclusão de grupo", "-(a OR b)", "not(or(text(a) text(b)))"},
+		{"AND seguido de NOT", "a AND NOT b", "and(text(a) not(text(b)))"},
+		{"negação dupla", "--a", "not(not(text(a)))"},
+		{"NOT e sinal combinados", "NOT -a", "not(not(text(a)))"},
+		{"exclusão dentro de OR", "a OR -b", "or(text(a) not(text(b)))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) retornou erro: %v", tt.input, err)
+			}
+			if got := describe(expr); got != tt.want {
+				t.Errorf("Parse(%q) = %s, esperado %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"ano", "created:2026", "created[2026-01-01T00:00:00Z,2027-01-01T00:00:00Z)"},
+		{"mês", "created:2026-02", "created[2026-02-01T00:00:00Z,2026-03-01T00:00:00Z)"},
+		{"dia", "updated:2026-02-28", "updated[2026-02-28T00:00:00Z,2026-03-01T00:00:00Z)"},
+		{"instante", "created:2026-01-15T10:00:00Z", "created[2026-01-15T10:00:00Z,2026-01-15T10:00:00.001Z)"},
+		{"a partir de", "created:>=2026-02", "created[2026-02-01T00:00:00Z,*)"},
+		{"depois de", "created:>2026-02", "created[2026-03-01T00:00:00Z,*)"},
+		{"antes de", "created:<2026-02", "created[*,2026-02-01T00:00:00Z)"},
+		{"até", "created:<=2026-02", "created[*,2026-03-01T00:00:00Z)"},
+		{"intervalo inclui o último período", "updated:2026-01..2026-03", "updated[2026-01-01T00:00:00Z,2026-04-01T00:00:00Z)"},
+		{"intervalo de um dia", "created:2026-01-10..2026-01-10", "created[2026-01-10T00:00:00Z,2026-01-11T00:00:00Z)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) retornou erro: %v", tt.input, err)
+			}
+			if got := describe(expr); got != tt.want {
+				t.Errorf("Parse(%q) = %s, esperado %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		position int
+		token    string
+		message  string
+	}{
+		// Erros do analisador léxico
+		{"sinal de exclusão solto", "a -", 3, "-", "sinal de exclusão"},
+		{"sinal de exclusão antes de espaço", "a - b", 3, "-", "sinal de exclusão"},
+		{"sinal de exclusão antes de parêntese", "(a -)", 4, "-", "sinal de exclusão"},
+		{"aspas sem fechamento", `foo "abc`, 5, `"abc`, "aspas sem fechamento"},
+		{"frase vazia", `a ""`, 3, `""`, "frase vazia"},
+		{"filtro sem valor", "a tag:", 3, "tag:", "valor ausente"},
+		{"posição em caracteres, não em bytes", "ação -", 6, "-", "sinal de exclusão"},
+
+		// Erros do analisador sintático
+		{"parêntese sem fechamento", "x (a", 3, "(", "aberto sem fechamento"},
+		{"parêntese sem abertura", "a)", 2, ")", "fechado sem abertura"},
+		{"parêntese sem abertura no início", ") a", 1, ")", "fechado sem abertura"},
+		{"parênteses vazios", "a ()", 3, "(", "sem conteúdo"},
+		{"operador no início", "OR a", 1, "OR", "sem termo à esquerda"},
+		{"operadores seguidos", "a AND OR b", 7, "OR", "sem termo à esquerda"},
+		{"operador no fim", "a AND", 3, "AND", "termo esperado"},
+		{"NOT sem termo", "a NOT", 3, "NOT", "termo esperado"},
+
+		// Erros dos filtros
+		{"campo desconhecido", "autor:ana", 1, "autor:ana", "campo desconhecido"},
+		{"status inválido", "a status:final", 3, "status:final", "status inválido"},
+		{"data inválida", "created:2026-13", 1, "created:2026-13", "data inválida"},
+		{"data entre aspas", `created:"2026"`, 1, `created:"2026"`, "data inválida"},
+		{"intervalo invertido", "created:2026-02..2026-01", 1, "created:2026-02..2026-01", "data inválida"},
+		{"intervalo sem fim", "created:2026..", 1, "created:2026..", "data inválida"},
+		{"operador sem data", "created:>=", 1, "created:>=", "data inválida"},
+
+		// Limites
+		{"consulta longa demais", strings.Repeat("a", MaxLength+1), MaxLength + 1, "", "longa demais"},
+		{"aninhamento excessivo", strings.Repeat("(", maxDepth+1) + "a" + strings.Repeat(")", maxDepth+1), maxDepth + 1, "(", "aninhada demais"},
+		{"negações excessivas", strings.Repeat("NOT ", maxDepth+1) + "a", 4*maxDepth + 1, "NOT", "aninhada demais"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %s, esperado erro", tt.input, describe(expr))
+			}
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Parse(%q) retornou %T, esperado *SyntaxError", tt.input, err)
+			}
+			if syntaxErr.Position != tt.position || syntaxErr.Token != tt.token {
+				t.Errorf("Parse(%q): erro em %d %q, esperado em %d %q", tt.input, syntaxErr.Position, syntaxErr.Token, tt.position, tt.token)
+			}
+			if !strings.Contains(syntaxErr.Message, tt.message) {
+				t.Errorf("Parse(%q): mensagem %q não contém %q", tt.input, syntaxErr.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"sem consulta", "", nil},
+		{"só filtros", "tag:ops status:draft", nil},
+		{"termos e frases em minúsculas", `Plano "Meta Anual"`, []string{"plano", "meta anual"}},
+		{"sem repetição", "plano PLANO", []string{"plano"}},
+		{"termos negados ficam de fora", "a -b NOT c", []string{"a"}},
+		{"grupo negado fica de fora", "a -(b OR c)", []string{"a"}},
+		{"alternativas entram", "(a OR b) tag:x", []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) retornou erro: %v", tt.input, err)
+			}
+			if got := Terms(expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, esperado %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
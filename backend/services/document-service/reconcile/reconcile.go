@@ -0,0 +1,291 @@
+// Package reconcile compara os documentos registrados no MongoDB com os objetos armazenados,
+// apontando objetos órfãos, referências para objetos inexistentes e contagens de referência
+// de blobs divergentes. Por padrão apenas relata; as correções exigem Apply.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultGracePeriod protege objetos recém-gravados cujo registro no MongoDB ainda não foi feito
+const DefaultGracePeriod = 24 * time.Hour
+
+// maxReportItems limita as listas detalhadas do relatório
+const maxReportItems = 1000
+
+// Options controla o que a reconciliação pode alterar
+type Options struct {
+	Apply         bool          // Sem Apply, nada é alterado (simulação)
+	DeleteOrphans bool          // Remover objetos órfãos
+	Repair        bool          // Corrigir referências inexistentes e contagens de blobs
+	GracePeriod   time.Duration // Objetos mais novos que isso nunca são considerados órfãos
+}
+
+// reference é um caminho de armazenamento usado por um documento
+type reference struct {
+	documentID    primitive.ObjectID
+	versionNumber int
+	path          string
+	hash          string
+}
+
+// Run executa a reconciliação e retorna o relatório
+func Run(ctx context.Context, store storage.BlobStore, opts Options) (*models.ReconcileReport, error) {
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = DefaultGracePeriod
+	}
+
+	report := &models.ReconcileReport{
+		DryRun:             !opts.Apply,
+		StartedAt:          time.Now(),
+		Orphans:            []models.OrphanObject{},
+		DanglingReferences: []models.DanglingReference{},
+		BlobMismatches:     []models.BlobRefMismatch{},
+	}
+
+	// 1. Caminhos referenciados pelos documentos, inclusive os da lixeira
+	referenced := map[string]bool{}
+	actualRefs := map[string]int64{}
+	var references []reference
+
+	err := db.DbCollections.Documents.ForEachStorageReference(ctx, func(doc *models.Document) error {
+		report.DocumentsScanned++
+
+		if doc.StoragePath != "" {
+			referenced[doc.StoragePath] = true
+			references = append(references, reference{documentID: doc.ID, path: doc.StoragePath, hash: doc.ContentHash})
+		}
+		for _, version := range doc.VersionHistory {
+			if version.ContentHash != "" {
+				actualRefs[version.ContentHash]++
+			}
+			if version.StoragePath == "" || version.StoragePath == doc.StoragePath {
+				continue
+			}
+			referenced[version.StoragePath] = true
+			references = append(references, reference{
+				documentID:    doc.ID,
+				versionNumber: version.VersionNumber,
+				path:          version.StoragePath,
+				hash:          version.ContentHash,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao percorrer documentos: %v", err)
+	}
+
+	// 2. Blobs registrados e envios em andamento também mantêm objetos
+	recordedRefs := map[string]int64{}
+	err = db.DbCollections.Blobs.ForEachBlob(ctx, func(blob *models.Blob) error {
+		recordedRefs[blob.Hash] = blob.RefCount
+		referenced[blob.ObjectName] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao percorrer blobs: %v", err)
+	}
+
+	err = db.DbCollections.Uploads.ForEachUpload(ctx, func(upload *models.Upload) error {
+		if !upload.IsComplete() {
+			referenced[upload.ObjectName] = true
+			referenced[upload.PendingObjectName()] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao percorrer envios: %v", err)
+	}
+
+	// 3. Objetos existentes no armazenamento
+	existing := map[string]bool{}
+	var orphans []storage.ObjectInfo
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	err = store.List(ctx, "", func(object storage.ObjectInfo) error {
+		report.ObjectsScanned++
+		existing[object.Key] = true
+		if !referenced[object.Key] && object.LastModified.Before(cutoff) {
+			orphans = append(orphans, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar objetos: %v", err)
+	}
+
+	reconcileOrphans(report, store, orphans, opts)
+	reconcileDangling(report, references, existing, opts)
+	reconcileBlobs(report, store, recordedRefs, actualRefs, existing, opts)
+
+	report.FinishedAt = time.Now()
+	log.Printf("Reconciliação concluída (simulação: %t): %d órfão(s), %d referência(s) inexistente(s), %d blob(s) com contagem divergente",
+		report.DryRun, report.OrphanCount, report.DanglingCount, len(report.BlobMismatches))
+	return report, nil
+}
+
+// reconcileOrphans relata e, se autorizado, remove os objetos que ninguém referencia
+func reconcileOrphans(report *models.ReconcileReport, store storage.BlobStore, orphans []storage.ObjectInfo, opts Options) {
+	for _, object := range orphans {
+		report.OrphanCount++
+		report.OrphanBytes += object.Size
+
+		item := models.OrphanObject{
+			ObjectName:   object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+			Action:       models.ReconcileNone,
+		}
+		if opts.DeleteOrphans {
+			item.Action = models.ReconcileWouldDelete
+			if opts.Apply {
+				item.Action = models.ReconcileDeleted
+				if err := store.Delete(object.Key); err != nil {
+					item.Action = models.ReconcileFailed
+					item.Error = err.Error()
+				}
+			}
+		}
+
+		if len(report.Orphans) < maxReportItems {
+			report.Orphans = append(report.Orphans, item)
+		} else {
+			report.Truncated = true
+		}
+	}
+}
+
+// reconcileDangling relata os caminhos registrados que não existem no armazenamento e, se
+// autorizado, os aponta para o blob do mesmo conteúdo quando ele existe
+func reconcileDangling(report *models.ReconcileReport, references []reference, existing map[string]bool, opts Options) {
+	for _, ref := range references {
+		if existing[ref.path] {
+			continue
+		}
+		report.DanglingCount++
+
+		item := models.DanglingReference{
+			DocumentID:    ref.documentID.Hex(),
+			VersionNumber: ref.versionNumber,
+			StoragePath:   ref.path,
+			ContentHash:   ref.hash,
+			Action:        models.ReconcileUnrepairable,
+		}
+
+		if ref.hash != "" && existing[storage.BlobObjectName(ref.hash)] {
+			item.RepairedPath = storage.BlobObjectName(ref.hash)
+			if opts.Repair {
+				item.Action = models.ReconcileWouldRepair
+				if opts.Apply {
+					item.Action = models.ReconcileRepaired
+					if err := db.DbCollections.Documents.RepointStoragePath(ref.documentID, ref.path, item.RepairedPath); err != nil {
+						item.Action = models.ReconcileFailed
+						item.Error = err.Error()
+					}
+				}
+			} else {
+				item.Action = models.ReconcileNone
+			}
+		}
+
+		if len(report.DanglingReferences) < maxReportItems {
+			report.DanglingReferences = append(report.DanglingReferences, item)
+		} else {
+			report.Truncated = true
+		}
+	}
+}
+
+// reconcileBlobs compara a contagem registrada de cada blob com as versões que o referenciam
+func reconcileBlobs(report *models.ReconcileReport, store storage.BlobStore, recordedRefs map[string]int64, actualRefs map[string]int64, existing map[string]bool, opts Options) {
+	hashes := map[string]bool{}
+	for hash := range recordedRefs {
+		hashes[hash] = true
+	}
+	for hash := range actualRefs {
+		hashes[hash] = true
+	}
+
+	for hash := range hashes {
+		recorded, hasRecord := recordedRefs[hash]
+		actual := actualRefs[hash]
+		if hasRecord && recorded == actual {
+			continue
+		}
+
+		item := models.BlobRefMismatch{Hash: hash, RecordedRefs: recorded, ActualRefs: actual, Action: models.ReconcileNone}
+		if !hasRecord {
+			item.RecordedRefs = -1
+		}
+
+		if opts.Repair {
+			item.Action = models.ReconcileWouldRepair
+			if opts.Apply {
+				item.Action, item.Error = repairBlob(store, hash, recorded, hasRecord, actual, existing)
+			}
+		}
+
+		if len(report.BlobMismatches) < maxReportItems {
+			report.BlobMismatches = append(report.BlobMismatches, item)
+		} else {
+			report.Truncated = true
+		}
+	}
+}
+
+// repairBlob corrige a contagem de um blob, recria o registro ausente ou remove o blob sem referências
+func repairBlob(store storage.BlobStore, hash string, recorded int64, hasRecord bool, actual int64, existing map[string]bool) (models.ReconcileAction, string) {
+	objectName := storage.BlobObjectName(hash)
+
+	switch {
+	case !hasRecord:
+		if !existing[objectName] {
+			return models.ReconcileUnrepairable, "o objeto do blob não existe"
+		}
+		restored, err := db.DbCollections.Blobs.RestoreBlob(&models.Blob{Hash: hash, ObjectName: objectName, RefCount: actual})
+		if err != nil {
+			return models.ReconcileFailed, err.Error()
+		}
+		if !restored {
+			return models.ReconcileChanged, ""
+		}
+		return models.ReconcileRepaired, ""
+
+	case actual == 0:
+		removed, err := db.DbCollections.Blobs.TombstoneUnreferenced(hash, recorded)
+		if err != nil {
+			return models.ReconcileFailed, err.Error()
+		}
+		if removed == nil {
+			return models.ReconcileChanged, ""
+		}
+		if existing[objectName] {
+			if err := store.Delete(objectName); err != nil {
+				return models.ReconcileFailed, err.Error()
+			}
+		}
+		if _, err := db.DbCollections.Blobs.DeleteTombstone(removed); err != nil {
+			return models.ReconcileFailed, err.Error()
+		}
+		return models.ReconcileDeleted, ""
+
+	default:
+		corrected, err := db.DbCollections.Blobs.CorrectRefCount(hash, recorded, actual)
+		if err != nil {
+			return models.ReconcileFailed, err.Error()
+		}
+		if !corrected {
+			return models.ReconcileChanged, ""
+		}
+		return models.ReconcileRepaired, ""
+	}
+}
@@ -0,0 +1,218 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/filetype"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/storage"
+)
+
+// Antes da correção do modelo de versões, a edição de conteúdo gravava o novo objeto no armazenamento,
+// guardava o caminho dele em Document.Content e registrava na versão um caminho inventado
+// (storage_path + ".v" + ObjectID), sem atualizar o ponteiro do documento. MigrateVersionStorage
+// reencontra o objeto de cada uma dessas versões e deixa documento, versões e texto indexado
+// coerentes.
+
+// legacyVersionPath identifica os caminhos inventados pelas edições antigas
+var legacyVersionPath = regexp.MustCompile(db.LegacyVersionPathPattern)
+
+// legacyObjectWindow é a diferença máxima entre a gravação do objeto e o registro da versão
+// para que um objeto antigo seja associado a ela
+const legacyObjectWindow = 2 * time.Minute
+
+// maxIndexedText é o limite do texto mantido no MongoDB, o mesmo aplicado pelos handlers
+const maxIndexedText = 1 << 20
+
+// VersionMigrationResult resume a migração das versões antigas
+type VersionMigrationResult struct {
+	Documents int // Documentos corrigidos
+	Recovered int // Versões associadas ao objeto gravado
+	Lost      int // Versões cujo objeto não existe mais; o caminho é esvaziado
+	Conflicts int // Documentos alterados durante a migração, corrigidos na próxima execução
+	Failures  int
+}
+
+// MigrateVersionStorage corrige os documentos gravados pelo modelo antigo de versões. É idempotente:
+// documentos já corrigidos não são mais selecionados.
+func MigrateVersionStorage(ctx context.Context, store storage.BlobStore) (*VersionMigrationResult, error) {
+	result := &VersionMigrationResult{}
+
+	err := db.DbCollections.Documents.ForEachLegacyVersionDocument(ctx, func(doc *models.Document) error {
+		if err := migrateDocument(ctx, store, doc, result); err != nil {
+			if err == db.ErrRevisionConflict {
+				result.Conflicts++
+				return nil
+			}
+			result.Failures++
+			log.Printf("Erro ao migrar versões do documento %s: %v", doc.ID.Hex(), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("falha ao percorrer documentos: %v", err)
+	}
+
+	if result.Documents > 0 || result.Conflicts > 0 || result.Failures > 0 {
+		log.Printf("Migração de versões: %d documento(s) corrigido(s), %d versão(ões) recuperada(s), %d sem conteúdo, %d conflito(s), %d falha(s)",
+			result.Documents, result.Recovered, result.Lost, result.Conflicts, result.Failures)
+	}
+	return result, nil
+}
+
+// migrateDocument associa cada versão com caminho inventado ao objeto gravado na edição e
+// aponta o documento para a versão mais recente
+func migrateDocument(ctx context.Context, store storage.BlobStore, doc *models.Document, result *VersionMigrationResult) error {
+	versions := make([]models.Version, len(doc.VersionHistory))
+	copy(versions, doc.VersionHistory)
+
+	// Objetos já usados pelo documento não podem ser associados a outra versão
+	claimed := map[string]bool{doc.StoragePath: true}
+	for _, version := range versions {
+		if !legacyVersionPath.MatchString(version.StoragePath) {
+			claimed[version.StoragePath] = true
+		}
+	}
+
+	// A edição mais recente deixou o caminho do objeto no conteúdo em linha
+	inlinePath := ""
+	if looksLikeObjectPath(doc.Content) && !claimed[doc.Content] {
+		inlinePath = doc.Content
+	}
+
+	latest := len(versions) - 1
+	recovered, lost := 0, 0
+	for i := latest; i >= 0; i-- {
+		version := &versions[i]
+		if !legacyVersionPath.MatchString(version.StoragePath) {
+			continue
+		}
+
+		objectName := ""
+		if i == latest && inlinePath != "" && objectExists(store, inlinePath) {
+			objectName = inlinePath
+		} else {
+			found, err := findLegacyObject(ctx, store, doc, version, claimed)
+			if err != nil {
+				return err
+			}
+			objectName = found
+		}
+
+		if objectName == "" {
+			log.Printf("Aviso: conteúdo da versão %d do documento %s não encontrado", version.VersionNumber, doc.ID.Hex())
+			version.StoragePath = ""
+			version.ContentHash = ""
+			lost++
+			continue
+		}
+
+		claimed[objectName] = true
+		version.StoragePath = objectName
+		version.ContentHash, _ = storage.BlobHash(objectName) // A referência ao blob já foi contada na edição
+		recovered++
+	}
+
+	// O documento passa a apontar para a versão mais recente que tem conteúdo
+	currentPath := doc.StoragePath
+	currentHash := doc.ContentHash
+	if latest >= 0 && versions[latest].StoragePath != "" {
+		currentPath = versions[latest].StoragePath
+		currentHash = versions[latest].ContentHash
+	}
+
+	var current *models.VersionContent
+	if currentPath != doc.StoragePath || looksLikeObjectPath(doc.Content) {
+		var err error
+		current, err = readVersionContent(store, doc, currentPath, currentHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := db.DbCollections.Documents.RewriteVersionStorage(doc.ID, doc.Revision, versions, current); err != nil {
+		return err
+	}
+
+	result.Documents++
+	result.Recovered += recovered
+	result.Lost += lost
+	return nil
+}
+
+// findLegacyObject procura, entre os objetos gravados pelo autor da versão na pasta do documento
+// (userID/documentID/), o mais próximo do momento da edição
+func findLegacyObject(ctx context.Context, store storage.BlobStore, doc *models.Document, version *models.Version, claimed map[string]bool) (string, error) {
+	if version.AuthorID == "" {
+		return "", nil
+	}
+
+	prefix := path.Join(version.AuthorID, doc.ID.Hex()) + "/"
+	best := ""
+	bestDistance := legacyObjectWindow + 1
+	err := store.List(ctx, prefix, func(object storage.ObjectInfo) error {
+		if claimed[object.Key] {
+			return nil
+		}
+		distance := object.LastModified.Sub(version.CreatedAt)
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance <= legacyObjectWindow && distance < bestDistance {
+			best, bestDistance = object.Key, distance
+		}
+		return nil
+	})
+	return best, err
+}
+
+// readVersionContent lê o objeto atual para recompor o texto indexado e os metadados do documento
+func readVersionContent(store storage.BlobStore, doc *models.Document, objectName string, hash string) (*models.VersionContent, error) {
+	current := &models.VersionContent{
+		StoragePath: objectName,
+		ContentHash: hash,
+		Size:        doc.Metadata.FileSize,
+		ContentType: doc.Metadata.ContentType,
+	}
+
+	content, err := storage.ReadAll(store, objectName)
+	if err != nil {
+		if storage.IsObjectNotFound(err) {
+			// Sem o objeto, apenas o caminho deixado no conteúdo em linha é descartado
+			return current, nil
+		}
+		return nil, err
+	}
+
+	// As edições gravavam Markdown; o nome só serve para distinguir os formatos de texto
+	fileType, ok := filetype.Detect(objectName+".md", content)
+	current.Size = int64(len(content))
+	if ok {
+		current.ContentType = fileType.ContentType
+		if fileType.Text {
+			if len(content) > maxIndexedText {
+				content = content[:maxIndexedText]
+			}
+			current.Text = strings.ToValidUTF8(string(content), "")
+		}
+	}
+	return current, nil
+}
+
+// objectExists verifica se o objeto está disponível no armazenamento
+func objectExists(store storage.BlobStore, objectName string) bool {
+	_, err := store.Stat(objectName)
+	return err == nil
+}
+
+// looksLikeObjectPath identifica conteúdo em linha que é, na verdade, o caminho de um objeto
+func looksLikeObjectPath(content string) bool {
+	return content != "" && len(content) < 512 && strings.Count(content, "/") >= 2 && !strings.ContainsAny(content, " \n\t")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"gestor-e-docs/document-service/reconcile"
+	"gestor-e-docs/document-service/storage"
+)
+
+// runReconcileCommand executa a reconciliação pela linha de comando e imprime o relatório em JSON.
+// Retorna o código de saída do processo.
+func runReconcileCommand(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "aplicar as correções (sem esta opção, apenas relata)")
+	deleteOrphans := flags.Bool("delete-orphans", false, "remover objetos que nenhum documento referencia")
+	repair := flags.Bool("repair", false, "corrigir referências inexistentes e contagens de blobs")
+	grace := flags.Duration("grace", reconcile.DefaultGracePeriod, "ignorar objetos gravados há menos tempo que isso")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	store, err := storage.GetBlobStore()
+	if err != nil {
+		log.Printf("Falha ao inicializar o armazenamento: %v", err)
+		return 1
+	}
+
+	report, err := reconcile.Run(context.Background(), store, reconcile.Options{
+		Apply:         *apply,
+		DeleteOrphans: *deleteOrphans,
+		Repair:        *repair,
+		GracePeriod:   *grace,
+	})
+	if err != nil {
+		log.Printf("Erro na reconciliação: %v", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("Erro ao imprimir o relatório: %v", err)
+		return 1
+	}
+	return 0
+}
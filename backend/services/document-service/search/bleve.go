@@ -0,0 +1,375 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/lang/pt"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// rebuildBatchSize é o número de documentos gravados por lote na reconstrução
+const rebuildBatchSize = 500
+
+// BleveIndex mantém um índice do Bleve em disco. Título e conteúdo são analisados no idioma
+// detectado em cada documento; os demais campos servem aos filtros, à ordenação e às facetas.
+// O índice guarda só os termos: os documentos exibidos são sempre lidos do MongoDB.
+type BleveIndex struct {
+	mu      sync.RWMutex // Exclusivo apenas para trocar o índice ao fim da reconstrução
+	path    string
+	index   bleve.Index
+	created bool
+
+	// Documentos gravados durante uma reconstrução, reaplicados antes da troca
+	touchedMu sync.Mutex
+	touched   map[string]bool
+}
+
+// OpenBleveIndex abre o índice no diretório informado, criando-o vazio se ainda não existir
+func OpenBleveIndex(path string) (*BleveIndex, error) {
+	index, err := bleve.Open(path)
+	created := false
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("falha ao criar o diretório do índice de busca: %w", err)
+		}
+		index, err = bleve.New(path, newIndexMapping())
+		created = true
+	}
+	if err != nil {
+		return nil, fmt.Errorf("falha ao abrir o índice de busca em %s: %w", path, err)
+	}
+
+	return &BleveIndex{path: path, index: index, created: created}, nil
+}
+
+// IsNew indica se o índice foi criado nesta execução e ainda não recebeu os documentos existentes
+func (b *BleveIndex) IsNew() bool {
+	return b.created
+}
+
+// Engine identifica o mecanismo
+func (b *BleveIndex) Engine() string {
+	return EngineBleve
+}
+
+// Index inclui ou atualiza o documento. Documentos na lixeira são retirados do índice.
+func (b *BleveIndex) Index(doc *models.Document) error {
+	id := doc.ID.Hex()
+	if doc.DeletedAt != nil {
+		return b.Remove(id)
+	}
+	b.touch(id)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.index.Index(id, indexedDocument(doc))
+}
+
+// Remove retira o documento do índice
+func (b *BleveIndex) Remove(id string) error {
+	b.touch(id)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.index.Delete(id)
+}
+
+// touch registra a gravação quando há uma reconstrução em andamento
+func (b *BleveIndex) touch(id string) {
+	b.touchedMu.Lock()
+	defer b.touchedMu.Unlock()
+	if b.touched != nil {
+		b.touched[id] = true
+	}
+}
+
+// Rebuild cria um índice novo ao lado do atual com todos os documentos fora da lixeira e
+// o coloca no lugar do atual. As buscas seguem no índice antigo até a troca, e os documentos
+// gravados nesse meio tempo são lidos de novo do MongoDB antes dela.
+func (b *BleveIndex) Rebuild(ctx context.Context) (int, error) {
+	b.touchedMu.Lock()
+	if b.touched != nil {
+		b.touchedMu.Unlock()
+		return 0, errors.New("reconstrução do índice de busca já em andamento")
+	}
+	b.touched = map[string]bool{}
+	b.touchedMu.Unlock()
+	defer func() {
+		b.touchedMu.Lock()
+		b.touched = nil
+		b.touchedMu.Unlock()
+	}()
+
+	rebuildPath := b.path + ".rebuild"
+	if err := os.RemoveAll(rebuildPath); err != nil {
+		return 0, err
+	}
+	fresh, err := bleve.New(rebuildPath, newIndexMapping())
+	if err != nil {
+		return 0, err
+	}
+	discard := func() {
+		fresh.Close()
+		os.RemoveAll(rebuildPath)
+	}
+
+	indexed := 0
+	batch := fresh.NewBatch()
+	err = db.DbCollections.Documents.ForEachDocument(ctx, func(doc *models.Document) error {
+		if err := batch.Index(doc.ID.Hex(), indexedDocument(doc)); err != nil {
+			return err
+		}
+		indexed++
+		if batch.Size() < rebuildBatchSize {
+			return nil
+		}
+		if err := fresh.Batch(batch); err != nil {
+			return err
+		}
+		batch.Reset()
+		return ctx.Err()
+	})
+	if err == nil && batch.Size() > 0 {
+		err = fresh.Batch(batch)
+	}
+	if err != nil {
+		discard()
+		return 0, err
+	}
+
+	// Bloquear as gravações até a troca para que nenhuma se perca entre os dois índices
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.touchedMu.Lock()
+	touched := b.touched
+	b.touched = map[string]bool{}
+	b.touchedMu.Unlock()
+	for id := range touched {
+		doc, err := db.DbCollections.Documents.GetDocumentByID(id)
+		if err != nil || doc.DeletedAt != nil {
+			err = fresh.Delete(id)
+		} else {
+			err = fresh.Index(id, indexedDocument(doc))
+		}
+		if err != nil {
+			discard()
+			return 0, err
+		}
+	}
+
+	// O Bleve grava no diretório em que foi aberto, então os dois índices são fechados
+	// antes de trocar os diretórios
+	if err := fresh.Close(); err != nil {
+		os.RemoveAll(rebuildPath)
+		return 0, err
+	}
+	if err := b.index.Close(); err != nil {
+		log.Printf("Erro ao fechar o índice de busca anterior: %v", err)
+	}
+	if err := os.RemoveAll(b.path); err != nil {
+		return 0, b.reopen(err)
+	}
+	if err := os.Rename(rebuildPath, b.path); err != nil {
+		return 0, b.reopen(err)
+	}
+	if err := b.reopen(nil); err != nil {
+		return 0, err
+	}
+
+	b.created = false
+	return indexed, nil
+}
+
+// Refresh regrava no índice os documentos alterados ou visualizados a partir de since. É
+// assim que o número de visualizações e as gravações que não atualizam o índice na hora
+// (reservas de edição, links públicos) chegam à ordenação.
+func (b *BleveIndex) Refresh(ctx context.Context, since time.Time) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	refreshed := 0
+	batch := b.index.NewBatch()
+	err := db.DbCollections.Documents.ForEachChangedDocument(ctx, since, func(doc *models.Document) error {
+		id := doc.ID.Hex()
+		b.touch(id)
+		if err := batch.Index(id, indexedDocument(doc)); err != nil {
+			return err
+		}
+		refreshed++
+		if batch.Size() < rebuildBatchSize {
+			return nil
+		}
+		if err := b.index.Batch(batch); err != nil {
+			return err
+		}
+		batch.Reset()
+		return ctx.Err()
+	})
+	if err == nil && batch.Size() > 0 {
+		err = b.index.Batch(batch)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return refreshed, nil
+}
+
+// reopen abre novamente o índice no diretório configurado depois de uma troca, criando um
+// vazio se a troca não deixou nenhum. cause é o erro que interrompeu a troca, se houve.
+func (b *BleveIndex) reopen(cause error) error {
+	index, err := bleve.Open(b.path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(b.path, newIndexMapping())
+	}
+	if err != nil {
+		log.Printf("Erro ao reabrir o índice de busca em %s: %v", b.path, err)
+		index, _ = bleve.NewMemOnly(newIndexMapping())
+		b.created = true
+	}
+	b.index = index
+
+	if cause != nil {
+		return fmt.Errorf("falha ao substituir o índice de busca: %w", cause)
+	}
+	return err
+}
+
+// Close fecha o índice
+func (b *BleveIndex) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.index.Close()
+}
+
+// newIndexMapping descreve os campos indexados. Título e conteúdo existem em uma versão por
+// idioma, e só a do idioma do documento é preenchida.
+func newIndexMapping() mapping.IndexMapping {
+	textField := func(analyzer string) *mapping.FieldMapping {
+		field := bleve.NewTextFieldMapping()
+		field.Analyzer = analyzer
+		field.Store = false
+		field.IncludeInAll = false
+		field.DocValues = false
+		return field
+	}
+	keywordField := bleve.NewKeywordFieldMapping()
+	keywordField.Store = false
+	keywordField.IncludeInAll = false
+	keywordField.IncludeTermVectors = false
+	dateField := bleve.NewDateTimeFieldMapping()
+	dateField.Store = false
+	dateField.IncludeInAll = false
+	numberField := bleve.NewNumericFieldMapping()
+	numberField.Store = false
+	numberField.IncludeInAll = false
+	booleanField := bleve.NewBooleanFieldMapping()
+	booleanField.Store = false
+	booleanField.IncludeInAll = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt(fieldTitle+"_"+db.LanguagePortuguese, textField(pt.AnalyzerName))
+	document.AddFieldMappingsAt(fieldContent+"_"+db.LanguagePortuguese, textField(pt.AnalyzerName))
+	document.AddFieldMappingsAt(fieldTitle+"_"+db.LanguageEnglish, textField(en.AnalyzerName))
+	document.AddFieldMappingsAt(fieldContent+"_"+db.LanguageEnglish, textField(en.AnalyzerName))
+	for _, name := range []string{fieldLanguage, fieldTitleSort, fieldTags, fieldCategories, fieldStatus, fieldAuthor,
+		fieldOwner, fieldReadAccess, fieldWriteAccess, fieldAdminAccess, fieldFolder, fieldCreatedMonth} {
+		document.AddFieldMappingsAt(name, keywordField)
+	}
+	document.AddFieldMappingsAt(fieldCreatedAt, dateField)
+	document.AddFieldMappingsAt(fieldUpdatedAt, dateField)
+	document.AddFieldMappingsAt(fieldViewCount, numberField)
+	document.AddFieldMappingsAt(fieldVersionCount, numberField)
+	document.AddFieldMappingsAt(fieldPublic, booleanField)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+	indexMapping.IndexDynamic = false
+	indexMapping.StoreDynamic = false
+	indexMapping.DocValuesDynamic = false
+	return indexMapping
+}
+
+// Campos do índice do Bleve
+const (
+	fieldTitle        = "title"
+	fieldContent      = "content"
+	fieldLanguage     = "language"
+	fieldTitleSort    = "title_sort"
+	fieldTags         = "tags"
+	fieldCategories   = "categories"
+	fieldStatus       = "status"
+	fieldAuthor       = "author_id"
+	fieldOwner        = "owner_id"
+	fieldReadAccess   = "read_access"
+	fieldWriteAccess  = "write_access"
+	fieldAdminAccess  = "admin_access"
+	fieldFolder       = "folder_id"
+	fieldPublic       = "is_public"
+	fieldCreatedAt    = "created_at"
+	fieldUpdatedAt    = "updated_at"
+	fieldCreatedMonth = "created_month"
+	fieldViewCount    = "view_count"
+	fieldVersionCount = "version_count"
+)
+
+// indexedDocument monta o registro do índice a partir do documento. O número de visualizações
+// é o do momento da gravação: ele não é atualizado a cada leitura, e sim na atualização
+// periódica do índice (Refresh).
+func indexedDocument(doc *models.Document) map[string]interface{} {
+	language := detectLanguage(doc.Title + "\n" + doc.Content)
+	record := map[string]interface{}{
+		fieldTitle + "_" + language:   doc.Title,
+		fieldContent + "_" + language: doc.Content,
+		fieldLanguage:                 language,
+		fieldTitleSort:                doc.Title,
+		fieldStatus:                   string(doc.Status.Normalize()),
+		fieldPublic:                   doc.Permissions.IsPublic,
+		fieldCreatedAt:                doc.CreatedAt,
+		fieldUpdatedAt:                doc.UpdatedAt,
+		fieldCreatedMonth:             doc.CreatedAt.UTC().Format("2006-01"),
+		fieldViewCount:                float64(doc.Metadata.ViewCount),
+		fieldVersionCount:             float64(doc.VersionCount),
+	}
+
+	keywords := map[string][]string{
+		fieldTags:        doc.Tags,
+		fieldCategories:  doc.Categories,
+		fieldReadAccess:  doc.Permissions.ReadAccess,
+		fieldWriteAccess: doc.Permissions.WriteAccess,
+		fieldAdminAccess: doc.Permissions.AdminAccess,
+		fieldAuthor:      {doc.AuthorID},
+		fieldOwner:       {doc.Permissions.OwnerID},
+		fieldFolder:      {doc.FolderID},
+	}
+	for field, values := range keywords {
+		var nonEmpty []string
+		for _, value := range values {
+			if value != "" {
+				nonEmpty = append(nonEmpty, value)
+			}
+		}
+		if len(nonEmpty) > 0 {
+			record[field] = nonEmpty
+		}
+	}
+
+	return record
+}
+
+// bleveDeadline limita cada consulta ao índice, como os timeouts das consultas ao MongoDB
+func bleveDeadline() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 10*time.Second)
+}
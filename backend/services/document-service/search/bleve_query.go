@@ -0,0 +1,451 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/highlight"
+	"gestor-e-docs/document-service/models"
+	"gestor-e-docs/document-service/pagination"
+	"gestor-e-docs/document-service/querylang"
+
+	"github.com/blevesearch/bleve/v2"
+	bsearch "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+const (
+	// facetSize limita os valores retornados por faceta, como na busca do MongoDB
+	facetSize = 20
+	// facetMonths limita os meses retornados na faceta de criação
+	facetMonths = 24
+	// facetMonthCandidates é o número de meses pedido ao Bleve, que ordena as facetas pela
+	// contagem, para escolher entre eles os mais recentes
+	facetMonthCandidates = 240
+	// titleBoost valoriza as ocorrências no título em relação às do conteúdo
+	titleBoost = 2
+)
+
+// bleveSortFields associa os campos de ordenação aceitos aos campos do índice
+var bleveSortFields = map[string]struct {
+	field string
+	kind  bsearch.SortFieldType
+}{
+	models.SortTitle:        {fieldTitleSort, bsearch.SortFieldAsString},
+	models.SortCreatedAt:    {fieldCreatedAt, bsearch.SortFieldAsDate},
+	models.SortUpdatedAt:    {fieldUpdatedAt, bsearch.SortFieldAsDate},
+	models.SortViewCount:    {fieldViewCount, bsearch.SortFieldAsNumber},
+	models.SortVersionCount: {fieldVersionCount, bsearch.SortFieldAsNumber},
+}
+
+// Search executa a busca no índice e lê do MongoDB os documentos encontrados. Ao contrário da
+// busca do MongoDB, a ordenação por relevância também pagina por cursor.
+func (b *BleveIndex) Search(searchQuery *models.DocumentSearchQuery) (*models.DocumentPage, error) {
+	expr, err := querylang.Parse(searchQuery.Query)
+	if err != nil {
+		return nil, err
+	}
+	terms := querylang.Terms(expr)
+	textSearch := len(terms) > 0
+
+	// Configurar paginação
+	searchQuery.NormalizeLimit()
+
+	// Configurar ordenação, com os mesmos padrões da busca do MongoDB
+	keys, err := models.ParseSort(searchQuery.SortBy, searchQuery.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.SortKey{{Field: models.SortUpdatedAt, Descending: true}}
+		if textSearch {
+			keys = []models.SortKey{{Field: models.SortRelevance, Descending: true}}
+		}
+	}
+	for _, key := range keys {
+		if key.Field == models.SortRelevance && !textSearch {
+			return nil, fmt.Errorf("%w: relevance exige termos de busca", models.ErrInvalidSort)
+		}
+	}
+	sortSpec := models.FormatSort(keys)
+	filterHash := pagination.FilterHash(searchQuery.FilterKey()...)
+
+	var position *pagination.Cursor
+	if searchQuery.Cursor != "" {
+		position, err = pagination.Decode(searchQuery.Cursor, sortSpec, filterHash)
+		if err != nil {
+			return nil, err
+		}
+		if len(position.Values) != len(keys) {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+	backward := position != nil && position.Backward
+
+	// Um item a mais indica se há outra página na direção percorrida
+	request := bleve.NewSearchRequestOptions(bleveQuery(searchQuery, expr), searchQuery.Limit+1, 0, false)
+	request.SortByCustom(sortOrder(keys, backward))
+	if position != nil {
+		after := make([]string, 0, len(keys)+1)
+		for _, value := range position.Values {
+			text, ok := value.StringValueOK()
+			if !ok {
+				return nil, pagination.ErrInvalidCursor
+			}
+			after = append(after, text)
+		}
+		request.SetSearchAfter(append(after, position.ID.Hex()))
+	} else {
+		request.From = searchQuery.Offset
+	}
+
+	ctx, cancel := bleveDeadline()
+	defer cancel()
+	b.mu.RLock()
+	result, err := b.index.SearchInContext(ctx, request)
+	b.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	hits := result.Hits
+	hasMore := len(hits) > searchQuery.Limit
+	if hasMore {
+		hits = hits[:searchQuery.Limit]
+	}
+	if backward {
+		for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+			hits[i], hits[j] = hits[j], hits[i]
+		}
+	}
+
+	// Ler os documentos do MongoDB, que confere de novo a lixeira e as permissões
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, hit := range hits {
+		if id, err := primitive.ObjectIDFromHex(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	documents, err := db.DbCollections.Documents.FindReadableDocuments(ids, searchQuery.ViewerID, searchQuery.ReadFolders, len(terms) > 0)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []models.DocumentListItem{}
+	for _, hit := range hits {
+		id, _ := primitive.ObjectIDFromHex(hit.ID)
+		doc, found := documents[id]
+		if !found {
+			// O índice ainda não recebeu a remoção ou a mudança de permissões
+			continue
+		}
+		item := doc.ListItem(searchQuery.ViewerID)
+		if textSearch {
+			item.Score = hit.Score
+		}
+		item.Highlights = highlight.Excerpts(doc.Content, terms)
+		results = append(results, item)
+	}
+
+	page := &models.DocumentPage{Documents: results, Total: int64(result.Total)}
+	if len(hits) == 0 {
+		return page, nil
+	}
+
+	// Os cursores saem dos resultados do índice, mesmo que algum não tenha sido exibido
+	moreAfter := hasMore || backward
+	moreBefore := (backward && hasMore) || (!backward && (position != nil || searchQuery.Offset > 0))
+	if moreAfter {
+		page.NextCursor, err = hitCursor(sortSpec, filterHash, keys, hits[len(hits)-1], false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if moreBefore {
+		page.PrevCursor, err = hitCursor(sortSpec, filterHash, keys, hits[0], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// Facets conta os documentos que atendem à consulta pelos campos de palavra-chave do índice
+func (b *BleveIndex) Facets(searchQuery *models.DocumentSearchQuery) (*models.SearchFacets, error) {
+	expr, err := querylang.Parse(searchQuery.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	request := bleve.NewSearchRequestOptions(bleveQuery(searchQuery, expr), 0, 0, false)
+	request.AddFacet("tags", bleve.NewFacetRequest(fieldTags, facetSize))
+	request.AddFacet("categories", bleve.NewFacetRequest(fieldCategories, facetSize))
+	request.AddFacet("status", bleve.NewFacetRequest(fieldStatus, facetSize))
+	request.AddFacet("author", bleve.NewFacetRequest(fieldAuthor, facetSize))
+	request.AddFacet("created_month", bleve.NewFacetRequest(fieldCreatedMonth, facetMonthCandidates))
+
+	ctx, cancel := bleveDeadline()
+	defer cancel()
+	b.mu.RLock()
+	result, err := b.index.SearchInContext(ctx, request)
+	b.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	months := facetCounts(result.Facets["created_month"])
+	sort.Slice(months, func(i, j int) bool { return months[i].Value > months[j].Value })
+	if len(months) > facetMonths {
+		months = months[:facetMonths]
+	}
+
+	return &models.SearchFacets{
+		Tags:         facetCounts(result.Facets["tags"]),
+		Categories:   facetCounts(result.Facets["categories"]),
+		Status:       facetCounts(result.Facets["status"]),
+		Authors:      facetCounts(result.Facets["author"]),
+		CreatedMonth: months,
+	}, nil
+}
+
+// facetCounts converte uma faceta do Bleve, do valor mais frequente ao menos frequente
+func facetCounts(facet *bsearch.FacetResult) []models.FacetCount {
+	counts := []models.FacetCount{}
+	if facet == nil {
+		return counts
+	}
+	for _, term := range facet.Terms.Terms() {
+		counts = append(counts, models.FacetCount{Value: term.Term, Count: int64(term.Count)})
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	return counts
+}
+
+// sortOrder monta a ordenação do Bleve, desempatando pelo ID no sentido do último campo.
+// Documentos sem o campo ordenado ficam antes na ordem crescente e depois na decrescente,
+// como no MongoDB. reverse inverte todos os sentidos.
+func sortOrder(keys []models.SortKey, reverse bool) bsearch.SortOrder {
+	order := bsearch.SortOrder{}
+	descending := false
+	for _, key := range keys {
+		descending = key.Descending != reverse
+		if key.Field == models.SortRelevance {
+			order = append(order, &bsearch.SortScore{Desc: descending})
+			continue
+		}
+		missing := bsearch.SortFieldMissingFirst
+		if descending {
+			missing = bsearch.SortFieldMissingLast
+		}
+		field := bleveSortFields[key.Field]
+		order = append(order, &bsearch.SortField{Field: field.field, Type: field.kind, Desc: descending, Missing: missing})
+	}
+	return append(order, &bsearch.SortDocID{Desc: descending})
+}
+
+// hitCursor gera o cursor de um resultado a partir dos valores de ordenação já decodificados,
+// que são os aceitos em SearchAfter. O último valor é o ID, guardado à parte no cursor.
+func hitCursor(sortSpec string, filterHash string, keys []models.SortKey, hit *bsearch.DocumentMatch, backward bool) (string, error) {
+	id, err := primitive.ObjectIDFromHex(hit.ID)
+	if err != nil {
+		return "", err
+	}
+	values := make([]bson.RawValue, len(keys))
+	for i := range keys {
+		values[i] = bson.RawValue{Type: bsontype.String, Value: bsoncore.AppendString(nil, hit.DecodedSort[i])}
+	}
+	return pagination.Encode(pagination.Cursor{Sort: sortSpec, Filter: filterHash, Values: values, ID: id, Backward: backward})
+}
+
+// bleveQuery monta a consulta do Bleve a partir dos critérios de pesquisa e da consulta já
+// interpretada, com as mesmas regras do filtro da busca do MongoDB
+func bleveQuery(searchQuery *models.DocumentSearchQuery, expr querylang.Expr) query.Query {
+	var conditions []query.Query
+	if expr != nil {
+		conditions = append(conditions, exprQuery(expr, searchQuery.ViewerID))
+	}
+
+	// Aplicar filtros de busca
+	if len(searchQuery.Tags) > 0 {
+		conditions = append(conditions, anyTerm(fieldTags, searchQuery.Tags))
+	}
+	if len(searchQuery.Categories) > 0 {
+		conditions = append(conditions, anyTerm(fieldCategories, searchQuery.Categories))
+	}
+	if searchQuery.AuthorID != "" {
+		conditions = append(conditions, term(fieldAuthor, searchQuery.AuthorID))
+	}
+	if searchQuery.Status != "" {
+		conditions = append(conditions, term(fieldStatus, searchQuery.Status))
+	}
+
+	// Filtros de data
+	dateFrom, errFrom := time.Parse(time.RFC3339, searchQuery.DateFrom)
+	dateTo, errTo := time.Parse(time.RFC3339, searchQuery.DateTo)
+	if errFrom == nil || errTo == nil {
+		inclusive := true
+		dateQuery := bleve.NewDateRangeInclusiveQuery(dateFrom, dateTo, &inclusive, &inclusive)
+		dateQuery.SetField(fieldCreatedAt)
+		conditions = append(conditions, dateQuery)
+	}
+	if len(searchQuery.FolderIDs) > 0 {
+		conditions = append(conditions, anyTerm(fieldFolder, searchQuery.FolderIDs))
+	}
+
+	// Restringir aos documentos que quem consulta pode ler
+	if searchQuery.ViewerID != "" {
+		conditions = append(conditions, scopeQuery(searchQuery.ViewerID, searchQuery.Scope, searchQuery.ReadFolders))
+	}
+
+	if len(conditions) == 0 {
+		return bleve.NewMatchAllQuery()
+	}
+	return bleve.NewConjunctionQuery(conditions...)
+}
+
+// exprQuery traduz um nó da consulta para o Bleve
+func exprQuery(expr querylang.Expr, viewerID string) query.Query {
+	switch e := expr.(type) {
+	case *querylang.And:
+		return bleve.NewConjunctionQuery(exprQueries(e.Items, viewerID)...)
+
+	case *querylang.Or:
+		return bleve.NewDisjunctionQuery(exprQueries(e.Items, viewerID)...)
+
+	case *querylang.Not:
+		return not(exprQuery(e.Item, viewerID))
+
+	case *querylang.Text:
+		return textQuery(e.Value, e.Phrase, fieldTitle, fieldContent)
+
+	case *querylang.Match:
+		switch e.Field {
+		case querylang.FieldTag:
+			return term(fieldTags, e.Value)
+		case querylang.FieldCategory:
+			return term(fieldCategories, e.Value)
+		case querylang.FieldTitle:
+			return textQuery(e.Value, true, fieldTitle)
+		case querylang.FieldAuthor:
+			if e.Value == querylang.AuthorMe {
+				return term(fieldAuthor, viewerID)
+			}
+			return term(fieldAuthor, e.Value)
+		case querylang.FieldStatus:
+			// O índice guarda o status normalizado, então rascunhos sem status também atendem
+			return term(fieldStatus, e.Value)
+		}
+
+	case *querylang.DateRange:
+		field := fieldCreatedAt
+		if e.Field == querylang.FieldUpdated {
+			field = fieldUpdatedAt
+		}
+		inclusive, exclusive := true, false
+		dateQuery := bleve.NewDateRangeInclusiveQuery(e.From, e.To, &inclusive, &exclusive)
+		dateQuery.SetField(field)
+		return dateQuery
+	}
+
+	// Parse só produz os nós tratados acima
+	return bleve.NewMatchNoneQuery()
+}
+
+// exprQueries traduz uma lista de itens da consulta
+func exprQueries(items []querylang.Expr, viewerID string) []query.Query {
+	queries := make([]query.Query, 0, len(items))
+	for _, item := range items {
+		queries = append(queries, exprQuery(item, viewerID))
+	}
+	return queries
+}
+
+// textQuery procura o termo ou a frase nos campos informados, em cada idioma do índice.
+// Cada versão do campo é analisada no próprio idioma, e o título vale mais que o conteúdo.
+func textQuery(value string, phrase bool, fields ...string) query.Query {
+	var queries []query.Query
+	for _, language := range []string{db.LanguagePortuguese, db.LanguageEnglish} {
+		for _, field := range fields {
+			boost := 1.0
+			if field == fieldTitle {
+				boost = titleBoost
+			}
+			if phrase {
+				match := bleve.NewMatchPhraseQuery(value)
+				match.SetField(field + "_" + language)
+				match.SetBoost(boost)
+				queries = append(queries, match)
+				continue
+			}
+			match := bleve.NewMatchQuery(value)
+			match.SetField(field + "_" + language)
+			match.SetOperator(query.MatchQueryOperatorAnd)
+			match.SetBoost(boost)
+			queries = append(queries, match)
+		}
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// scopeQuery monta a consulta de documentos legíveis pelo usuário dentro do escopo informado,
+// como scopeFilter na busca do MongoDB
+func scopeQuery(userID string, scope string, readFolders []string) query.Query {
+	owned := term(fieldOwner, userID)
+	sharedClauses := []query.Query{
+		term(fieldReadAccess, userID),
+		term(fieldWriteAccess, userID),
+		term(fieldAdminAccess, userID),
+	}
+	if len(readFolders) > 0 {
+		sharedClauses = append(sharedClauses, anyTerm(fieldFolder, readFolders))
+	}
+	public := bleve.NewBoolFieldQuery(true)
+	public.SetField(fieldPublic)
+
+	switch scope {
+	case models.ScopeOwned:
+		return owned
+	case models.ScopeShared:
+		return bleve.NewConjunctionQuery(bleve.NewDisjunctionQuery(sharedClauses...), not(term(fieldOwner, userID)))
+	case models.ScopePublic:
+		return public
+	default:
+		return bleve.NewDisjunctionQuery(append([]query.Query{owned, public}, sharedClauses...)...)
+	}
+}
+
+// term compara um campo de palavra-chave com o valor exato
+func term(field, value string) query.Query {
+	termQuery := bleve.NewTermQuery(value)
+	termQuery.SetField(field)
+	return termQuery
+}
+
+// anyTerm exige que o campo tenha ao menos um dos valores
+func anyTerm(field string, values []string) query.Query {
+	queries := make([]query.Query, 0, len(values))
+	for _, value := range values {
+		queries = append(queries, term(field, value))
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// not exige que a consulta não seja atendida
+func not(q query.Query) query.Query {
+	boolean := bleve.NewBooleanQuery()
+	boolean.AddMust(bleve.NewMatchAllQuery())
+	boolean.AddMustNot(q)
+	return boolean
+}
@@ -0,0 +1,78 @@
+// Package search reúne os mecanismos de busca da listagem de documentos. O MongoDB, com o
+// índice textual da própria coleção, é o padrão; o Bleve mantém um índice em disco com
+// análise por idioma (português e inglês) e precisa ser atualizado a cada gravação.
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"gestor-e-docs/document-service/models"
+)
+
+// Mecanismos aceitos em SEARCH_ENGINE
+const (
+	EngineMongo = "mongo"
+	EngineBleve = "bleve"
+)
+
+// SearchIndex é o mecanismo de busca usado na listagem de documentos. Os resultados
+// seguem sempre os documentos gravados no MongoDB, que é a fonte dos dados.
+type SearchIndex interface {
+	// Engine identifica o mecanismo, como em SEARCH_ENGINE
+	Engine() string
+	// Search retorna a página de documentos que atendem à consulta
+	Search(query *models.DocumentSearchQuery) (*models.DocumentPage, error)
+	// Facets conta os documentos que atendem à consulta por tag, categoria, status, autor e mês
+	Facets(query *models.DocumentSearchQuery) (*models.SearchFacets, error)
+	// Index inclui ou atualiza um documento no índice
+	Index(doc *models.Document) error
+	// Remove retira um documento do índice; remover um documento ausente não é erro
+	Remove(id string) error
+	// Rebuild reconstrói o índice a partir do MongoDB e retorna quantos documentos indexou
+	Rebuild(ctx context.Context) (int, error)
+	// Close libera os recursos do índice
+	Close() error
+}
+
+var (
+	searchIndex     SearchIndex
+	searchIndexLock sync.Mutex
+)
+
+// GetSearchIndex retorna o mecanismo configurado por SEARCH_ENGINE: mongo (padrão) ou
+// bleve (índice em SEARCH_INDEX_PATH)
+func GetSearchIndex() (SearchIndex, error) {
+	searchIndexLock.Lock()
+	defer searchIndexLock.Unlock()
+
+	if searchIndex != nil {
+		return searchIndex, nil
+	}
+
+	engine := strings.ToLower(os.Getenv("SEARCH_ENGINE"))
+	switch engine {
+	case "", EngineMongo:
+		searchIndex = NewMongoIndex()
+	case EngineBleve:
+		path := os.Getenv("SEARCH_INDEX_PATH")
+		if path == "" {
+			path = "data/search.bleve"
+			log.Println("Usando diretório padrão do índice de busca:", path)
+		}
+		index, err := OpenBleveIndex(path)
+		if err != nil {
+			return nil, err
+		}
+		searchIndex = index
+	default:
+		return nil, fmt.Errorf("mecanismo de busca desconhecido: %s", engine)
+	}
+
+	log.Printf("Mecanismo de busca: %s", searchIndex.Engine())
+	return searchIndex, nil
+}
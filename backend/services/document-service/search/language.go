@@ -0,0 +1,56 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"gestor-e-docs/document-service/db"
+)
+
+// languageSampleSize limita o trecho do documento usado para detectar o idioma
+const languageSampleSize = 20000
+
+// Palavras funcionais frequentes em cada idioma e raras no outro
+var languageMarkers = map[string]map[string]bool{
+	db.LanguagePortuguese: wordSet("de", "da", "do", "das", "dos", "em", "no", "na", "nos", "nas", "um", "uma",
+		"que", "não", "para", "com", "por", "são", "é", "ao", "aos", "pelo", "pela", "como", "mais", "também"),
+	db.LanguageEnglish: wordSet("the", "of", "and", "to", "in", "is", "are", "was", "for", "with", "that", "this",
+		"on", "by", "from", "it", "be", "not", "an", "which", "also", "have", "has"),
+}
+
+// detectLanguage escolhe o idioma do texto contando as palavras funcionais de cada idioma.
+// Sem diferença entre as contagens, vale o idioma de SEARCH_LANGUAGE.
+func detectLanguage(text string) string {
+	if len(text) > languageSampleSize {
+		text = text[:languageSampleSize]
+	}
+
+	counts := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		for language, markers := range languageMarkers {
+			if markers[word] {
+				counts[language]++
+			}
+		}
+	}
+
+	switch {
+	case counts[db.LanguagePortuguese] > counts[db.LanguageEnglish]:
+		return db.LanguagePortuguese
+	case counts[db.LanguageEnglish] > counts[db.LanguagePortuguese]:
+		return db.LanguageEnglish
+	default:
+		return db.SearchLanguage()
+	}
+}
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
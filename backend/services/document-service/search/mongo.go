@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+
+	"gestor-e-docs/document-service/db"
+	"gestor-e-docs/document-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MongoIndex busca com o índice textual da coleção de documentos. O MongoDB mantém o
+// índice a cada gravação, então Index e Remove não têm efeito.
+type MongoIndex struct{}
+
+// NewMongoIndex cria o mecanismo de busca do MongoDB
+func NewMongoIndex() *MongoIndex {
+	return &MongoIndex{}
+}
+
+// Engine identifica o mecanismo
+func (m *MongoIndex) Engine() string {
+	return EngineMongo
+}
+
+// Search executa a busca na coleção de documentos
+func (m *MongoIndex) Search(query *models.DocumentSearchQuery) (*models.DocumentPage, error) {
+	return db.DbCollections.Documents.SearchDocuments(query)
+}
+
+// Facets calcula as facetas com uma agregação sobre o mesmo filtro da busca
+func (m *MongoIndex) Facets(query *models.DocumentSearchQuery) (*models.SearchFacets, error) {
+	return db.DbCollections.Documents.SearchFacets(query)
+}
+
+// Index não tem efeito: o índice textual acompanha a coleção
+func (m *MongoIndex) Index(doc *models.Document) error {
+	return nil
+}
+
+// Remove não tem efeito: o índice textual acompanha a coleção
+func (m *MongoIndex) Remove(id string) error {
+	return nil
+}
+
+// Rebuild recria o índice textual no idioma de SEARCH_LANGUAGE e retorna o número de
+// documentos fora da lixeira cobertos por ele
+func (m *MongoIndex) Rebuild(ctx context.Context) (int, error) {
+	if err := db.DbCollections.Documents.RebuildTextIndex(ctx); err != nil {
+		return 0, err
+	}
+
+	count, err := db.DbCollections.Documents.CountDocuments(bson.M{"deleted_at": nil})
+	return int(count), err
+}
+
+// Close não tem efeito: a conexão pertence ao pacote db
+func (m *MongoIndex) Close() error {
+	return nil
+}
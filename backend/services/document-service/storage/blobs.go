@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// BlobObjectName retorna o caminho do objeto de um blob a partir do SHA-256 do conteúdo.
+// Os dois primeiros dígitos formam um prefixo para distribuir os objetos.
+func BlobObjectName(hash string) string {
+	return "blobs/" + hash[:2] + "/" + hash
+}
+
+// HashContent calcula o SHA-256 (em hexadecimal) e o tamanho do conteúdo lido
+func HashContent(reader io.Reader) (string, int64, error) {
+	hash := sha256.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return "", 0, fmt.Errorf("falha ao calcular o hash do conteúdo: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// PutBlob grava o conteúdo de um blob em streaming. Como o nome é derivado do próprio
+// conteúdo, gravar de novo o mesmo blob é inofensivo. Use size -1 quando o tamanho não for conhecido.
+func PutBlob(store BlobStore, hash string, reader io.Reader, size int64, contentType string) error {
+	err := store.Put(BlobObjectName(hash), reader, size, PutOptions{ContentType: contentType, SHA256: hash})
+	if err != nil {
+		return fmt.Errorf("falha ao gravar blob: %v", err)
+	}
+	return nil
+}
+
+// CopyToBlob copia, dentro do próprio armazenamento, um objeto já gravado para o caminho
+// do blob correspondente ao seu conteúdo
+func CopyToBlob(store BlobStore, objectName string, hash string) error {
+	if err := store.Copy(objectName, BlobObjectName(hash)); err != nil {
+		return fmt.Errorf("falha ao copiar objeto para o blob: %v", err)
+	}
+	return nil
+}
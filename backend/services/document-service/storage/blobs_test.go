@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestBlobHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectName string
+		want       string
+		ok         bool
+	}{
+		{"caminho do blob", BlobObjectName(emptyHash), emptyHash, true},
+		{"objeto por documento", "usuario/documento/v1.md", "", false},
+		{"hash curto", "blobs/e3/e3b0c442", "", false},
+		{"hash em maiúsculas", "blobs/E3/" + strings.ToUpper(emptyHash), "", false},
+		{"fora do prefixo", "outros/e3/" + emptyHash, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := BlobHash(tt.objectName)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("BlobHash(%q) = %q, %v; esperado %q, %v", tt.objectName, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPutBlob(t *testing.T) {
+	store := NewMemoryStore()
+	content := []byte("conteúdo da versão")
+	hash, size, err := HashContent(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("HashContent(): %v", err)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("HashContent() tamanho = %d, esperado %d", size, len(content))
+	}
+
+	// Gravar o mesmo blob duas vezes é inofensivo
+	for i := 0; i < 2; i++ {
+		if err := PutBlob(store, hash, bytes.NewReader(content), -1, "text/plain"); err != nil {
+			t.Fatalf("PutBlob(): %v", err)
+		}
+	}
+
+	got, err := ReadAll(store, BlobObjectName(hash))
+	if err != nil {
+		t.Fatalf("ReadAll(): %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("ReadAll() = %q, esperado %q", got, content)
+	}
+
+	// Uma cópia para o caminho do blob mantém o conteúdo e o checksum verificável
+	if err := store.Put("usuario/documento/v2.txt", bytes.NewReader(content), int64(len(content)), PutOptions{}); err != nil {
+		t.Fatalf("Put(): %v", err)
+	}
+	store.Delete(BlobObjectName(hash))
+	if err := CopyToBlob(store, "usuario/documento/v2.txt", hash); err != nil {
+		t.Fatalf("CopyToBlob(): %v", err)
+	}
+	if _, err := VerifyObject(store, BlobObjectName(hash), hash); err != nil {
+		t.Errorf("VerifyObject() após a cópia: %v", err)
+	}
+}
+
+func TestVerifyObjectMismatch(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Put(BlobObjectName(emptyHash), strings.NewReader("não está vazio"), -1, PutOptions{}); err != nil {
+		t.Fatalf("Put(): %v", err)
+	}
+
+	actual, err := VerifyObject(store, BlobObjectName(emptyHash), emptyHash)
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) || !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("VerifyObject() = %v, esperado *IntegrityError", err)
+	}
+	if integrityErr.Expected != emptyHash || integrityErr.Actual != actual || actual == emptyHash {
+		t.Errorf("VerifyObject() = %+v, hash calculado %q", integrityErr, actual)
+	}
+
+	if _, err := VerifyObject(store, BlobObjectName(strings.Repeat("0", 64)), emptyHash); !IsObjectNotFound(err) {
+		t.Errorf("VerifyObject() de objeto inexistente = %v, esperado ErrObjectNotFound", err)
+	}
+}
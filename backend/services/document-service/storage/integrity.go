@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrIntegrity indica que o conteúdo lido não confere com o checksum registrado
+var ErrIntegrity = errors.New("o conteúdo não confere com o checksum registrado")
+
+// IntegrityError detalha uma falha de integridade em um objeto
+type IntegrityError struct {
+	ObjectName string
+	Expected   string
+	Actual     string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("objeto %s corrompido: SHA-256 esperado %s, obtido %s", e.ObjectName, e.Expected, e.Actual)
+}
+
+// Unwrap permite identificar a falha com errors.Is(err, ErrIntegrity)
+func (e *IntegrityError) Unwrap() error {
+	return ErrIntegrity
+}
+
+// IsObjectNotFound indica se o erro corresponde a um objeto inexistente no armazenamento
+func IsObjectNotFound(err error) bool {
+	return errors.Is(err, ErrObjectNotFound)
+}
+
+// BlobHash extrai o SHA-256 do caminho de um blob; retorna false para outros objetos
+func BlobHash(objectName string) (string, bool) {
+	if !strings.HasPrefix(objectName, "blobs/") {
+		return "", false
+	}
+	hash := objectName[strings.LastIndex(objectName, "/")+1:]
+	if len(hash) != 64 || strings.Trim(hash, "0123456789abcdef") != "" {
+		return "", false
+	}
+	return hash, true
+}
+
+// VerifyObject relê o objeto inteiro e confere o SHA-256 com o esperado. Retorna o hash
+// calculado e um *IntegrityError se não conferir.
+func VerifyObject(store BlobStore, objectName string, expected string) (string, error) {
+	obj, _, err := store.Get(objectName)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	actual, _, err := HashContent(obj)
+	if err != nil {
+		return "", err
+	}
+	if actual != expected {
+		return actual, &IntegrityError{ObjectName: objectName, Expected: expected, Actual: actual}
+	}
+	return actual, nil
+}
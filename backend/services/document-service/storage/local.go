@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LocalStore guarda os objetos em um diretório local. Os objetos ficam em objects/, com o
+// nome do objeto como caminho relativo; tipo e checksum ficam em meta/ e os envios em
+// partes, em multipart/.
+type LocalStore struct {
+	root string
+}
+
+// localMetadata é gravado junto de cada objeto e de cada envio em partes
+type localMetadata struct {
+	ObjectName  string `json:"object_name,omitempty"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256,omitempty"`
+}
+
+// NewLocalStore prepara o diretório do armazenamento local
+func NewLocalStore(root string) (*LocalStore, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("caminho do armazenamento local inválido: %v", err)
+	}
+	for _, dir := range []string{"objects", "meta", "multipart"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o750); err != nil {
+			return nil, fmt.Errorf("falha ao preparar o armazenamento local: %v", err)
+		}
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// Put grava o objeto em um arquivo temporário e o move para o lugar definitivo,
+// para que leituras concorrentes nunca vejam um objeto incompleto
+func (s *LocalStore) Put(objectName string, reader io.Reader, size int64, opts PutOptions) error {
+	objectPath, err := s.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		reader = io.LimitReader(reader, size)
+	}
+
+	if err := s.writeFile(objectPath, reader, size); err != nil {
+		return fmt.Errorf("falha ao gravar objeto: %v", err)
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+	return s.writeMetadata(objectName, localMetadata{ContentType: opts.ContentType, SHA256: opts.SHA256})
+}
+
+// Get abre o arquivo do objeto, que já permite reposicionamento e leitura de trechos
+func (s *LocalStore) Get(objectName string) (ObjectReader, *ObjectInfo, error) {
+	objectPath, err := s.objectPath(objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, nil, localError("falha ao abrir objeto", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, localError("falha ao abrir objeto", err)
+	}
+
+	return file, s.objectInfo(objectName, stat), nil
+}
+
+// Stat retorna as informações do objeto
+func (s *LocalStore) Stat(objectName string) (*ObjectInfo, error) {
+	objectPath, err := s.objectPath(objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil {
+		return nil, localError("falha ao consultar objeto", err)
+	}
+	return s.objectInfo(objectName, stat), nil
+}
+
+// Delete remove o objeto, seus metadados e os diretórios que ficarem vazios
+func (s *LocalStore) Delete(objectName string) error {
+	objectPath, err := s.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(objectPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("falha ao remover objeto: %v", err)
+	}
+	os.Remove(s.metadataPath(objectName))
+
+	s.removeEmptyParents(filepath.Dir(objectPath), filepath.Join(s.root, "objects"))
+	s.removeEmptyParents(filepath.Dir(s.metadataPath(objectName)), filepath.Join(s.root, "meta"))
+	return nil
+}
+
+// List percorre os arquivos de objects/ a partir do diretório mais profundo que contém o prefixo
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	objectsRoot := filepath.Join(s.root, "objects")
+	start := objectsRoot
+	if dir := path.Dir(prefix + "x"); dir != "." {
+		start = filepath.Join(objectsRoot, filepath.FromSlash(dir))
+	}
+
+	err := filepath.WalkDir(start, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(objectsRoot, filePath)
+		if err != nil {
+			return err
+		}
+		objectName := filepath.ToSlash(relative)
+		if !strings.HasPrefix(objectName, prefix) {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // Removido durante a listagem
+			}
+			return err
+		}
+		return fn(*s.objectInfo(objectName, stat))
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("erro ao listar objetos: %w", err)
+	}
+	return err
+}
+
+// Copy copia o conteúdo e o tipo do objeto. O checksum só é mantido quando o destino é o
+// blob do mesmo conteúdo, já que nele o checksum vem do próprio nome.
+func (s *LocalStore) Copy(srcName string, dstName string) error {
+	src, info, err := s.Get(srcName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return s.Put(dstName, src, info.Size, PutOptions{ContentType: info.ContentType})
+}
+
+// PresignGet não é suportado: o diretório local não é acessível pelos clientes
+func (s *LocalStore) PresignGet(objectName string, expiry time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// PresignPost não é suportado: o diretório local não é acessível pelos clientes
+func (s *LocalStore) PresignPost(objectName string, size int64, expiry time.Duration) (*PresignedPost, error) {
+	return nil, ErrPresignUnsupported
+}
+
+// NewMultipartUpload cria o diretório que recebe as partes do envio
+func (s *LocalStore) NewMultipartUpload(objectName string, contentType string) (string, error) {
+	if _, err := s.objectPath(objectName); err != nil {
+		return "", err
+	}
+
+	uploadID := uuid.New().String()
+	dir := filepath.Join(s.root, "multipart", uploadID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("falha ao iniciar upload multipart: %v", err)
+	}
+
+	info, _ := json.Marshal(localMetadata{ObjectName: objectName, ContentType: contentType})
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), info, 0o640); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("falha ao iniciar upload multipart: %v", err)
+	}
+	return uploadID, nil
+}
+
+// PutPart grava uma parte do envio em um arquivo próprio
+func (s *LocalStore) PutPart(objectName string, uploadID string, number int, content []byte) (UploadedPart, error) {
+	dir, _, err := s.multipartUpload(objectName, uploadID)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+
+	partPath := filepath.Join(dir, strconv.Itoa(number))
+	if err := s.writeFile(partPath, bytes.NewReader(content), int64(len(content))); err != nil {
+		return UploadedPart{}, fmt.Errorf("falha ao enviar parte %d: %v", number, err)
+	}
+
+	sum := md5.Sum(content)
+	return UploadedPart{Number: number, ETag: hex.EncodeToString(sum[:]), Size: int64(len(content))}, nil
+}
+
+// CompleteMultipartUpload junta as partes, na ordem da numeração, no objeto final
+func (s *LocalStore) CompleteMultipartUpload(objectName string, uploadID string, parts []UploadedPart) error {
+	dir, upload, err := s.multipartUpload(objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	sorted := make([]UploadedPart, len(parts))
+	copy(sorted, parts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+
+	readers := make([]io.Reader, 0, len(sorted))
+	for _, part := range sorted {
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(part.Number)))
+		if err != nil {
+			return fmt.Errorf("falha ao concluir upload multipart: parte %d ausente", part.Number)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	if err := s.Put(objectName, io.MultiReader(readers...), -1, PutOptions{ContentType: upload.ContentType}); err != nil {
+		return fmt.Errorf("falha ao concluir upload multipart: %v", err)
+	}
+	return os.RemoveAll(dir)
+}
+
+// AbortMultipartUpload descarta as partes já enviadas
+func (s *LocalStore) AbortMultipartUpload(objectName string, uploadID string) error {
+	dir, _, err := s.multipartUpload(objectName, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("falha ao cancelar upload multipart: %v", err)
+	}
+	return nil
+}
+
+// Funções auxiliares do armazenamento local
+
+// objectPath converte o nome do objeto em caminho dentro de objects/, recusando nomes
+// que escapariam do diretório
+func (s *LocalStore) objectPath(objectName string) (string, error) {
+	clean := path.Clean("/" + objectName)
+	if objectName == "" || clean != "/"+objectName {
+		return "", fmt.Errorf("nome de objeto inválido: %q", objectName)
+	}
+	return filepath.Join(s.root, "objects", filepath.FromSlash(objectName)), nil
+}
+
+// metadataPath retorna o arquivo de metadados de um objeto
+func (s *LocalStore) metadataPath(objectName string) string {
+	return filepath.Join(s.root, "meta", filepath.FromSlash(objectName)+".json")
+}
+
+// writeFile grava o conteúdo em um arquivo temporário no mesmo diretório e o renomeia.
+// Com size positivo ou zero, o arquivo só é gravado se tiver exatamente esse tamanho.
+func (s *LocalStore) writeFile(filePath string, reader io.Reader, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("%d bytes recebidos, %d esperados", written, size)
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// writeMetadata grava o tipo e o checksum do objeto
+func (s *LocalStore) writeMetadata(objectName string, metadata localMetadata) error {
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if err := s.writeFile(s.metadataPath(objectName), bytes.NewReader(content), int64(len(content))); err != nil {
+		return fmt.Errorf("falha ao gravar metadados do objeto: %v", err)
+	}
+	return nil
+}
+
+// objectInfo monta as informações do objeto a partir do arquivo e dos metadados
+func (s *LocalStore) objectInfo(objectName string, stat fs.FileInfo) *ObjectInfo {
+	var metadata localMetadata
+	if content, err := os.ReadFile(s.metadataPath(objectName)); err == nil {
+		json.Unmarshal(content, &metadata)
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = "application/octet-stream"
+	}
+
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         stat.Size(),
+		ContentType:  metadata.ContentType,
+		ETag:         strconv.FormatInt(stat.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(stat.Size(), 16),
+		LastModified: stat.ModTime(),
+		SHA256:       recordedChecksum(objectName, metadata.SHA256),
+	}
+}
+
+// multipartUpload localiza um envio em partes e confere se ele pertence ao objeto informado
+func (s *LocalStore) multipartUpload(objectName string, uploadID string) (string, *localMetadata, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", nil, fmt.Errorf("upload multipart inválido: %s", uploadID)
+	}
+
+	dir := filepath.Join(s.root, "multipart", uploadID)
+	content, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", nil, fmt.Errorf("upload multipart %s não encontrado", uploadID)
+	}
+
+	var upload localMetadata
+	if err := json.Unmarshal(content, &upload); err != nil || upload.ObjectName != objectName {
+		return "", nil, fmt.Errorf("upload multipart %s não pertence ao objeto %s", uploadID, objectName)
+	}
+	return dir, &upload, nil
+}
+
+// removeEmptyParents remove os diretórios vazios entre dir e stop (exclusive)
+func (s *LocalStore) removeEmptyParents(dir string, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// localError identifica arquivos inexistentes, que passam a satisfazer errors.Is(err, ErrObjectNotFound)
+func localError(message string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", message, ErrObjectNotFound)
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
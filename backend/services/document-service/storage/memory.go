@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore guarda os objetos em memória. Serve a testes de integração e a execuções
+// locais sem MinIO; o conteúdo é perdido quando o processo termina.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	objects   map[string]*memoryObject
+	multipart map[string]*memoryMultipart
+}
+
+// memoryObject é um objeto gravado. O conteúdo nunca é alterado depois de gravado,
+// então os leitores podem compartilhá-lo.
+type memoryObject struct {
+	content      []byte
+	contentType  string
+	sha256       string
+	etag         string
+	lastModified time.Time
+}
+
+// memoryMultipart é um envio em partes em andamento
+type memoryMultipart struct {
+	objectName  string
+	contentType string
+	parts       map[int][]byte
+}
+
+// memoryReader adapta um bytes.Reader à interface ObjectReader
+type memoryReader struct {
+	*bytes.Reader
+}
+
+// Close não tem efeito: o conteúdo continua em memória
+func (memoryReader) Close() error {
+	return nil
+}
+
+// NewMemoryStore cria um armazenamento em memória vazio
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		objects:   map[string]*memoryObject{},
+		multipart: map[string]*memoryMultipart{},
+	}
+}
+
+// Put lê o conteúdo inteiro e o grava sob o nome informado
+func (s *MemoryStore) Put(objectName string, reader io.Reader, size int64, opts PutOptions) error {
+	if size >= 0 {
+		reader = io.LimitReader(reader, size)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("falha ao gravar objeto: %v", err)
+	}
+	if size >= 0 && int64(len(content)) != size {
+		return fmt.Errorf("falha ao gravar objeto: %d bytes recebidos, %d esperados", len(content), size)
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[objectName] = newMemoryObject(content, opts.ContentType, opts.SHA256)
+	return nil
+}
+
+// Get retorna um leitor sobre o conteúdo do objeto
+func (s *MemoryStore) Get(objectName string) (ObjectReader, *ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return nil, nil, fmt.Errorf("falha ao abrir objeto %s: %w", objectName, ErrObjectNotFound)
+	}
+	return memoryReader{bytes.NewReader(object.content)}, object.info(objectName), nil
+}
+
+// Stat retorna as informações do objeto
+func (s *MemoryStore) Stat(objectName string) (*ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	object, ok := s.objects[objectName]
+	if !ok {
+		return nil, fmt.Errorf("falha ao consultar objeto %s: %w", objectName, ErrObjectNotFound)
+	}
+	return object.info(objectName), nil
+}
+
+// Delete remove o objeto
+func (s *MemoryStore) Delete(objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, objectName)
+	return nil
+}
+
+// List percorre uma cópia da listagem, para que fn possa gravar ou remover objetos
+func (s *MemoryStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	s.mu.RLock()
+	infos := make([]ObjectInfo, 0, len(s.objects))
+	for name, object := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			infos = append(infos, *object.info(name))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range infos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy faz o destino compartilhar o conteúdo da origem. Como em LocalStore, o checksum
+// registrado não é copiado.
+func (s *MemoryStore) Copy(srcName string, dstName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[srcName]
+	if !ok {
+		return fmt.Errorf("falha ao copiar objeto %s: %w", srcName, ErrObjectNotFound)
+	}
+	s.objects[dstName] = newMemoryObject(object.content, object.contentType, "")
+	return nil
+}
+
+// PresignGet não é suportado: a memória do processo não é acessível pelos clientes
+func (s *MemoryStore) PresignGet(objectName string, expiry time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// PresignPost não é suportado: a memória do processo não é acessível pelos clientes
+func (s *MemoryStore) PresignPost(objectName string, size int64, expiry time.Duration) (*PresignedPost, error) {
+	return nil, ErrPresignUnsupported
+}
+
+// NewMultipartUpload registra um envio em partes
+func (s *MemoryStore) NewMultipartUpload(objectName string, contentType string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploadID := uuid.New().String()
+	s.multipart[uploadID] = &memoryMultipart{
+		objectName:  objectName,
+		contentType: contentType,
+		parts:       map[int][]byte{},
+	}
+	return uploadID, nil
+}
+
+// PutPart guarda uma cópia da parte enviada
+func (s *MemoryStore) PutPart(objectName string, uploadID string, number int, content []byte) (UploadedPart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err := s.multipartUpload(objectName, uploadID)
+	if err != nil {
+		return UploadedPart{}, err
+	}
+	upload.parts[number] = append([]byte(nil), content...)
+
+	sum := md5.Sum(content)
+	return UploadedPart{Number: number, ETag: hex.EncodeToString(sum[:]), Size: int64(len(content))}, nil
+}
+
+// CompleteMultipartUpload junta as partes, na ordem da numeração, no objeto final
+func (s *MemoryStore) CompleteMultipartUpload(objectName string, uploadID string, parts []UploadedPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err := s.multipartUpload(objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	sorted := make([]UploadedPart, len(parts))
+	copy(sorted, parts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+
+	var content bytes.Buffer
+	for _, part := range sorted {
+		data, ok := upload.parts[part.Number]
+		if !ok {
+			return fmt.Errorf("falha ao concluir upload multipart: parte %d ausente", part.Number)
+		}
+		content.Write(data)
+	}
+
+	contentType := upload.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	s.objects[objectName] = newMemoryObject(content.Bytes(), contentType, "")
+	delete(s.multipart, uploadID)
+	return nil
+}
+
+// AbortMultipartUpload descarta as partes já enviadas
+func (s *MemoryStore) AbortMultipartUpload(objectName string, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.multipartUpload(objectName, uploadID); err != nil {
+		return err
+	}
+	delete(s.multipart, uploadID)
+	return nil
+}
+
+// multipartUpload localiza um envio em partes e confere se ele pertence ao objeto informado.
+// Deve ser chamada com o mutex já obtido.
+func (s *MemoryStore) multipartUpload(objectName string, uploadID string) (*memoryMultipart, error) {
+	upload, ok := s.multipart[uploadID]
+	if !ok {
+		return nil, fmt.Errorf("upload multipart %s não encontrado", uploadID)
+	}
+	if upload.objectName != objectName {
+		return nil, fmt.Errorf("upload multipart %s não pertence ao objeto %s", uploadID, objectName)
+	}
+	return upload, nil
+}
+
+// newMemoryObject registra o conteúdo gravado agora
+func newMemoryObject(content []byte, contentType string, sha256 string) *memoryObject {
+	sum := md5.Sum(content)
+	return &memoryObject{
+		content:      content,
+		contentType:  contentType,
+		sha256:       sha256,
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: time.Now(),
+	}
+}
+
+// info monta as informações do objeto
+func (o *memoryObject) info(objectName string) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         int64(len(o.content)),
+		ContentType:  o.contentType,
+		ETag:         o.etag,
+		LastModified: o.lastModified,
+		SHA256:       recordedChecksum(objectName, o.sha256),
+	}
+}
@@ -1,20 +1,20 @@
 package storage
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	minio "github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
@@ -23,6 +23,11 @@ import (
 type MinioClient struct {
 	Client     *minio.Client
 	BucketName string
+
+	creds         *credentials.Credentials
+	presignOnce   sync.Once
+	presignClient *minio.Client // Cliente do endereço público, usado apenas para assinar URLs
+	presignErr    error
 }
 
 var minioInstance *MinioClient
@@ -77,8 +82,9 @@ func GetMinioClient() (*MinioClient, error) {
 	log.Printf("Iniciando conexão com MinIO em %s", endpoint)
 	
 	// Configuração melhorada para resolver problemas de hostname em ambientes Docker
+	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
 	minioOptions := &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Creds:  creds,
 		Secure: useSSL,
 		Region: "", // Definir região vazia para evitar validação de hostname
 		BucketLookup: minio.BucketLookupAuto, // Permitir busca automática do bucket
@@ -149,85 +155,90 @@ func GetMinioClient() (*MinioClient, error) {
 		log.Printf("Bucket %s já existe", bucketName)
 	}
 
-	// Definir política de acesso ao bucket (varia conforme o ambiente)
-	if os.Getenv("APP_ENV") != "production" {
-		// Em dev/test, permitir acesso de leitura para download facilitado
-		policy := `{
-			"Version": "2012-10-17",
-			"Statement": [
-				{
-					"Effect": "Allow",
-					"Principal": {"AWS": ["*"]},
-					"Action": ["s3:GetObject"],
-					"Resource": ["arn:aws:s3:::` + bucketName + `/*"]
-				}
-			]
-		}`
-		policyErr := client.SetBucketPolicy(ctx, bucketName, policy)
-		if policyErr != nil {
-			log.Printf("Aviso: falha ao definir política do bucket: %v", policyErr)
+	// O bucket é sempre privado: o acesso anônimo acontece apenas por links públicos
+	// do document-service. Remover a regra de leitura pública instalada pelas versões
+	// antigas, mantendo as demais regras configuradas no bucket.
+	policy, policyErr := client.GetBucketPolicy(ctx, bucketName)
+	if policyErr == nil && policy != "" {
+		remaining, removed, err := removeLegacyPublicRead(policy, bucketName)
+		if err != nil {
+			log.Printf("Aviso: política do bucket %s não interpretada; mantida sem alterações: %v", bucketName, err)
+		} else if removed {
+			policyErr = client.SetBucketPolicy(ctx, bucketName, remaining)
+			if policyErr != nil {
+				log.Printf("Aviso: falha ao remover política pública do bucket: %v", policyErr)
+			} else {
+				log.Printf("Regra de leitura pública removida da política do bucket %s", bucketName)
+			}
 		}
 	}
 
 	minioInstance = &MinioClient{
 		Client:     client,
 		BucketName: bucketName,
+		creds:      creds,
 	}
 
 	return minioInstance, nil
 }
 
-// UploadDocument faz upload de um documento para o MinIO
-func (m *MinioClient) UploadDocument(content []byte, userID string, documentID string, contentType string) (string, error) {
-	// Criar um path único para o arquivo
-	// Formato: userID/documentID/<uuid>.md
-	fileUUID := uuid.New().String()
-	objectName := filepath.Join(userID, documentID, fileUUID+".md")
+// unknownSizePartSize é o tamanho das partes usadas quando o tamanho do envio não é conhecido.
+// Sem ele, o cliente MinIO reservaria partes dimensionadas para o maior objeto possível.
+const unknownSizePartSize = 16 << 20
 
-	ctx := context.Background()
-	reader := bytes.NewReader(content)
-	contentSize := int64(len(content))
-
-	// Configurar opções de upload
-	opts := minio.PutObjectOptions{
-		ContentType: contentType,
-		UserMetadata: map[string]string{
-			"user-id":     userID,
-			"document-id": documentID,
-			"uploaded-at": time.Now().Format(time.RFC3339),
-		},
+// Put grava um objeto no MinIO em streaming
+func (m *MinioClient) Put(objectName string, reader io.Reader, size int64, opts PutOptions) error {
+	putOpts := minio.PutObjectOptions{ContentType: opts.ContentType}
+	if putOpts.ContentType == "" {
+		putOpts.ContentType = "application/octet-stream"
+	}
+	if opts.SHA256 != "" {
+		putOpts.UserMetadata = map[string]string{"sha256": opts.SHA256}
+	}
+	if size < 0 {
+		putOpts.PartSize = unknownSizePartSize
 	}
 
-	// Upload do arquivo
-	info, err := m.Client.PutObject(ctx, m.BucketName, objectName, reader, contentSize, opts)
+	info, err := m.Client.PutObject(context.Background(), m.BucketName, objectName, reader, size, putOpts)
 	if err != nil {
-		return "", fmt.Errorf("falha ao fazer upload do documento: %v", err)
+		return fmt.Errorf("falha ao gravar objeto: %v", err)
 	}
 
-	log.Printf("Arquivo '%s' de tamanho %d bytes enviado com sucesso", objectName, info.Size)
-	return objectName, nil
+	log.Printf("Objeto '%s' de tamanho %d bytes gravado com sucesso", objectName, info.Size)
+	return nil
 }
 
-// GetDocument recupera um documento do MinIO
-func (m *MinioClient) GetDocument(objectName string) ([]byte, error) {
+// Get abre um objeto do MinIO para leitura em streaming. O leitor permite
+// reposicionamento (Seek), o que viabiliza respostas parciais. Quem chama é
+// responsável por fechar o leitor retornado.
+func (m *MinioClient) Get(objectName string) (ObjectReader, *ObjectInfo, error) {
 	ctx := context.Background()
 	obj, err := m.Client.GetObject(ctx, m.BucketName, objectName, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("falha ao obter objeto do MinIO: %v", err)
+		return nil, nil, minioError("falha ao obter objeto do MinIO", err)
 	}
-	defer obj.Close()
 
-	// Lê o conteúdo completo
-	content, err := io.ReadAll(obj)
+	// GetObject é preguiçoso: o Stat confirma que o objeto existe antes de começar a responder
+	stat, err := obj.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("falha ao ler conteúdo do objeto: %v", err)
+		obj.Close()
+		return nil, nil, minioError("falha ao obter objeto do MinIO", err)
 	}
 
-	return content, nil
+	return obj, minioObjectInfo(objectName, stat), nil
+}
+
+// Stat retorna as informações de um objeto do MinIO
+func (m *MinioClient) Stat(objectName string) (*ObjectInfo, error) {
+	stat, err := m.Client.StatObject(context.Background(), m.BucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, minioError("falha ao consultar objeto do MinIO", err)
+	}
+	return minioObjectInfo(objectName, stat), nil
 }
 
-// DeleteDocument remove um documento do MinIO
-func (m *MinioClient) DeleteDocument(objectName string) error {
+// Delete remove um objeto do MinIO
+func (m *MinioClient) Delete(objectName string) error {
 	ctx := context.Background()
 	err := m.Client.RemoveObject(ctx, m.BucketName, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
@@ -237,29 +248,55 @@ func (m *MinioClient) DeleteDocument(objectName string) error {
 }
 
 // ListDocuments lista todos os documentos de um usuário
-func (m *MinioClient) ListDocuments(userID string) ([]minio.ObjectInfo, error) {
-	ctx := context.Background()
-	prefix := userID + "/"
+func (m *MinioClient) ListDocuments(userID string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := m.List(context.Background(), userID+"/", func(object ObjectInfo) error {
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// List percorre os objetos do bucket com o prefixo informado (vazio para todos)
+func (m *MinioClient) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	opts := minio.ListObjectsOptions{
 		Prefix:    prefix,
 		Recursive: true,
 	}
-
-	objectCh := m.Client.ListObjects(ctx, m.BucketName, opts)
-	
-	var objects []minio.ObjectInfo
-	for object := range objectCh {
+	for object := range m.Client.ListObjects(ctx, m.BucketName, opts) {
 		if object.Err != nil {
-			return nil, fmt.Errorf("erro ao listar objetos: %v", object.Err)
+			return fmt.Errorf("erro ao listar objetos: %v", object.Err)
+		}
+		if err := fn(*minioObjectInfo(object.Key, object)); err != nil {
+			return err
 		}
-		objects = append(objects, object)
 	}
+	return nil
+}
 
-	return objects, nil
+// Copy copia, dentro do próprio MinIO, um objeto já gravado para outro nome
+func (m *MinioClient) Copy(srcName string, dstName string) error {
+	// ComposeObject aceita origens maiores que o limite de 5GB de uma cópia simples
+	_, err := m.Client.ComposeObject(
+		context.Background(),
+		minio.CopyDestOptions{Bucket: m.BucketName, Object: dstName},
+		minio.CopySrcOptions{Bucket: m.BucketName, Object: srcName},
+	)
+	if err != nil {
+		return minioError("falha ao copiar objeto", err)
+	}
+	return nil
 }
 
-// GetDocumentURL gera uma URL pré-assinada para acesso temporário a um documento
-func (m *MinioClient) GetDocumentURL(objectName string, expiry time.Duration) (string, error) {
+// PresignGet gera uma URL pré-assinada para acesso temporário a um documento
+func (m *MinioClient) PresignGet(objectName string, expiry time.Duration) (string, error) {
 	ctx := context.Background()
 	presignedURL, err := m.Client.PresignedGetObject(ctx, m.BucketName, objectName, expiry, nil)
 	if err != nil {
@@ -267,3 +304,157 @@ func (m *MinioClient) GetDocumentURL(objectName string, expiry time.Duration) (s
 	}
 	return presignedURL.String(), nil
 }
+
+// PresignPost gera o formulário pré-assinado para o cliente gravar um objeto diretamente no
+// MinIO. A política limita o envio ao nome do objeto e a exatamente size bytes, o que uma
+// URL de PUT pré-assinada não consegue garantir.
+func (m *MinioClient) PresignPost(objectName string, size int64, expiry time.Duration) (*PresignedPost, error) {
+	client, err := m.presigner()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(m.BucketName); err != nil {
+		return nil, err
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return nil, err
+	}
+	if err := policy.SetContentLengthRange(size, size); err != nil {
+		return nil, err
+	}
+
+	presignedURL, fields, err := client.PresignedPostPolicy(context.Background(), policy)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao gerar formulário pré-assinado de envio: %v", err)
+	}
+	return &PresignedPost{URL: presignedURL.String(), Fields: fields}, nil
+}
+
+// presigner retorna o cliente usado para assinar URLs entregues aos usuários. Quando
+// MINIO_PUBLIC_ENDPOINT está definido, as URLs apontam para esse endereço (o MinIO
+// exposto pelo Nginx) em vez do endereço interno da rede dos contêineres.
+func (m *MinioClient) presigner() (*minio.Client, error) {
+	endpoint := os.Getenv("MINIO_PUBLIC_ENDPOINT")
+	if endpoint == "" {
+		return m.Client, nil
+	}
+
+	m.presignOnce.Do(func() {
+		region := os.Getenv("MINIO_REGION")
+		if region == "" {
+			region = "us-east-1" // Região explícita evita consultar o bucket pelo endereço público
+		}
+		// Usar SSL quando o endpoint for localhost (onde o Nginx termina SSL)
+		useSSL := strings.HasPrefix(endpoint, "localhost:") || strings.HasPrefix(endpoint, "127.0.0.1:")
+
+		m.presignClient, m.presignErr = minio.New(endpoint, &minio.Options{
+			Creds:  m.creds,
+			Secure: useSSL,
+			Region: region,
+		})
+		if m.presignErr != nil {
+			m.presignErr = fmt.Errorf("falha ao criar cliente MinIO para o endereço público: %v", m.presignErr)
+		}
+	})
+	return m.presignClient, m.presignErr
+}
+
+// minioObjectInfo converte as informações de um objeto do MinIO
+func minioObjectInfo(objectName string, object minio.ObjectInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          objectName,
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		ETag:         object.ETag,
+		LastModified: object.LastModified,
+		SHA256:       recordedChecksum(objectName, object.UserMetadata["Sha256"]),
+	}
+}
+
+// minioError identifica objetos inexistentes, que passam a satisfazer errors.Is(err, ErrObjectNotFound)
+func minioError(message string, err error) error {
+	var response minio.ErrorResponse
+	if errors.As(err, &response) && response.Code == "NoSuchKey" {
+		return fmt.Errorf("%s: %w", message, ErrObjectNotFound)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// removeLegacyPublicRead retira da política do bucket a regra de leitura anônima de todos os
+// objetos que as versões antigas instalavam. Retorna a política restante (vazia se não sobrou
+// nenhuma regra) e se a regra foi encontrada.
+func removeLegacyPublicRead(policy string, bucketName string) (string, bool, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return "", false, err
+	}
+
+	var statements []interface{}
+	switch value := document["Statement"].(type) {
+	case []interface{}:
+		statements = value
+	case map[string]interface{}:
+		statements = []interface{}{value}
+	default:
+		return "", false, nil
+	}
+
+	kept := []interface{}{}
+	for _, statement := range statements {
+		if fields, ok := statement.(map[string]interface{}); ok && isLegacyPublicRead(fields, bucketName) {
+			continue
+		}
+		kept = append(kept, statement)
+	}
+	if len(kept) == len(statements) {
+		return policy, false, nil
+	}
+	if len(kept) == 0 {
+		return "", true, nil
+	}
+
+	document["Statement"] = kept
+	remaining, err := json.Marshal(document)
+	if err != nil {
+		return "", false, err
+	}
+	return string(remaining), true, nil
+}
+
+// isLegacyPublicRead verifica se a regra é exatamente a leitura anônima de todo o bucket
+func isLegacyPublicRead(statement map[string]interface{}, bucketName string) bool {
+	for field := range statement {
+		switch field {
+		case "Sid", "Effect", "Principal", "Action", "Resource":
+		default:
+			return false // Regras com condições ou outros campos foram configuradas à parte
+		}
+	}
+	if statement["Effect"] != "Allow" {
+		return false
+	}
+
+	principal := statement["Principal"]
+	if fields, ok := principal.(map[string]interface{}); ok && len(fields) == 1 {
+		principal = fields["AWS"]
+	}
+	return policyValues(principal, "*") &&
+		policyValues(statement["Action"], "s3:GetObject") &&
+		policyValues(statement["Resource"], "arn:aws:s3:::"+bucketName+"/*")
+}
+
+// policyValues verifica se um campo da política, texto ou lista, contém apenas o valor informado
+func policyValues(field interface{}, expected string) bool {
+	switch value := field.(type) {
+	case string:
+		return value == expected
+	case []interface{}:
+		return len(value) == 1 && value[0] == expected
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	minio "github.com/minio/minio-go/v7"
+)
+
+// MinPartSize é o menor tamanho aceito pelo MinIO para as partes de um envio
+// multipart, exceto a última
+const MinPartSize = 5 << 20
+
+// UploadedPart identifica uma parte já enviada de um upload multipart
+type UploadedPart struct {
+	Number int
+	ETag   string
+	Size   int64
+}
+
+// NewObjectName gera um caminho único para um novo objeto de um documento
+func NewObjectName(userID string, documentID string, extension string) string {
+	return filepath.Join(userID, documentID, uuid.New().String()+"."+extension)
+}
+
+// NewMultipartUpload inicia um upload multipart e retorna o seu identificador no MinIO
+func (m *MinioClient) NewMultipartUpload(objectName string, contentType string) (string, error) {
+	core := minio.Core{Client: m.Client}
+	uploadID, err := core.NewMultipartUpload(context.Background(), m.BucketName, objectName, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("falha ao iniciar upload multipart: %v", err)
+	}
+	return uploadID, nil
+}
+
+// PutPart envia uma parte de um upload multipart
+func (m *MinioClient) PutPart(objectName string, uploadID string, number int, content []byte) (UploadedPart, error) {
+	core := minio.Core{Client: m.Client}
+	part, err := core.PutObjectPart(context.Background(), m.BucketName, objectName, uploadID, number,
+		bytes.NewReader(content), int64(len(content)), "", "", nil)
+	if err != nil {
+		return UploadedPart{}, fmt.Errorf("falha ao enviar parte %d: %v", number, err)
+	}
+	return UploadedPart{Number: number, ETag: part.ETag, Size: int64(len(content))}, nil
+}
+
+// CompleteMultipartUpload junta as partes enviadas no objeto final
+func (m *MinioClient) CompleteMultipartUpload(objectName string, uploadID string, parts []UploadedPart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+
+	core := minio.Core{Client: m.Client}
+	_, err := core.CompleteMultipartUpload(context.Background(), m.BucketName, objectName, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("falha ao concluir upload multipart: %v", err)
+	}
+	return nil
+}
+
+// AbortMultipartUpload descarta um upload multipart e as partes já enviadas
+func (m *MinioClient) AbortMultipartUpload(objectName string, uploadID string) error {
+	core := minio.Core{Client: m.Client}
+	err := core.AbortMultipartUpload(context.Background(), m.BucketName, objectName, uploadID)
+	if err != nil {
+		return fmt.Errorf("falha ao cancelar upload multipart: %v", err)
+	}
+	return nil
+}
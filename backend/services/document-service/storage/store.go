@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// BlobStore é o armazenamento de objetos usado pelo serviço. O MinIO é a implementação
+// padrão; o sistema de arquivos local e a memória atendem instalações pequenas e testes.
+type BlobStore interface {
+	// Put grava um objeto em streaming. Use size -1 quando o tamanho não for conhecido.
+	Put(objectName string, reader io.Reader, size int64, opts PutOptions) error
+	// Get abre um objeto para leitura. Quem chama é responsável por fechar o leitor.
+	Get(objectName string) (ObjectReader, *ObjectInfo, error)
+	// Stat retorna as informações de um objeto sem abri-lo
+	Stat(objectName string) (*ObjectInfo, error)
+	// Delete remove um objeto; remover um objeto inexistente não é erro
+	Delete(objectName string) error
+	// List percorre, em ordem lexicográfica, os objetos com o prefixo informado (vazio para
+	// todos), sem carregar a listagem inteira em memória. A listagem para no primeiro erro de fn.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	// Copy copia um objeto já gravado para outro nome
+	Copy(srcName string, dstName string) error
+
+	// PresignGet e PresignPost geram URLs temporárias de leitura e gravação direta; o
+	// formulário de PresignPost só aceita um arquivo com exatamente size bytes.
+	// Retornam ErrPresignUnsupported quando o armazenamento não é acessível pelos clientes.
+	PresignGet(objectName string, expiry time.Duration) (string, error)
+	PresignPost(objectName string, size int64, expiry time.Duration) (*PresignedPost, error)
+
+	// Envio em partes, usado pelos envios retomáveis
+	NewMultipartUpload(objectName string, contentType string) (string, error)
+	PutPart(objectName string, uploadID string, number int, content []byte) (UploadedPart, error)
+	CompleteMultipartUpload(objectName string, uploadID string, parts []UploadedPart) error
+	AbortMultipartUpload(objectName string, uploadID string) error
+}
+
+// ErrObjectNotFound indica que o objeto não existe no armazenamento
+var ErrObjectNotFound = errors.New("objeto não encontrado")
+
+// ErrPresignUnsupported indica que o armazenamento não gera URLs de acesso direto
+var ErrPresignUnsupported = errors.New("o armazenamento configurado não gera URLs de acesso direto")
+
+// PutOptions descreve o objeto gravado
+type PutOptions struct {
+	ContentType string
+	SHA256      string // Checksum registrado com o objeto e conferido nas leituras
+}
+
+// ObjectReader permite ler um objeto em sequência, reposicionar a leitura e ler trechos arbitrários
+type ObjectReader interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+}
+
+// PresignedPost é o formulário que o cliente envia por POST (multipart/form-data) para gravar
+// um objeto diretamente: os campos de Fields seguidos do arquivo no campo "file"
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+// ObjectInfo descreve um objeto armazenado
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	SHA256       string // Checksum registrado na gravação; vazio em objetos anteriores aos checksums
+}
+
+var blobStore BlobStore
+
+// GetBlobStore retorna o armazenamento configurado por STORAGE_DRIVER: minio (padrão),
+// local (diretório em STORAGE_LOCAL_PATH) ou memory (não persistente)
+func GetBlobStore() (BlobStore, error) {
+	if blobStore != nil {
+		return blobStore, nil
+	}
+
+	driver := strings.ToLower(os.Getenv("STORAGE_DRIVER"))
+	switch driver {
+	case "", "minio":
+		client, err := GetMinioClient()
+		if err != nil {
+			return nil, err
+		}
+		blobStore = client
+	case "local":
+		root := os.Getenv("STORAGE_LOCAL_PATH")
+		if root == "" {
+			root = "data"
+			log.Println("Usando diretório padrão do armazenamento local:", root)
+		}
+		store, err := NewLocalStore(root)
+		if err != nil {
+			return nil, err
+		}
+		blobStore = store
+	case "memory":
+		log.Println("Aviso: armazenamento em memória; o conteúdo é perdido ao reiniciar o serviço")
+		blobStore = NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("driver de armazenamento desconhecido: %s", driver)
+	}
+
+	log.Printf("Armazenamento de objetos: %T", blobStore)
+	return blobStore, nil
+}
+
+// ReadAll recupera o conteúdo completo de um objeto, conferindo o checksum registrado.
+// Se o conteúdo não conferir, retorna um *IntegrityError. Para arquivos grandes prefira Get.
+func ReadAll(store BlobStore, objectName string) ([]byte, error) {
+	obj, info, err := store.Get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler conteúdo do objeto: %v", err)
+	}
+
+	if info.SHA256 != "" {
+		sum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(sum[:]); actual != info.SHA256 {
+			return nil, &IntegrityError{ObjectName: objectName, Expected: info.SHA256, Actual: actual}
+		}
+	}
+
+	return content, nil
+}
+
+// recordedChecksum retorna o checksum de um objeto: blobs o têm no próprio nome; os demais,
+// nos metadados da gravação
+func recordedChecksum(objectName string, metadata string) string {
+	if checksum, ok := BlobHash(objectName); ok {
+		return checksum
+	}
+	return metadata
+}
+
+// Implementações disponíveis de BlobStore
+var (
+	_ BlobStore = (*MinioClient)(nil)
+	_ BlobStore = (*LocalStore)(nil)
+	_ BlobStore = (*MemoryStore)(nil)
+)
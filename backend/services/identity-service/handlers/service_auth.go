@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceTokenHeader é o cabeçalho em que os outros serviços enviam o segredo compartilhado
+const ServiceTokenHeader = "X-Service-Token"
+
+// ServiceAuthMiddleware restringe a rota às chamadas entre serviços, que se identificam pelo
+// segredo compartilhado de INTERNAL_SERVICE_TOKEN. Sem o segredo configurado, a rota fica
+// indisponível em vez de aberta.
+func ServiceAuthMiddleware() gin.HandlerFunc {
+	secret := os.Getenv("INTERNAL_SERVICE_TOKEN")
+	if secret == "" {
+		log.Println("Aviso: INTERNAL_SERVICE_TOKEN não configurado; rotas entre serviços desativadas")
+	}
+
+	return func(c *gin.Context) {
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Service-to-service authentication is not configured"})
+			return
+		}
+
+		token := c.GetHeader(ServiceTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing service token"})
+			return
+		}
+
+		c.Next()
+	}
+}
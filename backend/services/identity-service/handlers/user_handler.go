@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"gestor-e-docs/backend/services/identity-service/db"
+	"gestor-e-docs/backend/services/identity-service/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LookupUser busca um usuário pelo e-mail ou pelo ID e retorna apenas seus dados públicos.
+// Usado por outros serviços (ex.: compartilhamento de documentos) para resolver usuários;
+// só é acessível com o segredo de ServiceAuthMiddleware.
+func LookupUser(c *gin.Context) {
+	email := strings.TrimSpace(c.Query("email"))
+	id := strings.TrimSpace(c.Query("id"))
+
+	var filter bson.M
+	switch {
+	case email != "":
+		filter = bson.M{"email": email}
+	case id != "":
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+			return
+		}
+		filter = bson.M{"_id": objectID}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'email' or 'id' is required"})
+		return
+	}
+
+	collection := db.GetCollection("users")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error finding user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":    user.ID.Hex(),
+			"email": user.Email,
+			"name":  user.Name,
+		},
+	})
+}
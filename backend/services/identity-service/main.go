@@ -208,6 +208,13 @@ func main() {
 				twoFactorGroup.GET("/status", handlers.GetTwoFactorStatus)
 			}
 		}
+
+		// Rotas entre serviços, autenticadas pelo segredo compartilhado em vez do token do usuário
+		service := apiV1.Group("")
+		service.Use(handlers.ServiceAuthMiddleware())
+		{
+			service.GET("/users/lookup", handlers.LookupUser)
+		}
 	}
 
 	log.Printf("Identity service starting on port %s", port)